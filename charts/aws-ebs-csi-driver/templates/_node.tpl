@@ -83,6 +83,9 @@ spec:
             {{- with .Values.node.volumeAttachLimit }}
             - --volume-attach-limit={{ . }}
             {{- end }}
+            {{- if .Values.node.reconcileCSINodeAllocatable }}
+            - --reconcile-csinode-allocatable=true
+            {{- end }}
             {{- with .Values.node.metadataSources }}
             - --metadata-sources={{ . }}
             {{- end }}
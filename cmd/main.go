@@ -20,10 +20,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/cmd/hooks"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/cmd/volumelimit"
 	cloudPkg "github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver"
@@ -82,6 +85,11 @@ func main() {
 	options.Mode = driver.Mode(cmd)
 	options.AddFlags(fs)
 
+	volumeLimitOptions := volumelimit.Options{}
+	if cmd == volumelimit.Command {
+		volumeLimitOptions.AddFlags(fs)
+	}
+
 	plugin := plugin.GetPlugin()
 	if plugin != nil {
 		plugin.InitFlags(fs)
@@ -116,6 +124,15 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The volume-limit subcommand only reads the limits tables of the driver, so it exits before any client is created
+	if cmd == volumelimit.Command {
+		if err := volumelimit.Run(os.Stdout, &volumeLimitOptions); err != nil {
+			klog.ErrorS(err, "failed to compute volume limit")
+			klog.FlushAndExit(klog.ExitFlushTimeout, 1)
+		}
+		klog.FlushAndExit(klog.ExitFlushTimeout, 0)
+	}
+
 	// Start tracing as soon as possible
 	if options.EnableOtelTracing {
 		exporter, exporterErr := driver.InitOtelTracing()
@@ -200,7 +217,23 @@ func main() {
 				userAgentExtra = string(driver.MetadataLabelerMode)
 			}
 		}
-		cloud = cloudPkg.NewCloud(region, options.AwsSdkDebugLog, userAgentExtra, options.Batching, options.DeprecatedMetrics)
+		cloud = cloudPkg.NewCloud(region, cloudPkg.CloudOptions{
+			AwsSdkDebugLog: options.AwsSdkDebugLog,
+			UserAgentExtra: userAgentExtra,
+			Batching: cloudPkg.BatchingOptions{
+				Enabled:          options.Batching,
+				VolumeMaxEntries: options.BatchingVolumeMaxEntries,
+				VolumeMaxDelay:   options.BatchingVolumeMaxDelay,
+			},
+			DeprecatedMetrics: options.DeprecatedMetrics,
+			Transport: cloudPkg.HTTPTransportOptions{
+				MaxIdleConnsPerHost: options.AwsMaxIdleConnsPerHost,
+				IdleConnTimeout:     options.AwsIdleConnTimeout,
+				EC2Endpoint:         options.AwsEC2Endpoint,
+			},
+			ReservedDeviceNames:        options.ReservedDeviceNames,
+			VolumeTypeAttachmentLimits: options.VolumeTypeAttachmentLimits,
+		})
 	}
 
 	k8sClient, err = cfg.K8sAPIClient()
@@ -229,7 +262,7 @@ func main() {
 			klog.FlushAndExit(klog.ExitFlushTimeout, 0)
 		}
 	default:
-		klog.Errorf("Unknown driver mode %s: Expected %s, %s, %s, %s, or pre-stop-hook", cmd, driver.ControllerMode, driver.NodeMode, driver.AllMode, driver.MetadataLabelerMode)
+		klog.Errorf("Unknown driver mode %s: Expected %s, %s, %s, %s, pre-stop-hook, or %s", cmd, driver.ControllerMode, driver.NodeMode, driver.AllMode, driver.MetadataLabelerMode, volumelimit.Command)
 		klog.FlushAndExit(klog.ExitFlushTimeout, 0)
 	}
 
@@ -259,6 +292,15 @@ func main() {
 		klog.ErrorS(err, "failed to create driver")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 	}
+	if options.GracefulShutdownTimeout > 0 {
+		go func() {
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+			sig := <-sigCh
+			klog.InfoS("Received signal, waiting for in-flight requests to finish", "signal", sig, "timeout", options.GracefulShutdownTimeout)
+			drv.GracefulStop(options.GracefulShutdownTimeout)
+		}()
+	}
 	if err := drv.Run(); err != nil {
 		klog.ErrorS(err, "failed to run driver")
 		klog.FlushAndExit(klog.ExitFlushTimeout, 1)
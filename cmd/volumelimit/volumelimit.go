@@ -0,0 +1,78 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package volumelimit implements the volume-limit subcommand, which prints the volume
+// attachment limit of an instance type in the limits tables of the driver, without
+// requiring access to IMDS or the Kubernetes API. It does not apply the node options that
+// change the limit the node service reports, such as --reserved-device-names or
+// --max-advertised-attachments, nor the instance store volumes and accelerators the node
+// detects.
+package volumelimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/limits"
+	flag "github.com/spf13/pflag"
+)
+
+// Command is the name of the subcommand.
+const Command = "volume-limit"
+
+// Options are the flags accepted by the volume-limit subcommand.
+type Options struct {
+	// InstanceType is the EC2 instance type to compute the limit for.
+	InstanceType string
+	// AttachedENIs is the number of ENIs attached to the instance, including the primary ENI.
+	AttachedENIs int
+	// ReservedVolumeAttachments is the number of attachments reserved for volumes not managed by the driver,
+	// including the root volume.
+	ReservedVolumeAttachments int
+}
+
+// AddFlags registers the subcommand flags on f.
+func (o *Options) AddFlags(f *flag.FlagSet) {
+	f.StringVar(&o.InstanceType, "instance-type", "", "EC2 instance type to compute the volume limit for.")
+	f.IntVar(&o.AttachedENIs, "attached-enis", 1, "Number of ENIs attached to the instance, including the primary ENI.")
+	f.IntVar(&o.ReservedVolumeAttachments, "reserved-volume-attachments", 1, "Number of volume attachments reserved for volumes not managed by the driver, including the root volume.")
+}
+
+// Run writes the volume limit in the limits tables and its breakdown for the configured instance type to w.
+func Run(w io.Writer, o *Options) error {
+	if o.InstanceType == "" {
+		return errors.New("--instance-type must be specified")
+	}
+	// The limits tables resolve malformed instance types to the default limit, which would hide a typo
+	if _, err := limits.VolumeLimitForInstanceType(o.InstanceType); err != nil {
+		return fmt.Errorf("--instance-type: %w", err)
+	}
+	if o.AttachedENIs < 1 {
+		return errors.New("--attached-enis must be at least 1")
+	}
+	if o.ReservedVolumeAttachments < 0 {
+		return errors.New("--reserved-volume-attachments must not be negative")
+	}
+
+	vl := limits.GetVolumeLimit(o.InstanceType, o.ReservedVolumeAttachments, o.AttachedENIs)
+	_, err := fmt.Fprintf(w, `Instance type:                %s
+Attachment type:              %s
+Maximum attachments:          %d
+Reserved volume attachments:  %d
+ENI attachments:              %d
+Volume limit:                 %d
+`, o.InstanceType, vl.AttachmentType, vl.MaxAttachments, vl.ReservedAttachments, vl.ENIAttachments, vl.Limit)
+	return err
+}
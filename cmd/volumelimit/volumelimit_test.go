@@ -0,0 +1,118 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package volumelimit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		options     Options
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:    "dedicated instance type ignores ENIs",
+			options: Options{InstanceType: "m7i.24xlarge", AttachedENIs: 3, ReservedVolumeAttachments: 1},
+			expected: `Instance type:                m7i.24xlarge
+Attachment type:              dedicated
+Maximum attachments:          64
+Reserved volume attachments:  1
+ENI attachments:              0
+Volume limit:                 63
+`,
+		},
+		{
+			name:    "shared instance type subtracts secondary ENIs",
+			options: Options{InstanceType: "m5.large", AttachedENIs: 3, ReservedVolumeAttachments: 1},
+			expected: `Instance type:                m5.large
+Attachment type:              shared
+Maximum attachments:          27
+Reserved volume attachments:  1
+ENI attachments:              2
+Volume limit:                 24
+`,
+		},
+		{
+			name:    "non-nitro instance type",
+			options: Options{InstanceType: "t2.medium", AttachedENIs: 1, ReservedVolumeAttachments: 2},
+			expected: `Instance type:                t2.medium
+Attachment type:              dedicated
+Maximum attachments:          39
+Reserved volume attachments:  2
+ENI attachments:              0
+Volume limit:                 37
+`,
+		},
+		{
+			name:    "limit never drops below 1",
+			options: Options{InstanceType: "m5.large", AttachedENIs: 1, ReservedVolumeAttachments: 40},
+			expected: `Instance type:                m5.large
+Attachment type:              shared
+Maximum attachments:          27
+Reserved volume attachments:  40
+ENI attachments:              0
+Volume limit:                 1
+`,
+		},
+		{
+			name:        "missing instance type",
+			options:     Options{AttachedENIs: 1, ReservedVolumeAttachments: 1},
+			expectedErr: "--instance-type must be specified",
+		},
+		{
+			name:        "malformed instance type",
+			options:     Options{InstanceType: "garbage", AttachedENIs: 1, ReservedVolumeAttachments: 1},
+			expectedErr: `--instance-type: invalid instance type "garbage": expected <family>.<size>`,
+		},
+		{
+			name:        "instance type without size",
+			options:     Options{InstanceType: "m5.", AttachedENIs: 1, ReservedVolumeAttachments: 1},
+			expectedErr: `--instance-type: invalid instance type "m5.": expected <family>.<size>`,
+		},
+		{
+			name:        "no ENIs",
+			options:     Options{InstanceType: "m5.large", AttachedENIs: 0, ReservedVolumeAttachments: 1},
+			expectedErr: "--attached-enis must be at least 1",
+		},
+		{
+			name:        "negative reserved attachments",
+			options:     Options{InstanceType: "m5.large", AttachedENIs: 1, ReservedVolumeAttachments: -1},
+			expectedErr: "--reserved-volume-attachments must not be negative",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var out bytes.Buffer
+			err := Run(&out, &tc.options)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
@@ -31,6 +31,10 @@ func main() {
 	familyTypes := make(map[string]map[string]bool)
 
 	for _, instanceType := range limits.KnownInstanceTypes() {
+		// Non-Nitro instance types are always treated as dedicated, regardless of their family
+		if nitro, err := limits.NitroInstanceType(instanceType); err != nil || !nitro {
+			continue
+		}
 		family := strings.Split(instanceType, ".")[0]
 
 		_, attachmentType := limits.GetVolumeLimits(instanceType)
@@ -22,7 +22,9 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
+	"net/http"
 	"os"
 	"regexp"
 	"slices"
@@ -33,6 +35,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -155,6 +158,11 @@ const (
 	// Minimizes RPC latency and EC2 API calls. Tuned via scalability tests.
 	batchMaxDelay = 500 * time.Millisecond
 
+	// DefaultVolumeBatchMaxEntries is the maximum number of volumes DescribeVolumes returns without pagination.
+	DefaultVolumeBatchMaxEntries = 500
+	// DefaultVolumeBatchMaxDelay is how long DescribeVolumes requests are collected before a batch is sent.
+	DefaultVolumeBatchMaxDelay = batchMaxDelay
+
 	// Tuned for EC2 DescribeVolumeStatus -- as of July 2025 it takes up to 5 min for initialization info to be updated.
 	slowVolumeStatusBatchMaxDelay = 2 * time.Minute
 	fastVolumeStatusBatchMaxDelay = 500 * time.Millisecond
@@ -193,6 +201,9 @@ var (
 	// ErrInvalidMaxResults is returned when a MaxResults pagination parameter is between 1 and 4.
 	ErrInvalidMaxResults = errors.New("maxResults parameter must be 0 or greater than or equal to 5")
 
+	// ErrVolumeInErrorState is returned when a newly created volume enters the "error" state.
+	ErrVolumeInErrorState = errors.New("volume is in error state")
+
 	// ErrVolumeNotBeingModified is returned if volume being described is not being modified.
 	ErrVolumeNotBeingModified = errors.New("volume is not being modified")
 
@@ -204,6 +215,17 @@ var (
 
 	// ErrLimitExceeded is returned if a user exceeds a quota.
 	ErrLimitExceeded = errors.New("limit exceeded")
+
+	// ErrVolumeInUse is returned if a volume cannot be attached because it is attached to another instance.
+	ErrVolumeInUse = errors.New("volume is in use")
+
+	// ErrDeviceNamesExhausted is returned if no device name is left to attach a volume with,
+	// even though the instance may not have reached its attachment limit.
+	ErrDeviceNamesExhausted = errors.New("device names exhausted")
+
+	// errIncompleteCreateVolumeResponse is returned by createVolumeHelper if CreateVolume succeeds
+	// without returning the ID of the created volume.
+	errIncompleteCreateVolumeResponse = errors.New("CreateVolume response does not contain a volume ID")
 )
 
 // Set during build time via -ldflags.
@@ -250,6 +272,7 @@ type Disk struct {
 	SnapshotID         string
 	OutpostArn         string
 	KmsKeyID           string
+	VolumeType         string
 	Attachments        []string
 }
 
@@ -291,6 +314,13 @@ type iopsLimits struct {
 	maxIopsPerGb int32
 }
 
+// instanceTypeMaxIOPS is the result of looking up the maximum IOPS of an instance type. Failed lookups are
+// cached too, so that instance types the API cannot describe are not looked up for every volume.
+type instanceTypeMaxIOPS struct {
+	maxIOPS int32
+	err     error
+}
+
 // getVolumeLimitsParams represents the AZ parameters that getVolumeLimits will use to make the DryRun CreateVolume call.
 type getVolumeLimitsParams struct {
 	availabilityZone   string
@@ -323,6 +353,9 @@ type ListSnapshotsResponse struct {
 type SnapshotOptions struct {
 	Tags       map[string]string
 	OutpostArn string
+	// WaitForVolumeModification makes CreateSnapshot wait for an in-progress modification of the
+	// source volume to finish, instead of only logging a warning.
+	WaitForVolumeModification bool
 }
 
 // SnapshotLockOptions represents parameters to lock an EBS snapshot.
@@ -412,13 +445,55 @@ type cloud struct {
 	volumeInitializations expiringcache.ExpiringCache[string, volumeInitialization]
 	latestIOPSLimits      expiringcache.ExpiringCache[string, iopsLimits]
 	cardCountCache        expiringcache.ExpiringCache[string, int]
+	nitroCache            expiringcache.ExpiringCache[string, bool]
+	maxIOPSCache          expiringcache.ExpiringCache[string, instanceTypeMaxIOPS]
 	accountID             string
 	accountIDOnce         sync.Once
 	attemptDryRun         atomic.Bool
+	// volumeTypeAttachmentLimits caps the number of volumes of a volume type attached to an instance,
+	// independent of the attachment limit of the instance.
+	volumeTypeAttachmentLimits map[string]int
+	// detaching holds the volumes being detached by DetachDisk, which keep their attachment slot until
+	// EC2 reports them as detached.
+	detaching detachingVolumes
 }
 
 var _ Cloud = &cloud{}
 
+// detachingVolumes tracks the volumes being detached from each node. The zero value is ready to use.
+type detachingVolumes struct {
+	mux     sync.Mutex
+	volumes map[string]map[string]struct{}
+}
+
+func (d *detachingVolumes) add(nodeID, volumeID string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.volumes == nil {
+		d.volumes = make(map[string]map[string]struct{})
+	}
+	if d.volumes[nodeID] == nil {
+		d.volumes[nodeID] = make(map[string]struct{})
+	}
+	d.volumes[nodeID][volumeID] = struct{}{}
+}
+
+func (d *detachingVolumes) del(nodeID, volumeID string) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	delete(d.volumes[nodeID], volumeID)
+	if len(d.volumes[nodeID]) == 0 {
+		delete(d.volumes, nodeID)
+	}
+}
+
+// get returns the volumes being detached from nodeID.
+func (d *detachingVolumes) get(nodeID string) []string {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return slices.Collect(maps.Keys(d.volumes[nodeID]))
+}
+
 // initVariables initializes variables that depend on driver name.
 // Separated into a separate function from NewCloud so it can be called in tests.
 func initVariables() {
@@ -427,21 +502,81 @@ func initVariables() {
 	IOPSPerGBKey = util.GetDriverName() + "/IOPSPerGb"
 }
 
+// HTTPTransportOptions tunes how the AWS SDK clients reach the AWS APIs.
+// Zero values keep the AWS SDK defaults.
+type HTTPTransportOptions struct {
+	// MaxIdleConnsPerHost is the maximum number of idle connections kept open per host.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept open before it is closed.
+	IdleConnTimeout time.Duration
+	// EC2Endpoint is the URL of the EC2 API, for example of a proxy in front of it.
+	// Takes precedence over the AWS_EC2_ENDPOINT environment variable.
+	EC2Endpoint string
+}
+
+// BatchingOptions configures the batching of EC2 Describe* API calls.
+type BatchingOptions struct {
+	// Enabled turns on batching. When false, every request results in its own EC2 API call.
+	Enabled bool
+	// VolumeMaxEntries is the maximum number of volumes looked up by a single DescribeVolumes call.
+	// Zero uses DefaultVolumeBatchMaxEntries.
+	VolumeMaxEntries int
+	// VolumeMaxDelay is how long DescribeVolumes requests are collected before a batch is sent.
+	// Zero uses DefaultVolumeBatchMaxDelay.
+	VolumeMaxDelay time.Duration
+}
+
+// CloudOptions configures the cloud returned by NewCloud.
+// Zero values keep the defaults.
+type CloudOptions struct {
+	// AwsSdkDebugLog logs the body of every AWS API request and response.
+	AwsSdkDebugLog bool
+	// UserAgentExtra is appended to the user agent of every AWS API request.
+	UserAgentExtra string
+	// Batching configures the batching of EC2 Describe* API calls.
+	Batching BatchingOptions
+	// DeprecatedMetrics also emits the metrics under their deprecated names.
+	DeprecatedMetrics bool
+	// Transport tunes how the AWS SDK clients reach the AWS APIs.
+	Transport HTTPTransportOptions
+	// ReservedDeviceNames are device names never assigned to attached volumes.
+	ReservedDeviceNames []string
+	// VolumeTypeAttachmentLimits caps the number of volumes of a volume type attached to an instance.
+	VolumeTypeAttachmentLimits map[string]int
+}
+
+// newHTTPClient returns the HTTP client used by the AWS SDK clients with transportOptions applied
+// on top of the AWS SDK defaults.
+func newHTTPClient(transportOptions HTTPTransportOptions) *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if transportOptions.MaxIdleConnsPerHost > 0 {
+			tr.MaxIdleConnsPerHost = transportOptions.MaxIdleConnsPerHost
+			// MaxIdleConns caps idle connections across all hosts, so it must not be lower
+			tr.MaxIdleConns = max(tr.MaxIdleConns, transportOptions.MaxIdleConnsPerHost)
+		}
+		if transportOptions.IdleConnTimeout > 0 {
+			tr.IdleConnTimeout = transportOptions.IdleConnTimeout
+		}
+	})
+}
+
 // NewCloud returns a new instance of AWS cloud
 // It panics if session is invalid.
-func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batchingEnabled bool, deprecatedMetrics bool) Cloud {
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+func NewCloud(region string, options CloudOptions) Cloud {
+	// The HTTP client is passed to LoadDefaultConfig (instead of being set on the config afterwards)
+	// so that settings such as a custom CA bundle are applied to it
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region), config.WithHTTPClient(newHTTPClient(options.Transport)))
 	if err != nil {
 		panic(err)
 	}
 
-	if awsSdkDebugLog {
+	if options.AwsSdkDebugLog {
 		cfg.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 	}
 
 	// Set the env var so that the session appends custom user agent string
-	if userAgentExtra != "" {
-		if err := os.Setenv("AWS_EXECUTION_ENV", "aws-ebs-csi-driver-"+driverVersion+"-"+userAgentExtra); err != nil {
+	if options.UserAgentExtra != "" {
+		if err := os.Setenv("AWS_EXECUTION_ENV", "aws-ebs-csi-driver-"+driverVersion+"-"+options.UserAgentExtra); err != nil {
 			klog.ErrorS(err, "Failed to set AWS_EXECUTION_ENV")
 		}
 	} else {
@@ -452,11 +587,14 @@ func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batchin
 
 	ec2Options := func(o *ec2.Options) {
 		o.APIOptions = append(o.APIOptions,
-			RecordRequestsMiddleware(deprecatedMetrics),
+			RecordRequestsMiddleware(options.DeprecatedMetrics),
 			LogServerErrorsMiddleware(), // This middlware should always be last so it sees an unmangled error
 		)
 
 		endpoint := os.Getenv("AWS_EC2_ENDPOINT")
+		if options.Transport.EC2Endpoint != "" {
+			endpoint = options.Transport.EC2Endpoint
+		}
 		if endpoint != "" {
 			o.BaseEndpoint = &endpoint
 		}
@@ -489,14 +627,14 @@ func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batchin
 	}
 
 	var bm *batcherManager
-	if batchingEnabled {
+	if options.Batching.Enabled {
 		klog.V(4).InfoS("NewCloud: batching enabled")
-		bm = newBatcherManager(ec2Client)
+		bm = newBatcherManager(ec2Client, options.Batching)
 	}
 	c := &cloud{
 		awsConfig:             cfg,
 		region:                region,
-		dm:                    dm.NewDeviceManager(),
+		dm:                    dm.NewDeviceManager(options.ReservedDeviceNames...),
 		ec2:                   ec2Client,
 		sm:                    smClient,
 		bm:                    bm,
@@ -507,6 +645,10 @@ func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batchin
 		volumeInitializations: expiringcache.New[string, volumeInitialization](volInitCacheForgetDelay),
 		latestIOPSLimits:      expiringcache.New[string, iopsLimits](iopsLimitCacheForgetDelay),
 		cardCountCache:        expiringcache.New[string, int](cacheForgetDelay),
+		nitroCache:            expiringcache.New[string, bool](cacheForgetDelay),
+		maxIOPSCache:          expiringcache.New[string, instanceTypeMaxIOPS](cacheForgetDelay),
+
+		volumeTypeAttachmentLimits: options.VolumeTypeAttachmentLimits,
 	}
 
 	// Ensure an EC2 Dry-run API call is made on startup and every dryRunInterval
@@ -524,16 +666,26 @@ func NewCloud(region string, awsSdkDebugLog bool, userAgentExtra string, batchin
 // newBatcherManager initializes a new instance of batcherManager.
 // Each batcher's `entries` set to maximum results returned by relevant EC2 API call without pagination.
 // Each batcher's `delay` minimizes RPC latency and EC2 API calls. Tuned via scalability tests.
-func newBatcherManager(svc util.EC2API) *batcherManager {
+// The DescribeVolumes batchers can be tuned with batchingOptions.
+func newBatcherManager(svc util.EC2API, batchingOptions BatchingOptions) *batcherManager {
+	volumeMaxEntries := DefaultVolumeBatchMaxEntries
+	if batchingOptions.VolumeMaxEntries > 0 {
+		volumeMaxEntries = batchingOptions.VolumeMaxEntries
+	}
+	volumeMaxDelay := DefaultVolumeBatchMaxDelay
+	if batchingOptions.VolumeMaxDelay > 0 {
+		volumeMaxDelay = batchingOptions.VolumeMaxDelay
+	}
+
 	likelyNotFoundInstanceIDs := expiringcache.New[string, struct{}](cacheForgetDelay)
 	likelyNotFoundVolumeIDs := expiringcache.New[string, struct{}](cacheForgetDelay)
 	likelyNotFoundSnapshotIDs := expiringcache.New[string, struct{}](cacheForgetDelay)
 
 	return &batcherManager{
-		volumeIDBatcher: batcher.New(500, batchMaxDelay, func(ids []string) (map[string]*types.Volume, error) {
+		volumeIDBatcher: batcher.New(volumeMaxEntries, volumeMaxDelay, func(ids []string) (map[string]*types.Volume, error) {
 			return execBatchDescribeVolumes(svc, ids, volumeIDBatcher, likelyNotFoundVolumeIDs)
 		}),
-		volumeTagBatcher: batcher.New(500, batchMaxDelay, func(names []string) (map[string]*types.Volume, error) {
+		volumeTagBatcher: batcher.New(volumeMaxEntries, volumeMaxDelay, func(names []string) (map[string]*types.Volume, error) {
 			return execBatchDescribeVolumes(svc, names, volumeTagBatcher, likelyNotFoundVolumeIDs)
 		}),
 		instanceIDBatcher: batcher.New(50, batchMaxDelay, func(ids []string) (map[string]*types.Instance, error) {
@@ -732,6 +884,10 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 		return nil, errors.New("CreateDisk: multi-attach is only supported for io2 volumes")
 	}
 
+	if diskOptions.Throughput > 0 && !strings.EqualFold(createType, VolumeTypeGP3) {
+		return nil, fmt.Errorf("%w: throughput is only supported for %s volumes, not %s", ErrInvalidArgument, VolumeTypeGP3, createType)
+	}
+
 	tags := make([]types.Tag, 0, len(diskOptions.Tags))
 	for key, value := range diskOptions.Tags {
 		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
@@ -809,53 +965,42 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 	if err != nil {
 		switch {
 		case isAWSErrorSnapshotNotFound(err):
-			return nil, ErrSourceNotFound
+			return nil, withRequestID(ErrSourceNotFound, err)
 		case isAWSErrorVolumeNotFound(err):
-			return nil, ErrSourceNotFound
+			return nil, withRequestID(ErrSourceNotFound, err)
 		case isAWSErrorIdempotentParameterMismatch(err):
-			nextTokenNumber := 2
-			if tokenNumber, ok := c.latestClientTokens.Get(volumeName); ok {
-				nextTokenNumber = *tokenNumber + 1
-			}
-			c.latestClientTokens.Set(volumeName, &nextTokenNumber)
-			return nil, ErrIdempotentParameterMismatch
+			c.advanceClientToken(volumeName)
+			return nil, withRequestID(ErrIdempotentParameterMismatch, err)
 		case isAWSErrorInvalidParameterCombination(err):
 			return nil, fmt.Errorf("%w: %w", ErrInvalidArgument, err)
 		case isAWSErrorVolumeLimitExceeded(err):
 			// EC2 API does NOT handle idempotency correctly when a theoretical volume
 			// would put the caller over a limit for their account
 			//
-			// To avoid leaking volumes, make a DescribeVolumes call here
-			request := &ec2.DescribeVolumesInput{
-				Filters: []types.Filter{
-					{
-						Name:   aws.String("tag:" + VolumeNameTagKey),
-						Values: []string{volumeName},
-					},
-				},
-			}
-			// Call DescribeVolumes directly as there is a high chance this volume
-			// will return a NotFound error and would poison a batch call
-			volumes, describeErr := describeVolumes(ctx, c.ec2, request)
-			if describeErr != nil {
-				if isAWSErrorVolumeNotFound(describeErr) {
-					return nil, fmt.Errorf("%w: %w", ErrLimitExceeded, err)
-				} else {
-					return nil, describeErr
-				}
+			// To avoid leaking volumes, look up the volume by its name tag here
+			volume, findErr := c.findCreatedVolume(ctx, volumeName, capacityGiB, createType)
+			if findErr != nil {
+				return nil, findErr
 			}
-
-			// Volume with requested name exists, continue with it
-			if l := len(volumes); l > 1 {
-				return nil, ErrMultiDisks
-			} else if l < 1 {
-				// This should in theory be impossible, but if the API
-				// changes or breaks it would cause a panic, so handle it
+			if volume == nil {
 				return nil, fmt.Errorf("%w: %w", ErrLimitExceeded, err)
 			}
-			volumeID = aws.ToString(volumes[0].VolumeId)
-			size = aws.ToInt32(volumes[0].Size)
-			outpostArn = aws.ToString(volumes[0].OutpostArn)
+			volumeID = aws.ToString(volume.VolumeId)
+			size = aws.ToInt32(volume.Size)
+			outpostArn = aws.ToString(volume.OutpostArn)
+		case errors.Is(err, errIncompleteCreateVolumeResponse):
+			// The volume was most likely created, find it instead of failing and creating a duplicate on retry
+			klog.InfoS("CreateDisk: CreateVolume did not return a volume ID, looking up the volume by its name tag", "volumeName", volumeName)
+			volume, findErr := c.findCreatedVolume(ctx, volumeName, capacityGiB, createType)
+			if findErr != nil {
+				return nil, findErr
+			}
+			if volume == nil {
+				return nil, fmt.Errorf("could not create volume in EC2: %w", err)
+			}
+			volumeID = aws.ToString(volume.VolumeId)
+			size = aws.ToInt32(volume.Size)
+			outpostArn = aws.ToString(volume.OutpostArn)
 		case isAwsErrorMaxIOPSLimitExceeded(err):
 			return nil, fmt.Errorf("%w: %w", ErrLimitExceeded, err)
 		default:
@@ -873,6 +1018,22 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 
 	volume, err := c.waitForVolume(ctx, volumeID)
 	if err != nil {
+		if errors.Is(err, ErrVolumeInErrorState) {
+			// The volume will never become usable, delete it so the next attempt can create a new one
+			klog.InfoS("CreateDisk: volume entered error state after creation, deleting it", "volumeID", volumeID, "volumeName", volumeName)
+			if _, deleteErr := c.DeleteDisk(ctx, volumeID); deleteErr != nil && !errors.Is(deleteErr, ErrNotFound) {
+				// The next attempt gets the same volume back and tries to delete it again
+				klog.ErrorS(deleteErr, "CreateDisk: failed to delete volume in error state", "volumeID", volumeID)
+			} else {
+				// EC2 would return the deleted volume for the same client token
+				c.advanceClientToken(volumeName)
+			}
+			// The most common cause for encrypted volumes is a KMS key that EBS is not allowed to use on behalf of the driver
+			if diskOptions.KmsKeyID != "" {
+				return nil, fmt.Errorf("failed to create volume %s, check that the key policy of KMS key %q allows the driver's IAM role to use the key and to create grants for AWS resources: %w", volumeID, diskOptions.KmsKeyID, err)
+			}
+			return nil, fmt.Errorf("failed to create volume %s: %w", volumeID, err)
+		}
 		return nil, fmt.Errorf("timed out waiting for volume to create: %w", err)
 	}
 
@@ -881,6 +1042,15 @@ func (c *cloud) CreateDisk(ctx context.Context, volumeName string, diskOptions *
 	return &Disk{CapacityGiB: size, VolumeID: volumeID, AvailabilityZone: zone, SnapshotID: diskOptions.SnapshotID, SourceVolumeID: diskOptions.SourceVolumeID, OutpostArn: outpostArn}, nil
 }
 
+// advanceClientToken makes the next CreateVolume call for volumeName use a new client token.
+func (c *cloud) advanceClientToken(volumeName string) {
+	nextTokenNumber := 2
+	if tokenNumber, ok := c.latestClientTokens.Get(volumeName); ok {
+		nextTokenNumber = *tokenNumber + 1
+	}
+	c.latestClientTokens.Set(volumeName, &nextTokenNumber)
+}
+
 func (c *cloud) createCloneHelper(ctx context.Context, input *ec2.CopyVolumesInput, iops int32, throughput int32) (int32, string, string, error) {
 	if iops > 0 {
 		input.Iops = aws.Int32(iops)
@@ -900,6 +1070,44 @@ func (c *cloud) createCloneHelper(ctx context.Context, input *ec2.CopyVolumesInp
 	return *copyResponse.Volumes[0].Size, aws.ToString(copyResponse.Volumes[0].OutpostArn), aws.ToString(copyResponse.Volumes[0].VolumeId), nil
 }
 
+// findCreatedVolume looks up a volume created by CreateDisk through its name tag, for when the result of
+// the CreateVolume call is unknown. It returns nil if no volume has the name.
+func (c *cloud) findCreatedVolume(ctx context.Context, volumeName string, capacityGiB int32, volumeType string) (*types.Volume, error) {
+	request := &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("tag:" + VolumeNameTagKey),
+				Values: []string{volumeName},
+			},
+		},
+	}
+	// Call DescribeVolumes directly as there is a high chance this volume
+	// will return a NotFound error and would poison a batch call
+	volumes, err := describeVolumes(ctx, c.ec2, request)
+	if err != nil {
+		if isAWSErrorVolumeNotFound(err) {
+			return nil, nil //nolint:nilnil // Not finding the volume is an expected outcome
+		}
+		return nil, err
+	}
+
+	switch l := len(volumes); {
+	case l > 1:
+		return nil, ErrMultiDisks
+	case l < 1:
+		// This should in theory be impossible, but if the API
+		// changes or breaks it would cause a panic, so handle it
+		return nil, nil //nolint:nilnil // Not finding the volume is an expected outcome
+	}
+	// The existing volume was not returned through the client token, so EC2 did not check
+	// that it was created with the same parameters as this request
+	if aws.ToInt32(volumes[0].Size) != capacityGiB || string(volumes[0].VolumeType) != volumeType {
+		klog.InfoS("CreateDisk: existing volume does not match requested parameters", "volumeName", volumeName, "volumeID", aws.ToString(volumes[0].VolumeId), "size", aws.ToInt32(volumes[0].Size), "requestedSize", capacityGiB, "volumeType", volumes[0].VolumeType, "requestedVolumeType", volumeType)
+		return nil, ErrIdempotentParameterMismatch
+	}
+	return &volumes[0], nil
+}
+
 func (c *cloud) createVolumeHelper(ctx context.Context, diskOptions *DiskOptions, input *ec2.CreateVolumeInput, iops int32, throughput int32, zone string, zoneID string) (int32, string, string, error) {
 	if len(zone) > 0 {
 		input.AvailabilityZone = aws.String(zone)
@@ -937,7 +1145,10 @@ func (c *cloud) createVolumeHelper(ctx context.Context, diskOptions *DiskOptions
 	if err != nil {
 		return 0, "", "", err
 	}
-	return *createResponse.Size, aws.ToString(createResponse.OutpostArn), aws.ToString(createResponse.VolumeId), nil
+	if aws.ToString(createResponse.VolumeId) == "" {
+		return 0, "", "", errIncompleteCreateVolumeResponse
+	}
+	return aws.ToInt32(createResponse.Size), aws.ToString(createResponse.OutpostArn), aws.ToString(createResponse.VolumeId), nil
 }
 
 // execBatchDescribeVolumesModifications executes a batched DescribeVolumesModifications API call.
@@ -1048,6 +1259,11 @@ func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSize
 		return 0, err
 	}
 
+	// EBS volumes cannot be shrunk, reject before asking EC2 to modify the volume
+	if newSizeBytes != 0 && volume.Size != nil && newSizeGiB < *volume.Size {
+		return 0, fmt.Errorf("%w: volume %q cannot be shrunk from %d GiB to %d GiB", ErrInvalidArgument, volumeID, *volume.Size, newSizeGiB)
+	}
+
 	needsModification, volumeSize, err := c.validateVolumeState(ctx, volumeID, newSizeGiB, *volume.Size, options)
 	if err != nil || !needsModification {
 		return volumeSize, err
@@ -1069,6 +1285,9 @@ func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSize
 		volTypeToUse = req.VolumeType
 	}
 	if options.Throughput != 0 {
+		if !strings.EqualFold(string(volTypeToUse), VolumeTypeGP3) {
+			return 0, fmt.Errorf("%w: throughput is only supported for %s volumes, not %s", ErrInvalidArgument, VolumeTypeGP3, volTypeToUse)
+		}
 		req.Throughput = aws.Int32(options.Throughput)
 	}
 
@@ -1092,6 +1311,11 @@ func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSize
 	case iopsPerGbVal > 0 && (options.AllowIopsIncreaseOnResize || allowAutoIncreaseIsSet):
 		iopsForModify = sizeToUse * iopsPerGbVal
 	}
+	// Migrating io1 to io2 keeps the provisioned IOPS unless others were explicitly requested
+	io1ToIO2 := volume.VolumeType == types.VolumeTypeIo1 && strings.EqualFold(string(volTypeToUse), VolumeTypeIO2)
+	if io1ToIO2 && iopsForModify == 0 && volume.Iops != nil {
+		iopsForModify = *volume.Iops
+	}
 	if iopsForModify != 0 {
 		azParams := getVolumeLimitsParams{}
 
@@ -1106,6 +1330,12 @@ func (c *cloud) ResizeOrModifyDisk(ctx context.Context, volumeID string, newSize
 			azParams.outpostArn = *volume.OutpostArn
 		}
 		iopsLimits := c.getVolumeLimits(ctx, string(volTypeToUse), azParams)
+		if io1ToIO2 {
+			// Reject IOPS that io2 does not support at this size instead of silently capping them
+			if err := validateIOPS(VolumeTypeIO2, sizeToUse, iopsForModify, iopsLimits); err != nil {
+				return 0, err
+			}
+		}
 		req.Iops = aws.Int32(capIOPS(string(volTypeToUse), sizeToUse, iopsForModify, iopsLimits, true))
 		options.IOPS = *req.Iops
 	}
@@ -1149,7 +1379,7 @@ func (c *cloud) DeleteDisk(ctx context.Context, volumeID string) (bool, error) {
 		o.Retryer = c.rm.deleteVolumeRetryer
 	}); err != nil {
 		if isAWSErrorVolumeNotFound(err) {
-			return false, ErrNotFound
+			return false, withRequestID(ErrNotFound, err)
 		}
 		return false, fmt.Errorf("DeleteDisk could not delete volume: %w", err)
 	}
@@ -1267,6 +1497,108 @@ func (c *cloud) getCardCount(ctx context.Context, instanceType string) int {
 	return cards
 }
 
+// IsNitroInstanceType reports whether the instance type is built on the Nitro System based
+// on the hypervisor returned by DescribeInstanceTypes, using a cache to avoid repeated API calls.
+// Falls back to the static table if the API call fails or does not report a hypervisor
+// (as is the case for bare metal instances).
+func (c *cloud) IsNitroInstanceType(ctx context.Context, instanceType string) bool {
+	if val, ok := c.nitroCache.Get(instanceType); ok {
+		return *val
+	}
+
+	resp, err := c.ec2.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		nitro := staticNitroInstanceType(instanceType)
+		klog.ErrorS(err, "Failed to describe instance type, falling back to static table", "instanceType", instanceType, "fallbackNitro", nitro)
+		c.nitroCache.Set(instanceType, &nitro)
+		return nitro
+	}
+
+	var nitro bool
+	switch {
+	case len(resp.InstanceTypes) > 0 && resp.InstanceTypes[0].Hypervisor == types.InstanceTypeHypervisorNitro:
+		nitro = true
+	case len(resp.InstanceTypes) > 0 && resp.InstanceTypes[0].Hypervisor == types.InstanceTypeHypervisorXen:
+		nitro = false
+	default:
+		nitro = staticNitroInstanceType(instanceType)
+		klog.V(4).InfoS("Hypervisor not reported by API, falling back to static table", "instanceType", instanceType, "bareMetal", limits.IsBareMetal(instanceType), "nitro", nitro)
+	}
+
+	c.nitroCache.Set(instanceType, &nitro)
+	return nitro
+}
+
+// staticNitroInstanceType looks up whether instanceType is built on the Nitro System in the static table.
+// Malformed instance types are assumed to be Nitro, like the instance types missing from the table.
+func staticNitroInstanceType(instanceType string) bool {
+	nitro, err := limits.NitroInstanceType(instanceType)
+	if err != nil {
+		klog.V(4).InfoS("Malformed instance type, assuming it is built on the Nitro System", "instanceType", instanceType, "err", err)
+		return true
+	}
+	return nitro
+}
+
+// GetInstanceTypeMaxIOPS returns the maximum EBS IOPS an instance type can deliver across all
+// of its attached volumes, as reported by DescribeInstanceTypes. Returns 0 when the API does not
+// report a maximum, for example for instance types that are not EBS optimized.
+func (c *cloud) GetInstanceTypeMaxIOPS(ctx context.Context, instanceType string) (int32, error) {
+	if val, ok := c.maxIOPSCache.Get(instanceType); ok {
+		return val.maxIOPS, val.err
+	}
+
+	resp, err := c.ec2.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		err = fmt.Errorf("could not describe instance type %s: %w", instanceType, err)
+		c.maxIOPSCache.Set(instanceType, &instanceTypeMaxIOPS{err: err})
+		return 0, err
+	}
+
+	var maxIOPS int32
+	if len(resp.InstanceTypes) > 0 {
+		info := resp.InstanceTypes[0]
+		if info.EbsInfo != nil && info.EbsInfo.EbsOptimizedInfo != nil {
+			maxIOPS = aws.ToInt32(info.EbsInfo.EbsOptimizedInfo.MaximumIops)
+		}
+	}
+
+	c.maxIOPSCache.Set(instanceType, &instanceTypeMaxIOPS{maxIOPS: maxIOPS})
+	return maxIOPS, nil
+}
+
+// GetInstanceTypeAcceleratorCount returns the number of GPUs and inference accelerators of an instance type,
+// as reported by DescribeInstanceTypes.
+func (c *cloud) GetInstanceTypeAcceleratorCount(ctx context.Context, instanceType string) (int, error) {
+	resp, err := c.ec2.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not describe instance type %s: %w", instanceType, err)
+	}
+	if len(resp.InstanceTypes) == 0 {
+		return 0, fmt.Errorf("%w: instance type %s", ErrNotFound, instanceType)
+	}
+
+	var count int
+	info := resp.InstanceTypes[0]
+	if info.GpuInfo != nil {
+		for _, gpu := range info.GpuInfo.Gpus {
+			count += int(aws.ToInt32(gpu.Count))
+		}
+	}
+	if info.InferenceAcceleratorInfo != nil {
+		for _, accelerator := range info.InferenceAcceleratorInfo.Accelerators {
+			count += int(aws.ToInt32(accelerator.Count))
+		}
+	}
+	return count, nil
+}
+
 func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string, error) {
 	if util.IsHyperPodNode(nodeID) {
 		return c.attachDiskHyperPod(ctx, volumeID, nodeID)
@@ -1277,6 +1609,10 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 		return "", err
 	}
 
+	if err = c.checkAttachmentLimits(ctx, volumeID, instance); err != nil {
+		return "", err
+	}
+
 	likelyBadDeviceNames, ok := c.likelyBadDeviceNames.Get(nodeID)
 	if !ok {
 		likelyBadDeviceNames = new(sync.Map)
@@ -1286,6 +1622,9 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 	numCards := c.getCardCount(ctx, string(instance.InstanceType))
 	device, err := c.dm.NewDevice(instance, volumeID, likelyBadDeviceNames, numCards)
 	if err != nil {
+		if errors.Is(err, dm.ErrNoDeviceNamesAvailable) {
+			return "", fmt.Errorf("%w: %w", ErrDeviceNamesExhausted, err)
+		}
 		return "", err
 	}
 	defer device.Release(false)
@@ -1311,7 +1650,16 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 				likelyBadDeviceNames.Store(device.Path, struct{}{})
 			}
 			if isAWSErrorAttachmentLimitExceeded(attachErr) {
-				return "", fmt.Errorf("%w: %w", ErrLimitExceeded, attachErr)
+				return "", fmt.Errorf("%w (%s): %w", ErrLimitExceeded, attachmentBudget(volumeID, instance), attachErr)
+			}
+			if isAWSErrorVolumeNotFound(attachErr) {
+				return "", fmt.Errorf("%w: %w", ErrNotFound, attachErr)
+			}
+			if isAWSErrorVolumeInUse(attachErr) {
+				return "", fmt.Errorf("%w: %w", ErrVolumeInUse, attachErr)
+			}
+			if isAWSErrorIncorrectState(attachErr) && c.isVolumeDeleted(ctx, volumeID) {
+				return "", fmt.Errorf("%w: volume %q is being deleted: %w", ErrNotFound, volumeID, attachErr)
 			}
 			return "", fmt.Errorf("could not attach volume %q to node %q: %w", volumeID, nodeID, attachErr)
 		}
@@ -1333,6 +1681,168 @@ func (c *cloud) AttachDisk(ctx context.Context, volumeID, nodeID string) (string
 	return device.Path, nil
 }
 
+// checkAttachmentLimits returns ErrLimitExceeded when attaching volumeID to instance would exceed the attachment
+// limit of the instance or the cap of its volume type in volumeTypeAttachmentLimits.
+// Volumes being detached by DetachDisk count against both, as they keep their attachment slot until EC2 reports
+// them as detached, even once DescribeInstances no longer returns them.
+func (c *cloud) checkAttachmentLimits(ctx context.Context, volumeID string, instance *types.Instance) error {
+	for _, bdm := range instance.BlockDeviceMappings {
+		if bdm.Ebs != nil && aws.ToString(bdm.Ebs.VolumeId) == volumeID {
+			// Already attached, AttachDisk only waits for the attachment
+			return nil
+		}
+	}
+
+	detaching, err := c.describeDetachingVolumes(ctx, volumeID, instance)
+	if err != nil {
+		return err
+	}
+	if err = checkInstanceAttachmentLimit(volumeID, instance, len(detaching)); err != nil {
+		return err
+	}
+	return c.checkVolumeTypeAttachmentLimit(ctx, volumeID, instance, detaching)
+}
+
+// describeDetachingVolumes returns the volumes DetachDisk is detaching from instance that are missing from its
+// block device mappings, but are still attached to it. Volumes that no longer exist or that are already
+// detached are left out. volumeID is never returned.
+func (c *cloud) describeDetachingVolumes(ctx context.Context, volumeID string, instance *types.Instance) ([]types.Volume, error) {
+	instanceID := aws.ToString(instance.InstanceId)
+	var volumeIDs []string
+	for _, detachingVolumeID := range c.detaching.get(instanceID) {
+		if detachingVolumeID == volumeID || slices.ContainsFunc(instance.BlockDeviceMappings, func(bdm types.InstanceBlockDeviceMapping) bool {
+			return bdm.Ebs != nil && aws.ToString(bdm.Ebs.VolumeId) == detachingVolumeID
+		}) {
+			continue
+		}
+		volumeIDs = append(volumeIDs, detachingVolumeID)
+	}
+	if len(volumeIDs) == 0 {
+		return nil, nil
+	}
+
+	// Filters, unlike VolumeIds, do not fail the whole call when one of the volumes no longer exists
+	resp, err := c.ec2.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("volume-id"), Values: volumeIDs},
+			{Name: aws.String("attachment.instance-id"), Values: []string{instanceID}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not describe the volumes being detached from node %q: %w", instanceID, err)
+	}
+	return resp.Volumes, nil
+}
+
+// checkInstanceAttachmentLimit returns ErrLimitExceeded when the detaching volumes still attached to instance
+// take up the last free attachment slots of its instance type.
+// When the instance is full without them, the attachment is left to EC2, which rejects it with its own figures.
+func checkInstanceAttachmentLimit(volumeID string, instance *types.Instance, detaching int) error {
+	if detaching == 0 {
+		return nil
+	}
+
+	instanceType := string(instance.InstanceType)
+	attachedEBS := 0
+	for _, bdm := range instance.BlockDeviceMappings {
+		if bdm.Ebs != nil {
+			attachedEBS++
+		}
+	}
+	// The primary ENI is always attached, even if it is missing from the instance
+	attachedENIs := max(len(instance.NetworkInterfaces), 1)
+
+	if canAttach, _ := limits.CanAttachAnotherVolume(instanceType, attachedEBS, attachedENIs); !canAttach {
+		return nil
+	}
+	if canAttach, reason := limits.CanAttachAnotherVolume(instanceType, attachedEBS+detaching, attachedENIs); !canAttach {
+		klog.InfoS("AttachDisk: attachment slots taken by volumes being detached", "volumeID", volumeID, "nodeID", aws.ToString(instance.InstanceId),
+			"instanceType", instanceType, "attached", attachedEBS, "detaching", detaching, "reason", reason)
+		return fmt.Errorf("%w: %d volumes are still being detached from node %q: %s", ErrLimitExceeded, detaching, aws.ToString(instance.InstanceId), reason)
+	}
+	return nil
+}
+
+// checkVolumeTypeAttachmentLimit returns ErrLimitExceeded when the volume type of volumeID has a cap in
+// volumeTypeAttachmentLimits and as many volumes of that type are already attached to instance or being detached from it.
+// The cap is checked on top of the attachment limit of the instance, which counts volumes of all types.
+func (c *cloud) checkVolumeTypeAttachmentLimit(ctx context.Context, volumeID string, instance *types.Instance, detaching []types.Volume) error {
+	if len(c.volumeTypeAttachmentLimits) == 0 {
+		return nil
+	}
+
+	volumeIDs := []string{volumeID}
+	for _, bdm := range instance.BlockDeviceMappings {
+		if bdm.Ebs != nil {
+			volumeIDs = append(volumeIDs, aws.ToString(bdm.Ebs.VolumeId))
+		}
+	}
+
+	resp, err := c.ec2.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: volumeIDs})
+	if err != nil {
+		if isAWSErrorVolumeNotFound(err) {
+			return fmt.Errorf("%w: %w", ErrNotFound, err)
+		}
+		return fmt.Errorf("could not describe the volumes attached to node %q: %w", aws.ToString(instance.InstanceId), err)
+	}
+	volumes := slices.Concat(resp.Volumes, detaching)
+
+	var volumeType types.VolumeType
+	for _, volume := range volumes {
+		if aws.ToString(volume.VolumeId) == volumeID {
+			volumeType = volume.VolumeType
+		}
+	}
+	maxAttachments, ok := c.volumeTypeAttachmentLimits[string(volumeType)]
+	if !ok {
+		return nil
+	}
+
+	attached := 0
+	for _, volume := range volumes {
+		if aws.ToString(volume.VolumeId) != volumeID && volume.VolumeType == volumeType {
+			attached++
+		}
+	}
+	if attached >= maxAttachments {
+		klog.InfoS("AttachDisk: volume type attachment cap reached", "volumeID", volumeID, "nodeID", aws.ToString(instance.InstanceId),
+			"volumeType", volumeType, "maxAttachments", maxAttachments, "attached", attached)
+		return fmt.Errorf("%w: %d of %d %s volumes allowed on node %q are already attached", ErrLimitExceeded, attached, maxAttachments, volumeType, aws.ToString(instance.InstanceId))
+	}
+	return nil
+}
+
+// attachmentBudget logs how the attachment limit of instance is used when attaching volumeID exceeded it,
+// and returns a summary of the figures for the error returned to the CO.
+// The figures are the driver's estimate from the limits tables, EC2 is what rejected the attachment.
+func attachmentBudget(volumeID string, instance *types.Instance) string {
+	instanceType := string(instance.InstanceType)
+	// The primary ENI is always attached, even if it is missing from the instance
+	attachedENIs := max(len(instance.NetworkInterfaces), 1)
+	vl := limits.GetVolumeLimit(instanceType, limits.GetInstanceStoreVolumeCount(instanceType), attachedENIs)
+	currentAttachments := len(instance.BlockDeviceMappings)
+
+	klog.InfoS("AttachDisk: attachment limit exceeded", "volumeID", volumeID, "nodeID", aws.ToString(instance.InstanceId),
+		"instanceType", instanceType, "attachmentType", vl.AttachmentType, "maxAttachments", vl.MaxAttachments,
+		"reservedSlots", vl.ReservedSlots(), "currentAttachments", currentAttachments, "usableLimit", vl.Limit)
+	return fmt.Sprintf("instance type %q: limit %d, reserved %d, usable %d, attached %d",
+		instanceType, vl.MaxAttachments, vl.ReservedSlots(), vl.Limit, currentAttachments)
+}
+
+// isVolumeDeleted reports whether the volume no longer exists or is in the "deleting" or "deleted" state.
+// Errors other than the volume not being found are logged and treated as the volume still existing.
+func (c *cloud) isVolumeDeleted(ctx context.Context, volumeID string) bool {
+	volume, err := c.getVolume(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || isAWSErrorVolumeNotFound(err) {
+			return true
+		}
+		klog.ErrorS(err, "Failed to describe volume after attach failure", "volumeID", volumeID)
+		return false
+	}
+	return volume.State == types.VolumeStateDeleting || volume.State == types.VolumeStateDeleted
+}
+
 func (c *cloud) attachDiskHyperPod(ctx context.Context, volumeID, nodeID string) (string, error) {
 	klog.V(2).InfoS("AttachDisk: HyperPod node detected", "volumeID", volumeID, "nodeID", nodeID)
 
@@ -1414,6 +1924,10 @@ func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
 		VolumeId:   aws.String(volumeID),
 	}
 
+	// The volume keeps its slot until WaitForAttachmentState sees it detached, or DetachDisk gives up
+	c.detaching.add(nodeID, volumeID)
+	defer c.detaching.del(nodeID, volumeID)
+
 	_, err = c.ec2.DetachVolume(ctx, request, func(o *ec2.Options) {
 		o.Retryer = c.rm.detachVolumeRetryer
 	})
@@ -1422,7 +1936,7 @@ func (c *cloud) DetachDisk(ctx context.Context, volumeID, nodeID string) error {
 			isAWSErrorInvalidAttachmentNotFound(err) ||
 			isAWSErrorVolumeNotFound(err) {
 			metrics.AsyncEC2Metrics().ClearDetachMetric(volumeID, nodeID)
-			return ErrNotFound
+			return withRequestID(ErrNotFound, err)
 		}
 		return fmt.Errorf("could not detach volume %q from node %q: %w", volumeID, nodeID, err)
 	}
@@ -1469,7 +1983,7 @@ func (c *cloud) detachDiskHyperPod(ctx context.Context, volumeID, nodeID string)
 			isAWSHyperPodErrorInvalidAttachmentNotFound(err) ||
 			isAWSHyperPodErrorVolumeNotFound(err) {
 			metrics.AsyncEC2Metrics().ClearDetachMetric(volumeID, nodeID)
-			return ErrNotFound
+			return withRequestID(ErrNotFound, err)
 		}
 		return fmt.Errorf("could not detach volume %q from node %q: %w", volumeID, nodeID, err)
 	}
@@ -1658,6 +2172,10 @@ func (c *cloud) WaitForAttachmentState(ctx context.Context, expectedState types.
 				}
 			}
 
+			// Retrying cannot succeed without the permissions or with the same parameters
+			if isAWSErrorUnauthorized(err) || isAWSErrorInvalidParameter(err) {
+				return false, fmt.Errorf("could not describe volume %q: %w", volumeID, err)
+			}
 			klog.InfoS("Ignoring error from describe volume, will retry", "volumeID", volumeID, "err", err)
 			return false, nil
 		}
@@ -1807,6 +2325,7 @@ func (c *cloud) GetDiskByID(ctx context.Context, volumeID string) (*Disk, error)
 		OutpostArn:       aws.ToString(volume.OutpostArn),
 		Attachments:      getVolumeAttachmentsList(*volume),
 		KmsKeyID:         aws.ToString(volume.KmsKeyId),
+		VolumeType:       string(volume.VolumeType),
 	}
 
 	if volume.Size != nil {
@@ -2016,6 +2535,11 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 	if snapshotOptions.OutpostArn != "" {
 		request.OutpostArn = aws.String(snapshotOptions.OutpostArn)
 	}
+
+	if err := c.handleVolumeModificationBeforeSnapshot(ctx, volumeID, snapshotOptions.WaitForVolumeModification); err != nil {
+		return nil, err
+	}
+
 	res, err := c.ec2.CreateSnapshot(ctx, request, func(o *ec2.Options) {
 		o.Retryer = c.rm.createSnapshotRetryer
 	})
@@ -2038,6 +2562,34 @@ func (c *cloud) CreateSnapshot(ctx context.Context, volumeID string, snapshotOpt
 	}, nil
 }
 
+// handleVolumeModificationBeforeSnapshot detects whether the volume is being modified and, if so,
+// either waits for the modification to reach a stable state or logs a warning and lets the snapshot proceed.
+func (c *cloud) handleVolumeModificationBeforeSnapshot(ctx context.Context, volumeID string, waitForModification bool) error {
+	m, err := c.getLatestVolumeModification(ctx, volumeID, true)
+	if err != nil {
+		if !errors.Is(err, ErrVolumeNotBeingModified) {
+			klog.ErrorS(err, "Could not determine whether volume is being modified, proceeding with snapshot", "volumeID", volumeID)
+		}
+		return nil
+	}
+
+	state := string(m.ModificationState)
+	if volumeModificationDone(state) || state == string(types.VolumeModificationStateFailed) {
+		return nil
+	}
+
+	if !waitForModification {
+		klog.Warningf("Creating snapshot of volume %s while it is being modified (state %s), the snapshot may not reflect the requested modification", volumeID, state)
+		return nil
+	}
+
+	klog.V(4).InfoS("Waiting for volume modification to finish before creating snapshot", "volumeID", volumeID, "modificationState", state)
+	if err := c.waitForVolumeModification(ctx, volumeID); err != nil {
+		return fmt.Errorf("failed to wait for modification of volume %s before creating snapshot: %w", volumeID, err)
+	}
+	return nil
+}
+
 func (c *cloud) LockSnapshot(ctx context.Context, lockOptions *SnapshotLockOptions) error {
 	lockSnapshotInput := ec2.LockSnapshotInput{
 		SnapshotId:     lockOptions.SnapshotId,
@@ -2061,7 +2613,7 @@ func (c *cloud) DeleteSnapshot(ctx context.Context, snapshotID string) (success
 		o.Retryer = c.rm.deleteSnapshotRetryer
 	}); err != nil {
 		if isAWSErrorSnapshotNotFound(err) {
-			return false, ErrNotFound
+			return false, withRequestID(ErrNotFound, err)
 		}
 		return false, fmt.Errorf("DeleteSnapshot could not delete snapshot: %w", err)
 	}
@@ -2274,7 +2826,7 @@ func (c *cloud) getInstance(ctx context.Context, nodeID string) (*types.Instance
 		instances, err := describeInstances(ctx, c.ec2, request)
 		if err != nil {
 			if isAWSErrorInstanceNotFound(err) {
-				return nil, ErrNotFound
+				return nil, withRequestID(ErrNotFound, err)
 			}
 			return nil, err
 		}
@@ -2324,7 +2876,7 @@ func (c *cloud) getInstancesPatchingBatch(ctx context.Context, nodeIDs []string)
 		response, err := c.ec2.DescribeInstances(ctx, request)
 		if err != nil {
 			if isAWSErrorInstanceNotFound(err) {
-				return nil, ErrNotFound
+				return nil, withRequestID(ErrNotFound, err)
 			}
 			return nil, fmt.Errorf("error listing AWS instances: %w", err)
 		}
@@ -2404,6 +2956,7 @@ func (c *cloud) listSnapshots(ctx context.Context, request *ec2.DescribeSnapshot
 }
 
 // waitForVolume waits for volume to be in the "available" state.
+// Returns ErrVolumeInErrorState if the volume enters the "error" state.
 func (c *cloud) waitForVolume(ctx context.Context, volumeID string) (*types.Volume, error) {
 	time.Sleep(c.vwp.creationInitialDelay)
 
@@ -2421,6 +2974,9 @@ func (c *cloud) waitForVolume(ctx context.Context, volumeID string) (*types.Volu
 			volume = vol
 			return true, nil
 		}
+		if vol.State == types.VolumeStateError {
+			return true, ErrVolumeInErrorState
+		}
 		return false, nil
 	})
 
@@ -2475,6 +3031,17 @@ func (c *cloud) getAccountID(ctx context.Context) (string, error) {
 	}
 }
 
+// withRequestID returns sentinel annotated with the AWS request ID of err, if it has one.
+// Use it when translating an AWS error into one of the errors above, so that the ID needed
+// to investigate the failed call with AWS support is not lost.
+func withRequestID(sentinel error, err error) error {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.ServiceRequestID() != "" {
+		return fmt.Errorf("%w (request ID: %s)", sentinel, respErr.ServiceRequestID())
+	}
+	return sentinel
+}
+
 // isAWSError returns a boolean indicating whether the error is AWS-related
 // and has the given code. More information on AWS error codes at:
 // https://docs.aws.amazon.com/AWSEC2/latest/APIReference/errors-overview.html
@@ -2509,6 +3076,12 @@ func isAWSErrorIncorrectState(err error) bool {
 	return isAWSError(err, "IncorrectState")
 }
 
+// isAWSErrorVolumeInUse returns a boolean indicating whether the given error is an AWS VolumeInUse error.
+// This error is reported when attaching a volume that is attached to another instance.
+func isAWSErrorVolumeInUse(err error) bool {
+	return isAWSError(err, "VolumeInUse")
+}
+
 // isAWSErrorInvalidAttachmentNotFound returns a boolean indicating whether the
 // given error is an AWS InvalidAttachment.NotFound error. This error is reported
 // when attempting to detach a volume from an instance to which it is not attached.
@@ -2640,6 +3213,13 @@ func isAwsErrorSnapshotLimitExceeded(err error) bool {
 	return isAWSError(err, "SnapshotLimitExceeded")
 }
 
+// isAWSErrorUnauthorized returns a boolean indicating whether the given error is an AWS
+// UnauthorizedOperation or AuthFailure error. These errors are reported when the caller
+// is not allowed to make the request.
+func isAWSErrorUnauthorized(err error) bool {
+	return isAWSError(err, "UnauthorizedOperation") || isAWSError(err, "AuthFailure")
+}
+
 // isAWSErrorInvalidParameter returns a boolean indicating whether the
 // given error is caused by invalid parameters in a EC2 API request.
 func isAWSErrorInvalidParameter(err error) bool {
@@ -2682,6 +3262,16 @@ func (c *cloud) checkDesiredState(ctx context.Context, volumeID string, desiredS
 	return realSizeGiB, nil
 }
 
+// GetVolumeModificationProgress returns the state and the progress in percent of the latest modification of the volume.
+// ErrVolumeNotBeingModified is returned if the volume has never been modified.
+func (c *cloud) GetVolumeModificationProgress(ctx context.Context, volumeID string) (string, int, error) {
+	m, err := c.getLatestVolumeModification(ctx, volumeID, true)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(m.ModificationState), int(aws.ToInt64(m.Progress)), nil
+}
+
 // waitForVolumeModification waits for a volume modification to finish.
 func (c *cloud) waitForVolumeModification(ctx context.Context, volumeID string) error {
 	waitErr := wait.ExponentialBackoff(c.vwp.modificationBackoff, func() (bool, error) {
@@ -2861,7 +3451,11 @@ func (c *cloud) validateVolumeState(ctx context.Context, volumeID string, newSiz
 
 	// latestMod can be nil if the volume has never been modified
 	if latestMod != nil && string(latestMod.ModificationState) == string(types.VolumeModificationStateModifying) {
-		// If volume is already modifying, detour to waiting for it to modify
+		// EC2 rejects a new modification while one is in progress, so only a retry of the same request can succeed
+		if !volumeModificationMatches(*latestMod, newSizeGiB, options) {
+			return false, oldSizeGiB, fmt.Errorf("volume %q is already being modified to a different target, cannot currently modify", volumeID)
+		}
+		// If volume is already modifying towards the requested target, detour to waiting for it to modify
 		klog.V(5).InfoS("[Debug] Watching ongoing modification", "volumeID", volumeID)
 		err = c.waitForVolumeModification(ctx, volumeID)
 		if err != nil {
@@ -2900,16 +3494,56 @@ func (c *cloud) validateModifyVolume(ctx context.Context, volumeID string, newSi
 	}
 
 	if latestMod != nil && string(latestMod.ModificationState) == string(types.VolumeModificationStateOptimizing) {
+		// The requested target has already been applied, the volume is only being optimized
+		if volumeModificationMatches(*latestMod, newSizeGiB, options) {
+			klog.V(5).InfoS("[Debug] Skipping modification for volume already optimizing towards the requested target", "volumeID", volumeID)
+			return false, aws.ToInt32(latestMod.TargetSize), nil
+		}
 		return true, 0, fmt.Errorf("volume %q in OPTIMIZING state, cannot currently modify", volumeID)
 	}
 
 	return true, 0, nil
 }
 
+// volumeModificationMatches reports whether the volume modification targets every parameter that is set in the request.
+func volumeModificationMatches(mod types.VolumeModification, newSizeGiB int32, options *ModifyDiskOptions) bool {
+	if newSizeGiB != 0 && aws.ToInt32(mod.TargetSize) < newSizeGiB {
+		return false
+	}
+	if options == nil {
+		return true
+	}
+	if options.IOPS != 0 && aws.ToInt32(mod.TargetIops) != options.IOPS {
+		return false
+	}
+	if options.Throughput != 0 && aws.ToInt32(mod.TargetThroughput) != options.Throughput {
+		return false
+	}
+	if options.VolumeType != "" && !strings.EqualFold(string(mod.TargetVolumeType), options.VolumeType) {
+		return false
+	}
+	return true
+}
+
 func volumeModificationDone(state string) bool {
 	return state == string(types.VolumeModificationStateCompleted) || state == string(types.VolumeModificationStateOptimizing)
 }
 
+// validateIOPS returns ErrInvalidArgument if the IOPS are outside of the limits supported by the volume type
+// at the given capacity. Any limit of 0 is considered "infinite" (i.e. is not applied).
+func validateIOPS(volumeType string, capacityGiB int32, iops int32, iopsLimits iopsLimits) error {
+	if iopsLimits.minIops > 0 && iops < iopsLimits.minIops {
+		return fmt.Errorf("%w: %d IOPS is below the minimum of %d IOPS for %s volumes", ErrInvalidArgument, iops, iopsLimits.minIops, volumeType)
+	}
+	if iopsLimits.maxIops > 0 && iops > iopsLimits.maxIops {
+		return fmt.Errorf("%w: %d IOPS exceeds the maximum of %d IOPS for %s volumes", ErrInvalidArgument, iops, iopsLimits.maxIops, volumeType)
+	}
+	if maxIopsByCapacity := iopsLimits.maxIopsPerGb * capacityGiB; maxIopsByCapacity > 0 && iops > maxIopsByCapacity {
+		return fmt.Errorf("%w: %d IOPS exceeds the maximum of %d IOPS for a %d GiB %s volume", ErrInvalidArgument, iops, maxIopsByCapacity, capacityGiB, volumeType)
+	}
+	return nil
+}
+
 // Calculate actual IOPS for a volume and cap it at supported AWS limits. Any limit of 0 is considered "infinite" (i.e. is not applied).
 func capIOPS(volumeType string, requestedCapacityGiB int32, requestedIops int32, iopsLimits iopsLimits, allowIncrease bool) int32 {
 	// If requestedIops is zero the user did not request a specific amount, and the default will be used instead
@@ -21,19 +21,24 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/sagemaker"
 	smtypes "github.com/aws/aws-sdk-go-v2/service/sagemaker/types"
 	"github.com/aws/smithy-go"
 	"github.com/aws/smithy-go/ptr"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/batcher"
 	dm "github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/devicemanager"
@@ -122,7 +127,12 @@ func TestNewCloud(t *testing.T) {
 		},
 	}
 	for _, tc := range testCases {
-		ec2Cloud := NewCloud(tc.region, tc.awsSdkDebugLog, tc.userAgentExtra, tc.batchingEnabled, tc.deprecatedMetrics)
+		ec2Cloud := NewCloud(tc.region, CloudOptions{
+			AwsSdkDebugLog:    tc.awsSdkDebugLog,
+			UserAgentExtra:    tc.userAgentExtra,
+			Batching:          BatchingOptions{Enabled: tc.batchingEnabled},
+			DeprecatedMetrics: tc.deprecatedMetrics,
+		})
 		ec2CloudAscloud, ok := ec2Cloud.(*cloud)
 		if !ok {
 			t.Fatalf("could not assert object ec2Cloud as cloud type, %v", ec2Cloud)
@@ -135,6 +145,87 @@ func TestNewCloud(t *testing.T) {
 		}
 	}
 }
+
+func TestNewHTTPClient(t *testing.T) {
+	testCases := []struct {
+		name                        string
+		transportOptions            HTTPTransportOptions
+		expectedMaxIdleConnsPerHost int
+		expectedMaxIdleConns        int
+		expectedIdleConnTimeout     time.Duration
+	}{
+		{
+			name:                        "success: zero values keep SDK defaults",
+			expectedMaxIdleConnsPerHost: awshttp.DefaultHTTPTransportMaxIdleConnsPerHost,
+			expectedMaxIdleConns:        awshttp.DefaultHTTPTransportMaxIdleConns,
+			expectedIdleConnTimeout:     awshttp.DefaultHTTPTransportIdleConnTimeout,
+		},
+		{
+			name: "success: custom values are applied",
+			transportOptions: HTTPTransportOptions{
+				MaxIdleConnsPerHost: 64,
+				IdleConnTimeout:     2 * time.Minute,
+			},
+			expectedMaxIdleConnsPerHost: 64,
+			expectedMaxIdleConns:        awshttp.DefaultHTTPTransportMaxIdleConns,
+			expectedIdleConnTimeout:     2 * time.Minute,
+		},
+		{
+			name: "success: MaxIdleConns is raised to MaxIdleConnsPerHost",
+			transportOptions: HTTPTransportOptions{
+				MaxIdleConnsPerHost: 256,
+			},
+			expectedMaxIdleConnsPerHost: 256,
+			expectedMaxIdleConns:        256,
+			expectedIdleConnTimeout:     awshttp.DefaultHTTPTransportIdleConnTimeout,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := newHTTPClient(tc.transportOptions).GetTransport()
+			assert.Equal(t, tc.expectedMaxIdleConnsPerHost, tr.MaxIdleConnsPerHost)
+			assert.Equal(t, tc.expectedMaxIdleConns, tr.MaxIdleConns)
+			assert.Equal(t, tc.expectedIdleConnTimeout, tr.IdleConnTimeout)
+		})
+	}
+}
+
+func TestNewCloudEC2Endpoint(t *testing.T) {
+	var actions []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		actions = append(actions, r.PostForm.Get("Action"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprint(w, `<DescribeInstanceTypesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">`+
+			`<instanceTypeSet><item><instanceType>m7i.large</instanceType></item></instanceTypeSet>`+
+			`</DescribeInstanceTypesResponse>`)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "test-access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret-key")
+	// The configured endpoint takes precedence over the environment variable
+	t.Setenv("AWS_EC2_ENDPOINT", "http://127.0.0.1:1")
+
+	c := NewCloud("us-east-1", CloudOptions{Transport: HTTPTransportOptions{EC2Endpoint: server.URL}}).(*cloud)
+	resp, err := c.ec2.DescribeInstanceTypes(t.Context(), &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceTypeM7iLarge},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.InstanceTypes, 1)
+	assert.Equal(t, types.InstanceTypeM7iLarge, resp.InstanceTypes[0].InstanceType)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"DescribeInstanceTypes"}, actions)
+}
+
 func TestBatchDescribeVolumes(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -238,7 +329,7 @@ func TestBatchDescribeVolumes(t *testing.T) {
 			if !ok {
 				t.Fatalf("could not assert cloudInstance as type cloud, %v", cloudInstance)
 			}
-			cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, BatchingOptions{})
 
 			tc.mockFunc(mockEC2, tc.expErr, tc.volumes)
 			volumeIDs, volumeNames := extractVolumeIdentifiers(tc.volumes)
@@ -246,6 +337,73 @@ func TestBatchDescribeVolumes(t *testing.T) {
 		})
 	}
 }
+func TestBatchDescribeVolumesOptions(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		batchingOptions BatchingOptions
+		volumes         int
+		expCalls        int
+	}{
+		{
+			name:            "success: requests within the delay are batched into one call",
+			batchingOptions: BatchingOptions{VolumeMaxDelay: 200 * time.Millisecond},
+			volumes:         10,
+			expCalls:        1,
+		},
+		{
+			name:            "success: batch is sent once max entries is reached",
+			batchingOptions: BatchingOptions{VolumeMaxEntries: 5, VolumeMaxDelay: time.Minute},
+			volumes:         10,
+			expCalls:        2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+			cloudInstance, ok := c.(*cloud)
+			if !ok {
+				t.Fatalf("could not assert cloudInstance as type cloud, %v", cloudInstance)
+			}
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, tc.batchingOptions)
+
+			mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).DoAndReturn(
+				func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+					// Return the volumes in reverse order to make sure results are matched by ID
+					volumes := make([]types.Volume, 0, len(input.VolumeIds))
+					for _, id := range slices.Backward(input.VolumeIds) {
+						volumes = append(volumes, types.Volume{VolumeId: aws.String(id)})
+					}
+					return &ec2.DescribeVolumesOutput{Volumes: volumes}, nil
+				}).Times(tc.expCalls)
+
+			var wg sync.WaitGroup
+			for i := range tc.volumes {
+				volumeID := fmt.Sprintf("vol-%d", i)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					volume, err := cloudInstance.batchDescribeVolumes(&ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}})
+					if err != nil {
+						t.Errorf("unexpected error for %s: %v", volumeID, err)
+						return
+					}
+					if aws.ToString(volume.VolumeId) != volumeID {
+						t.Errorf("expected volume %s, got %s", volumeID, aws.ToString(volume.VolumeId))
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
 func executeDescribeVolumesTest(t *testing.T, c *cloud, volumeIDs, volumeNames []string, expErr error) {
 	t.Helper()
 	var wg sync.WaitGroup
@@ -358,7 +516,7 @@ func TestBatchDescribeInstances(t *testing.T) {
 			if !ok {
 				t.Fatalf("could not assert cloudInstance as type cloud, %v", cloudInstance)
 			}
-			cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, BatchingOptions{})
 
 			// Setup mocks
 			var instances []types.Instance
@@ -536,7 +694,7 @@ func TestBatchDescribeSnapshots(t *testing.T) {
 			if !ok {
 				t.Fatalf("could not assert cloudInstance as type cloud, %v", cloudInstance)
 			}
-			cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, BatchingOptions{})
 
 			tc.mockFunc(mockEC2, tc.expErr, tc.snapshots)
 			snapshotIDs, snapshotNames := extractSnapshotIdentifiers(tc.snapshots)
@@ -710,6 +868,87 @@ func TestCheckDesiredState(t *testing.T) {
 	}
 }
 
+func TestGetVolumeModificationProgress(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name            string
+		modifications   []types.VolumeModification
+		describeErr     error
+		expectedState   string
+		expectedPercent int
+		expErr          error
+	}{
+		{
+			name: "success: optimizing",
+			modifications: []types.VolumeModification{
+				{VolumeId: aws.String("vol-test"), ModificationState: types.VolumeModificationStateOptimizing, Progress: aws.Int64(40)},
+			},
+			expectedState:   string(types.VolumeModificationStateOptimizing),
+			expectedPercent: 40,
+		},
+		{
+			name: "success: completed",
+			modifications: []types.VolumeModification{
+				{VolumeId: aws.String("vol-test"), ModificationState: types.VolumeModificationStateCompleted, Progress: aws.Int64(100)},
+			},
+			expectedState:   string(types.VolumeModificationStateCompleted),
+			expectedPercent: 100,
+		},
+		{
+			name: "success: failed",
+			modifications: []types.VolumeModification{
+				{VolumeId: aws.String("vol-test"), ModificationState: types.VolumeModificationStateFailed, Progress: aws.Int64(0)},
+			},
+			expectedState: string(types.VolumeModificationStateFailed),
+		},
+		{
+			name: "success: latest modification is returned",
+			modifications: []types.VolumeModification{
+				{VolumeId: aws.String("vol-test"), ModificationState: types.VolumeModificationStateCompleted, Progress: aws.Int64(100)},
+				{VolumeId: aws.String("vol-test"), ModificationState: types.VolumeModificationStateModifying, Progress: aws.Int64(5)},
+			},
+			expectedState:   string(types.VolumeModificationStateModifying),
+			expectedPercent: 5,
+		},
+		{
+			name:   "fail: volume was never modified",
+			expErr: ErrVolumeNotBeingModified,
+		},
+		{
+			name: "fail: modification not found",
+			describeErr: &smithy.GenericAPIError{
+				Code:    "InvalidVolumeModification.NotFound",
+				Message: "Modification for volume vol-test not found",
+			},
+			expErr: ErrVolumeNotBeingModified,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			var output *ec2.DescribeVolumesModificationsOutput
+			if tc.describeErr == nil {
+				output = &ec2.DescribeVolumesModificationsOutput{VolumesModifications: tc.modifications}
+			}
+			mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), gomock.Eq(&ec2.DescribeVolumesModificationsInput{VolumeIds: []string{"vol-test"}}), testutil.EC2Options()).Return(output, tc.describeErr)
+
+			state, percent, err := c.GetVolumeModificationProgress(t.Context(), "vol-test")
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedState, state)
+			assert.Equal(t, tc.expectedPercent, percent)
+		})
+	}
+}
+
 func TestBatchDescribeVolumesModifications(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
@@ -756,7 +995,7 @@ func TestBatchDescribeVolumesModifications(t *testing.T) {
 			if !ok {
 				t.Fatalf("could not assert cloudInstance as type cloud, %v", cloudInstance)
 			}
-			cloudInstance.bm = newBatcherManager(cloudInstance.ec2)
+			cloudInstance.bm = newBatcherManager(cloudInstance.ec2, BatchingOptions{})
 
 			// Setup mocks
 			var volumeModifications []types.VolumeModification
@@ -1824,6 +2063,22 @@ func TestCreateDisk(t *testing.T) {
 			},
 			expErr: errors.New("CreateDisk: multi-attach is only supported for io2 volumes"),
 		},
+		{
+			name:       "fail: throughput with gp2",
+			volumeName: "vol-test-name",
+			diskOptions: &DiskOptions{
+				CapacityBytes: util.GiBToBytes(4),
+				Tags:          map[string]string{VolumeNameTagKey: "vol-test", AwsEbsDriverTagKey: "true"},
+				VolumeType:    VolumeTypeGP2,
+				Throughput:    250,
+			},
+			expDisk: &Disk{
+				VolumeID:         "vol-test",
+				CapacityGiB:      4,
+				AvailabilityZone: defaultZone,
+			},
+			expErr: fmt.Errorf("%w: throughput is only supported for gp3 volumes, not gp2", ErrInvalidArgument),
+		},
 		{
 			name:       "success: create volume returned volume limit exceeded error, but volume exists",
 			volumeName: "vol-test-name",
@@ -1925,11 +2180,16 @@ func TestCreateDisk(t *testing.T) {
 							OutpostArn: aws.String(tc.diskOptions.OutpostArn),
 						}, tc.expCreateVolumeErr
 					}).MinTimes(1)
+				volumeType := tc.diskOptions.VolumeType
+				if volumeType == "" {
+					volumeType = VolumeTypeGP3
+				}
 				mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
 					Volumes: []types.Volume{
 						{
 							VolumeId:         aws.String(tc.diskOptions.Tags[VolumeNameTagKey]),
 							Size:             aws.Int32(util.BytesToGiB(tc.diskOptions.CapacityBytes)),
+							VolumeType:       types.VolumeType(volumeType),
 							State:            types.VolumeState(volState),
 							AvailabilityZone: aws.String(tc.diskOptions.AvailabilityZone),
 							OutpostArn:       aws.String(tc.diskOptions.OutpostArn),
@@ -2093,6 +2353,274 @@ func TestCreateDiskClientToken(t *testing.T) {
 	}
 }
 
+func TestCreateDiskExistingVolumeParameterMismatch(t *testing.T) {
+	t.Parallel()
+
+	const volumeName = "test-vol-mismatch"
+	const volumeID = "vol-abcd1234"
+	volumeLimitExceededErr := &smithy.GenericAPIError{
+		Code:    "VolumeLimitExceeded",
+		Message: "Volume limit exceeded",
+	}
+
+	testCases := []struct {
+		name           string
+		existingVolume types.Volume
+		expErr         error
+	}{
+		{
+			name: "success: existing volume has same parameters",
+			existingVolume: types.Volume{
+				VolumeId:         aws.String(volumeID),
+				Size:             aws.Int32(10),
+				VolumeType:       types.VolumeTypeGp3,
+				State:            types.VolumeStateAvailable,
+				AvailabilityZone: aws.String(defaultZone),
+			},
+		},
+		{
+			name: "fail: existing volume has different size",
+			existingVolume: types.Volume{
+				VolumeId:         aws.String(volumeID),
+				Size:             aws.Int32(20),
+				VolumeType:       types.VolumeTypeGp3,
+				State:            types.VolumeStateAvailable,
+				AvailabilityZone: aws.String(defaultZone),
+			},
+			expErr: ErrIdempotentParameterMismatch,
+		},
+		{
+			name: "fail: existing volume has different type",
+			existingVolume: types.Volume{
+				VolumeId:         aws.String(volumeID),
+				Size:             aws.Int32(10),
+				VolumeType:       types.VolumeTypeIo2,
+				State:            types.VolumeStateAvailable,
+				AvailabilityZone: aws.String(defaultZone),
+			},
+			expErr: ErrIdempotentParameterMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().CreateVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateVolumeInput{}), testutil.EC2Options()).DoAndReturn(
+				func(_ context.Context, input *ec2.CreateVolumeInput, _ ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+					if input.DryRun != nil && *input.DryRun {
+						return nil, errors.New("Volume iops of 2147483647 is too high; maximum is 16000.")
+					}
+					return nil, volumeLimitExceededErr
+				}).MinTimes(1)
+			mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+				Volumes: []types.Volume{tc.existingVolume},
+			}, nil).MinTimes(1)
+
+			ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(defaultCreateDiskDeadline))
+			defer cancel()
+			disk, err := c.CreateDisk(ctx, volumeName, &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				VolumeType:       VolumeTypeGP3,
+				Tags:             map[string]string{VolumeNameTagKey: volumeName, AwsEbsDriverTagKey: "true"},
+				AvailabilityZone: defaultZone,
+			})
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, volumeID, disk.VolumeID)
+			}
+		})
+	}
+}
+
+func TestCreateDiskIncompleteCreateVolumeResponse(t *testing.T) {
+	t.Parallel()
+
+	const volumeName = "test-vol-incomplete"
+	const volumeID = "vol-abcd1234"
+
+	testCases := []struct {
+		name            string
+		existingVolumes []types.Volume
+		expErr          error
+	}{
+		{
+			name: "success: volume is found by its name tag",
+			existingVolumes: []types.Volume{
+				{
+					VolumeId:         aws.String(volumeID),
+					Size:             aws.Int32(10),
+					VolumeType:       types.VolumeTypeGp3,
+					State:            types.VolumeStateAvailable,
+					AvailabilityZone: aws.String(defaultZone),
+				},
+			},
+		},
+		{
+			name:   "fail: no volume has the name tag",
+			expErr: errIncompleteCreateVolumeResponse,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().CreateVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateVolumeInput{}), testutil.EC2Options()).DoAndReturn(
+				func(_ context.Context, input *ec2.CreateVolumeInput, _ ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+					if input.DryRun != nil && *input.DryRun {
+						return nil, errors.New("Volume iops of 2147483647 is too high; maximum is 16000.")
+					}
+					return &ec2.CreateVolumeOutput{}, nil
+				}).MinTimes(1)
+			mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+				Volumes: tc.existingVolumes,
+			}, nil).MinTimes(1)
+
+			ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(defaultCreateDiskDeadline))
+			defer cancel()
+			disk, err := c.CreateDisk(ctx, volumeName, &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(10),
+				VolumeType:       VolumeTypeGP3,
+				Tags:             map[string]string{VolumeNameTagKey: volumeName, AwsEbsDriverTagKey: "true"},
+				AvailabilityZone: defaultZone,
+			})
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, volumeID, disk.VolumeID)
+				assert.Equal(t, int32(10), disk.CapacityGiB)
+			}
+		})
+	}
+}
+
+func TestCreateDiskVolumeInErrorState(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		kmsKeyID string
+		expErr   string
+	}{
+		{
+			name:   "unencrypted volume",
+			expErr: "failed to create volume vol-abcd1234: volume is in error state",
+		},
+		{
+			name:     "volume encrypted with KMS key",
+			kmsKeyID: "arn:aws:kms:us-east-1:012345678910:key/abcd1234",
+			expErr:   "check that the key policy of KMS key \"arn:aws:kms:us-east-1:012345678910:key/abcd1234\" allows the driver's IAM role",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			const volumeName = "test-vol-error-state"
+			const volumeID = "vol-abcd1234"
+			diskOptions := &DiskOptions{
+				CapacityBytes:    util.GiBToBytes(1),
+				Tags:             map[string]string{VolumeNameTagKey: volumeName, AwsEbsDriverTagKey: "true"},
+				AvailabilityZone: defaultZone,
+				KmsKeyID:         tc.kmsKeyID,
+				Encrypted:        tc.kmsKeyID != "",
+			}
+
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			mockEC2.EXPECT().CreateVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateVolumeInput{}), testutil.EC2Options()).DoAndReturn(
+				func(_ context.Context, input *ec2.CreateVolumeInput, _ ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+					if input.DryRun != nil && *input.DryRun {
+						return nil, errors.New("Volume iops of 2147483647 is too high; maximum is 16000.")
+					}
+					return &ec2.CreateVolumeOutput{
+						VolumeId: aws.String(volumeID),
+						Size:     aws.Int32(util.BytesToGiB(diskOptions.CapacityBytes)),
+					}, nil
+				}).MinTimes(1)
+			mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+				Volumes: []types.Volume{
+					{
+						VolumeId:         aws.String(volumeID),
+						Size:             aws.Int32(util.BytesToGiB(diskOptions.CapacityBytes)),
+						State:            types.VolumeStateError,
+						AvailabilityZone: aws.String(diskOptions.AvailabilityZone),
+					},
+				},
+			}, nil).Times(1)
+			mockEC2.EXPECT().DeleteVolume(testutil.AnyContext(), gomock.Eq(&ec2.DeleteVolumeInput{VolumeId: aws.String(volumeID)}), testutil.EC2Options()).Return(&ec2.DeleteVolumeOutput{}, nil).Times(1)
+
+			ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(defaultCreateDiskDeadline))
+			defer cancel()
+			disk, err := c.CreateDisk(ctx, volumeName, diskOptions)
+			require.ErrorIs(t, err, ErrVolumeInErrorState)
+			require.ErrorContains(t, err, tc.expErr)
+			assert.Nil(t, disk)
+		})
+	}
+}
+
+func TestCreateDiskVolumeInErrorStateRetry(t *testing.T) {
+	t.Parallel()
+
+	const volumeName = "test-vol-error-state-retry"
+	diskOptions := &DiskOptions{
+		CapacityBytes:    util.GiBToBytes(1),
+		Tags:             map[string]string{VolumeNameTagKey: volumeName, AwsEbsDriverTagKey: "true"},
+		AvailabilityZone: defaultZone,
+	}
+
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	var clientTokens []string
+	mockEC2.EXPECT().CreateVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateVolumeInput{}), testutil.EC2Options()).DoAndReturn(
+		func(_ context.Context, input *ec2.CreateVolumeInput, _ ...func(*ec2.Options)) (*ec2.CreateVolumeOutput, error) {
+			if input.DryRun != nil && *input.DryRun {
+				return nil, errors.New("Volume iops of 2147483647 is too high; maximum is 16000.")
+			}
+			clientTokens = append(clientTokens, aws.ToString(input.ClientToken))
+			return &ec2.CreateVolumeOutput{
+				VolumeId: aws.String(fmt.Sprintf("vol-%d", len(clientTokens))),
+				Size:     aws.Int32(util.BytesToGiB(diskOptions.CapacityBytes)),
+			}, nil
+		}).MinTimes(2)
+	gomock.InOrder(
+		mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+			Volumes: []types.Volume{{VolumeId: aws.String("vol-1"), State: types.VolumeStateError}},
+		}, nil),
+		mockEC2.EXPECT().DeleteVolume(testutil.AnyContext(), gomock.Eq(&ec2.DeleteVolumeInput{VolumeId: aws.String("vol-1")}), testutil.EC2Options()).Return(&ec2.DeleteVolumeOutput{}, nil),
+		mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+			Volumes: []types.Volume{{VolumeId: aws.String("vol-2"), State: types.VolumeStateAvailable}},
+		}, nil),
+	)
+
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(defaultCreateDiskDeadline))
+	defer cancel()
+	_, err := c.CreateDisk(ctx, volumeName, diskOptions)
+	require.ErrorIs(t, err, ErrVolumeInErrorState)
+
+	disk, err := c.CreateDisk(ctx, volumeName, diskOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "vol-2", disk.VolumeID)
+	require.Len(t, clientTokens, 2)
+	assert.NotEqual(t, clientTokens[0], clientTokens[1], "the retry must not reuse the client token of the deleted volume")
+}
+
 func TestDeleteDisk(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2147,10 +2675,271 @@ func TestDeleteDisk(t *testing.T) {
 	}
 }
 
-func TestAttachDisk(t *testing.T) {
-	blockDeviceInUseErr := &smithy.GenericAPIError{
-		Code:    "InvalidParameterValue",
-		Message: fmt.Sprintf("Invalid value '%s' for unixDevice. Attachment point %s is already in use", defaultPath, defaultPath),
+func TestWithRequestID(t *testing.T) {
+	notFoundErr := &smithy.OperationError{
+		ServiceID:     "EC2",
+		OperationName: "DeleteVolume",
+		Err: &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusBadRequest}},
+				Err:      &smithy.GenericAPIError{Code: "InvalidVolume.NotFound"},
+			},
+			RequestID: "a1b2c3d4-request-id",
+		},
+	}
+
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	mockEC2.EXPECT().DeleteVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.DeleteVolumeInput{}), testutil.EC2Options()).Return(nil, notFoundErr)
+	_, err := c.DeleteDisk(t.Context(), "vol-test")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.ErrorContains(t, err, "a1b2c3d4-request-id")
+
+	mockEC2.EXPECT().DeleteSnapshot(testutil.AnyContext(), testutil.EC2Input(&ec2.DeleteSnapshotInput{}), testutil.EC2Options()).Return(nil, &smithy.OperationError{
+		ServiceID:     "EC2",
+		OperationName: "DeleteSnapshot",
+		Err:           &smithy.GenericAPIError{Code: "InvalidSnapshot.NotFound"},
+	})
+	_, err = c.DeleteSnapshot(t.Context(), "snap-test")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NotContains(t, err.Error(), "request ID", "errors without a request ID must be returned unchanged")
+}
+
+func TestIsNitroInstanceType(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		hypervisor   types.InstanceTypeHypervisor
+		apiErr       error
+		expected     bool
+	}{
+		{
+			name:         "API reports nitro",
+			instanceType: "r5b.large",
+			hypervisor:   types.InstanceTypeHypervisorNitro,
+			expected:     true,
+		},
+		{
+			name:         "API reports xen",
+			instanceType: "g3s.xlarge",
+			hypervisor:   types.InstanceTypeHypervisorXen,
+			expected:     false,
+		},
+		{
+			name:         "API reports xen for instance type in static denylist",
+			instanceType: "c4.large",
+			hypervisor:   types.InstanceTypeHypervisorXen,
+			expected:     false,
+		},
+		{
+			name:         "API overrides static table",
+			instanceType: "m5.large",
+			hypervisor:   types.InstanceTypeHypervisorXen,
+			expected:     false,
+		},
+		{
+			name:         "no hypervisor reported falls back to static table",
+			instanceType: "m7i.metal-24xl",
+			expected:     true,
+		},
+		{
+			name:         "API error falls back to static table for non-nitro",
+			instanceType: "t2.micro",
+			apiErr:       errors.New("DescribeInstanceTypes failed"),
+			expected:     false,
+		},
+		{
+			name:         "API error falls back to static table for nitro",
+			instanceType: "m5.large",
+			apiErr:       errors.New("DescribeInstanceTypes failed"),
+			expected:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			var output *ec2.DescribeInstanceTypesOutput
+			if tc.apiErr == nil {
+				output = &ec2.DescribeInstanceTypesOutput{
+					InstanceTypes: []types.InstanceTypeInfo{{
+						InstanceType: types.InstanceType(tc.instanceType),
+						Hypervisor:   tc.hypervisor,
+					}},
+				}
+			}
+			// The result is cached, so the API must only be called once
+			mockEC2.EXPECT().DescribeInstanceTypes(testutil.AnyContext(), gomock.Eq(&ec2.DescribeInstanceTypesInput{
+				InstanceTypes: []types.InstanceType{types.InstanceType(tc.instanceType)},
+			})).Return(output, tc.apiErr).Times(1)
+
+			assert.Equal(t, tc.expected, c.IsNitroInstanceType(t.Context(), tc.instanceType))
+			assert.Equal(t, tc.expected, c.IsNitroInstanceType(t.Context(), tc.instanceType))
+		})
+	}
+}
+
+func TestGetInstanceTypeMaxIOPS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		ebsInfo      *types.EbsInfo
+		apiErr       error
+		expected     int32
+		expErr       bool
+	}{
+		{
+			name:         "API reports maximum IOPS",
+			instanceType: "m5.large",
+			ebsInfo: &types.EbsInfo{
+				EbsOptimizedInfo: &types.EbsOptimizedInfo{MaximumIops: aws.Int32(18750)},
+			},
+			expected: 18750,
+		},
+		{
+			name:         "not EBS optimized",
+			instanceType: "t1.micro",
+			ebsInfo:      &types.EbsInfo{},
+			expected:     0,
+		},
+		{
+			name:         "API error",
+			instanceType: "m5.large",
+			apiErr:       errors.New("DescribeInstanceTypes failed"),
+			expErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			var output *ec2.DescribeInstanceTypesOutput
+			if tc.apiErr == nil {
+				output = &ec2.DescribeInstanceTypesOutput{
+					InstanceTypes: []types.InstanceTypeInfo{{
+						InstanceType: types.InstanceType(tc.instanceType),
+						EbsInfo:      tc.ebsInfo,
+					}},
+				}
+			}
+			// Both successful and failed results are cached
+			mockEC2.EXPECT().DescribeInstanceTypes(testutil.AnyContext(), gomock.Eq(&ec2.DescribeInstanceTypesInput{
+				InstanceTypes: []types.InstanceType{types.InstanceType(tc.instanceType)},
+			})).Return(output, tc.apiErr).Times(1)
+
+			for range 2 {
+				maxIOPS, err := c.GetInstanceTypeMaxIOPS(t.Context(), tc.instanceType)
+				if tc.expErr {
+					require.Error(t, err)
+					continue
+				}
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, maxIOPS)
+			}
+		})
+	}
+}
+
+func TestGetInstanceTypeAcceleratorCount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name         string
+		instanceType string
+		info         *types.InstanceTypeInfo
+		apiErr       error
+		expected     int
+		expErr       error
+	}{
+		{
+			name:         "GPUs",
+			instanceType: "p4d.24xlarge",
+			info: &types.InstanceTypeInfo{
+				GpuInfo: &types.GpuInfo{Gpus: []types.GpuDeviceInfo{{Count: aws.Int32(8)}}},
+			},
+			expected: 8,
+		},
+		{
+			name:         "inference accelerators",
+			instanceType: "inf1.6xlarge",
+			info: &types.InstanceTypeInfo{
+				InferenceAcceleratorInfo: &types.InferenceAcceleratorInfo{
+					Accelerators: []types.InferenceDeviceInfo{{Count: aws.Int32(4)}},
+				},
+			},
+			expected: 4,
+		},
+		{
+			name:         "no accelerators",
+			instanceType: "m5.large",
+			info:         &types.InstanceTypeInfo{},
+			expected:     0,
+		},
+		{
+			name:         "instance type not found",
+			instanceType: "zz9.xlarge",
+			expErr:       ErrNotFound,
+		},
+		{
+			name:         "API error",
+			instanceType: "g5.xlarge",
+			apiErr:       errors.New("DescribeInstanceTypes failed"),
+			expErr:       errors.New("DescribeInstanceTypes failed"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			var output *ec2.DescribeInstanceTypesOutput
+			if tc.apiErr == nil {
+				output = &ec2.DescribeInstanceTypesOutput{}
+				if tc.info != nil {
+					tc.info.InstanceType = types.InstanceType(tc.instanceType)
+					output.InstanceTypes = []types.InstanceTypeInfo{*tc.info}
+				}
+			}
+			mockEC2.EXPECT().DescribeInstanceTypes(testutil.AnyContext(), gomock.Eq(&ec2.DescribeInstanceTypesInput{
+				InstanceTypes: []types.InstanceType{types.InstanceType(tc.instanceType)},
+			})).Return(output, tc.apiErr)
+
+			count, err := c.GetInstanceTypeAcceleratorCount(t.Context(), tc.instanceType)
+			if tc.expErr != nil {
+				require.ErrorContains(t, err, tc.expErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, count)
+		})
+	}
+}
+
+func TestAttachDisk(t *testing.T) {
+	blockDeviceInUseErr := &smithy.GenericAPIError{
+		Code:    "InvalidParameterValue",
+		Message: fmt.Sprintf("Invalid value '%s' for unixDevice. Attachment point %s is already in use", defaultPath, defaultPath),
+	}
+	incorrectStateErr := &smithy.GenericAPIError{
+		Code:    "IncorrectState",
+		Message: fmt.Sprintf("Volume '%s' is in the 'deleting' state", defaultVolumeID),
 	}
 
 	testCases := []struct {
@@ -2216,6 +3005,26 @@ func TestAttachDisk(t *testing.T) {
 				)
 			},
 		},
+		{
+			name:     "fail: AttachVolume device names exhausted",
+			volumeID: defaultVolumeID,
+			nodeID:   defaultNodeID,
+			path:     defaultPath,
+			expErr:   fmt.Errorf("%w: %w", ErrDeviceNamesExhausted, fmt.Errorf("could not get a free device name to assign to node %s: %w", defaultNodeID, dm.ErrNoDeviceNamesAvailable)),
+			mockFunc: func(mockEC2 *MockEC2API, ctx context.Context, volumeID, nodeID, nodeID2, path string, deviceManager dm.DeviceManager) {
+				instanceRequest := createInstanceRequest(nodeID)
+
+				// Occupy every device name with in-flight attachments of other volumes
+				fakeInstance := types.Instance{InstanceId: aws.String(nodeID)}
+				for i := 0; ; i++ {
+					if _, err := deviceManager.NewDevice(&fakeInstance, fmt.Sprintf("vol-%d", i), new(sync.Map), 1); err != nil {
+						break
+					}
+				}
+
+				mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(instanceRequest)).Return(newDescribeInstancesOutput(nodeID), nil)
+			},
+		},
 		{
 			name:     "success: AttachVolume device already assigned",
 			volumeID: defaultVolumeID,
@@ -2272,7 +3081,7 @@ func TestAttachDisk(t *testing.T) {
 			volumeID: defaultVolumeID,
 			nodeID:   defaultNodeID,
 			path:     defaultPath,
-			expErr: fmt.Errorf("%w: %w", ErrLimitExceeded, &smithy.GenericAPIError{
+			expErr: fmt.Errorf("%w (%s): %w", ErrLimitExceeded, `instance type "": limit 27, reserved 0, usable 27, attached 0`, &smithy.GenericAPIError{
 				Code:    "AttachmentLimitExceeded",
 				Message: "Volume attachment limit exceeded",
 			}),
@@ -2290,6 +3099,46 @@ func TestAttachDisk(t *testing.T) {
 				)
 			},
 		},
+		{
+			name:     "fail: AttachVolume volume is being deleted",
+			volumeID: defaultVolumeID,
+			nodeID:   defaultNodeID,
+			path:     defaultPath,
+			expErr:   fmt.Errorf("%w: volume %q is being deleted: %w", ErrNotFound, defaultVolumeID, incorrectStateErr),
+			mockFunc: func(mockEC2 *MockEC2API, ctx context.Context, volumeID, nodeID, nodeID2, path string, dm dm.DeviceManager) {
+				instanceRequest := createInstanceRequest(nodeID)
+				attachRequest := createAttachRequest(volumeID, nodeID, path)
+				volumeRequest := &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}}
+
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstances(ctx, instanceRequest).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().AttachVolume(ctx, attachRequest, testutil.EC2Options()).Return(nil, incorrectStateErr),
+					mockEC2.EXPECT().DescribeVolumes(ctx, volumeRequest).Return(&ec2.DescribeVolumesOutput{
+						Volumes: []types.Volume{{VolumeId: aws.String(volumeID), State: types.VolumeStateDeleting}},
+					}, nil),
+				)
+			},
+		},
+		{
+			name:     "fail: AttachVolume returned incorrect state error for existing volume",
+			volumeID: defaultVolumeID,
+			nodeID:   defaultNodeID,
+			path:     defaultPath,
+			expErr:   fmt.Errorf("could not attach volume %q to node %q: %w", defaultVolumeID, defaultNodeID, incorrectStateErr),
+			mockFunc: func(mockEC2 *MockEC2API, ctx context.Context, volumeID, nodeID, nodeID2, path string, dm dm.DeviceManager) {
+				instanceRequest := createInstanceRequest(nodeID)
+				attachRequest := createAttachRequest(volumeID, nodeID, path)
+				volumeRequest := &ec2.DescribeVolumesInput{VolumeIds: []string{volumeID}}
+
+				gomock.InOrder(
+					mockEC2.EXPECT().DescribeInstances(ctx, instanceRequest).Return(newDescribeInstancesOutput(nodeID), nil),
+					mockEC2.EXPECT().AttachVolume(ctx, attachRequest, testutil.EC2Options()).Return(nil, incorrectStateErr),
+					mockEC2.EXPECT().DescribeVolumes(ctx, volumeRequest).Return(&ec2.DescribeVolumesOutput{
+						Volumes: []types.Volume{{VolumeId: aws.String(volumeID), State: types.VolumeStateInUse}},
+					}, nil),
+				)
+			},
+		},
 
 		{
 			name:     "success: AttachVolume multi-attach",
@@ -2496,83 +3345,497 @@ func TestAttachDisk(t *testing.T) {
 			expErr:    nil,
 		},
 		{
-			name:     "fail: HyperPod attachment limit exceeded",
-			volumeID: "vol-test",
-			nodeID:   "hyperpod-cluster1-i-1234567890",
-			setupMocks: func(mockEC2 *MockEC2API, mockSM *MockSageMakerAPI, volumeID, nodeID string) {
-				// Setup SageMaker mock to return error
-				instanceID := getInstanceIDFromHyperPodNode(nodeID)
-				mockSM.EXPECT().AttachClusterNodeVolume(
-					testutil.AnyContext(),
-					gomock.Eq(&sagemaker.AttachClusterNodeVolumeInput{
-						ClusterArn: aws.String("arn:aws:sagemaker:us-west-2:123456789012:cluster/cluster1"),
-						NodeId:     aws.String(instanceID),
-						VolumeId:   aws.String(volumeID),
-					}),
-				).Return(nil, &smithy.GenericAPIError{
-					Code:    "ValidationException",
-					Message: "HyperPod - Ec2ErrCode: AttachmentLimitExceeded : Ec2ErrMsg: Volume attachment limit exceeded",
-				})
-			},
-			expErr: errors.New("limit exceeded: api error ValidationException: HyperPod - Ec2ErrCode: AttachmentLimitExceeded : Ec2ErrMsg: Volume attachment limit exceeded"),
+			name:     "fail: HyperPod attachment limit exceeded",
+			volumeID: "vol-test",
+			nodeID:   "hyperpod-cluster1-i-1234567890",
+			setupMocks: func(mockEC2 *MockEC2API, mockSM *MockSageMakerAPI, volumeID, nodeID string) {
+				// Setup SageMaker mock to return error
+				instanceID := getInstanceIDFromHyperPodNode(nodeID)
+				mockSM.EXPECT().AttachClusterNodeVolume(
+					testutil.AnyContext(),
+					gomock.Eq(&sagemaker.AttachClusterNodeVolumeInput{
+						ClusterArn: aws.String("arn:aws:sagemaker:us-west-2:123456789012:cluster/cluster1"),
+						NodeId:     aws.String(instanceID),
+						VolumeId:   aws.String(volumeID),
+					}),
+				).Return(nil, &smithy.GenericAPIError{
+					Code:    "ValidationException",
+					Message: "HyperPod - Ec2ErrCode: AttachmentLimitExceeded : Ec2ErrMsg: Volume attachment limit exceeded",
+				})
+			},
+			expErr: errors.New("limit exceeded: api error ValidationException: HyperPod - Ec2ErrCode: AttachmentLimitExceeded : Ec2ErrMsg: Volume attachment limit exceeded"),
+		},
+		{
+			name:     "fail: HyperPod attachment generic error",
+			volumeID: "vol-test",
+			nodeID:   "hyperpod-cluster1-i-1234567890",
+			setupMocks: func(mockEC2 *MockEC2API, mockSM *MockSageMakerAPI, volumeID, nodeID string) {
+				// Setup SageMaker mock to return error
+				instanceID := getInstanceIDFromHyperPodNode(nodeID)
+				mockSM.EXPECT().AttachClusterNodeVolume(
+					testutil.AnyContext(),
+					gomock.Eq(&sagemaker.AttachClusterNodeVolumeInput{
+						ClusterArn: aws.String("arn:aws:sagemaker:us-west-2:123456789012:cluster/cluster1"),
+						NodeId:     aws.String(instanceID),
+						VolumeId:   aws.String(volumeID),
+					}),
+				).Return(nil, errors.New("AttachVolume error"))
+			},
+			expErr: errors.New("could not attach volume \"vol-test\" to node \"hyperpod-cluster1-i-1234567890\": AttachVolume error"),
+		},
+	}
+
+	for _, tc := range hyperPodTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create mock EC2 client (needed for base cloud struct)
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+
+			// Create mock SageMaker client
+			mockSM := NewMockSageMakerAPI(mockCtrl)
+
+			// Create cloud with both mocks
+			c := &cloud{
+				region:    "us-west-2",
+				accountID: "123456789012",
+				ec2:       mockEC2,
+				sm:        mockSM,
+				dm:        dm.NewDeviceManager(),
+				rm:        newRetryManager(),
+				vwp:       testVolumeWaitParameters(),
+			}
+
+			tc.setupMocks(mockEC2, mockSM, tc.volumeID, tc.nodeID)
+
+			ctx := t.Context()
+			devicePath, err := c.AttachDisk(ctx, tc.volumeID, tc.nodeID)
+
+			// Verify results
+			if tc.expErr != nil {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expErr.Error())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expDevice, devicePath)
+			}
+
+			mockCtrl.Finish()
+		})
+	}
+}
+
+func TestAttachDiskLimitExceededReportsBudget(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2)
+
+	instance := types.Instance{
+		InstanceId:        aws.String(defaultNodeID),
+		InstanceType:      types.InstanceTypeM5Large,
+		NetworkInterfaces: make([]types.InstanceNetworkInterface, 3),
+	}
+	for i := range 25 {
+		instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(fmt.Sprintf("/dev/xvd%c%c", 'a'+i/26, 'a'+i%26)),
+			Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String(fmt.Sprintf("vol-%d", i))},
+		})
+	}
+
+	mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).Return(&ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: []types.Instance{instance}}},
+	}, nil)
+	mockEC2.EXPECT().DescribeInstanceTypes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeInstanceTypesInput{})).Return(&ec2.DescribeInstanceTypesOutput{
+		InstanceTypes: []types.InstanceTypeInfo{{EbsInfo: &types.EbsInfo{}}},
+	}, nil)
+	mockEC2.EXPECT().AttachVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.AttachVolumeInput{}), testutil.EC2Options()).Return(nil, &smithy.GenericAPIError{
+		Code:    "AttachmentLimitExceeded",
+		Message: "Volume attachment limit exceeded",
+	})
+
+	_, err := c.AttachDisk(t.Context(), defaultVolumeID, defaultNodeID)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	// m5.large allows 27 attachments, 2 are taken by the secondary ENIs
+	assert.Contains(t, err.Error(), `instance type "m5.large": limit 27, reserved 2, usable 25, attached 25`)
+}
+
+func TestCheckVolumeTypeAttachmentLimit(t *testing.T) {
+	// Two io2 and three gp3 volumes are attached to the instance
+	attachedVolumes := []types.Volume{
+		{VolumeId: aws.String("vol-io2-0"), VolumeType: types.VolumeTypeIo2},
+		{VolumeId: aws.String("vol-io2-1"), VolumeType: types.VolumeTypeIo2},
+		{VolumeId: aws.String("vol-gp3-0"), VolumeType: types.VolumeTypeGp3},
+		{VolumeId: aws.String("vol-gp3-1"), VolumeType: types.VolumeTypeGp3},
+		{VolumeId: aws.String("vol-gp3-2"), VolumeType: types.VolumeTypeGp3},
+	}
+	instance := &types.Instance{InstanceId: aws.String(defaultNodeID)}
+	for i, volume := range attachedVolumes {
+		instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+			DeviceName: aws.String(fmt.Sprintf("/dev/xvdb%c", 'a'+i)),
+			Ebs:        &types.EbsInstanceBlockDevice{VolumeId: volume.VolumeId},
+		})
+	}
+
+	testCases := []struct {
+		name         string
+		limits       map[string]int
+		volume       types.Volume
+		detaching    []types.Volume
+		skipDescribe bool
+		expErr       error
+	}{
+		{
+			name:         "no caps",
+			volume:       types.Volume{VolumeId: aws.String("vol-io2-2"), VolumeType: types.VolumeTypeIo2},
+			skipDescribe: true,
+		},
+		{
+			name:   "io2 below the io2 cap",
+			limits: map[string]int{"io2": 3},
+			volume: types.Volume{VolumeId: aws.String("vol-io2-2"), VolumeType: types.VolumeTypeIo2},
+		},
+		{
+			name:   "io2 at the io2 cap",
+			limits: map[string]int{"io2": 2},
+			volume: types.Volume{VolumeId: aws.String("vol-io2-2"), VolumeType: types.VolumeTypeIo2},
+			expErr: ErrLimitExceeded,
+		},
+		{
+			name:   "gp3 is not counted against the io2 cap",
+			limits: map[string]int{"io2": 2},
+			volume: types.Volume{VolumeId: aws.String("vol-gp3-3"), VolumeType: types.VolumeTypeGp3},
+		},
+		{
+			name:         "io2 already attached",
+			limits:       map[string]int{"io2": 2},
+			volume:       attachedVolumes[0],
+			skipDescribe: true,
+		},
+		{
+			name:      "io2 being detached counts against the io2 cap",
+			limits:    map[string]int{"io2": 3},
+			volume:    types.Volume{VolumeId: aws.String("vol-io2-2"), VolumeType: types.VolumeTypeIo2},
+			detaching: []types.Volume{{VolumeId: aws.String("vol-io2-detaching"), VolumeType: types.VolumeTypeIo2}},
+			expErr:    ErrLimitExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2).(*cloud)
+			c.volumeTypeAttachmentLimits = tc.limits
+
+			if len(tc.detaching) > 0 {
+				var detachingVolumeIDs []string
+				for _, volume := range tc.detaching {
+					c.detaching.add(defaultNodeID, aws.ToString(volume.VolumeId))
+					detachingVolumeIDs = append(detachingVolumeIDs, aws.ToString(volume.VolumeId))
+				}
+				mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createDetachingVolumesRequest(detachingVolumeIDs, defaultNodeID))).Return(&ec2.DescribeVolumesOutput{
+					Volumes: tc.detaching,
+				}, nil)
+			}
+			if !tc.skipDescribe {
+				volumeIDs := []string{aws.ToString(tc.volume.VolumeId)}
+				for _, volume := range attachedVolumes {
+					volumeIDs = append(volumeIDs, aws.ToString(volume.VolumeId))
+				}
+				mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(&ec2.DescribeVolumesInput{VolumeIds: volumeIDs})).Return(&ec2.DescribeVolumesOutput{
+					Volumes: append([]types.Volume{tc.volume}, attachedVolumes...),
+				}, nil)
+			}
+
+			err := c.checkAttachmentLimits(t.Context(), aws.ToString(tc.volume.VolumeId), instance)
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAttachDiskVolumeTypeAttachmentLimitExceeded(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	c.volumeTypeAttachmentLimits = map[string]int{"io2": 1}
+
+	mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).Return(newDescribeInstancesOutput(defaultNodeID, "vol-io2"), nil)
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(&ec2.DescribeVolumesOutput{
+		Volumes: []types.Volume{
+			{VolumeId: aws.String(defaultVolumeID), VolumeType: types.VolumeTypeIo2},
+			{VolumeId: aws.String("vol-io2"), VolumeType: types.VolumeTypeIo2},
+		},
+	}, nil)
+
+	_, err := c.AttachDisk(t.Context(), defaultVolumeID, defaultNodeID)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Contains(t, err.Error(), "1 of 1 io2 volumes")
+}
+
+func TestAttachDiskWhileDetachInProgress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	c.volumeTypeAttachmentLimits = map[string]int{"gp3": 1}
+	c.vwp.attachmentBackoff = wait.Backoff{Duration: 10 * time.Millisecond, Factor: 1, Steps: 100}
+
+	detachingVolumeID := "vol-detaching"
+	detachStarted := make(chan struct{})
+	detachDone := make(chan struct{})
+	var startOnce sync.Once
+
+	gomock.InOrder(
+		mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).Return(newDescribeInstancesOutput(defaultNodeID, detachingVolumeID), nil),
+		// DescribeInstances no longer returns the volume being detached
+		mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).Return(newDescribeInstancesOutput(defaultNodeID), nil).Times(2),
+	)
+	mockEC2.EXPECT().DetachVolume(testutil.AnyContext(), gomock.Eq(createDetachRequest(detachingVolumeID, defaultNodeID)), testutil.EC2Options()).Return(&ec2.DetachVolumeOutput{}, nil)
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createVolumeRequest(detachingVolumeID))).DoAndReturn(
+		func(_ context.Context, _ *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+			startOnce.Do(func() { close(detachStarted) })
+			select {
+			case <-detachDone:
+				return createDescribeVolumesOutput([]*string{&detachingVolumeID}, defaultNodeID, "", "detached"), nil
+			default:
+				return createDescribeVolumesOutput([]*string{&detachingVolumeID}, defaultNodeID, "", "detaching"), nil
+			}
+		}).MinTimes(1)
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createDetachingVolumesRequest([]string{detachingVolumeID}, defaultNodeID))).Return(&ec2.DescribeVolumesOutput{
+		Volumes: []types.Volume{{VolumeId: aws.String(detachingVolumeID), VolumeType: types.VolumeTypeGp3}},
+	}, nil)
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createVolumeRequest(defaultVolumeID))).Return(&ec2.DescribeVolumesOutput{
+		Volumes: []types.Volume{{VolumeId: aws.String(defaultVolumeID), VolumeType: types.VolumeTypeGp3}},
+	}, nil).Times(2)
+
+	detachErr := make(chan error, 1)
+	go func() {
+		detachErr <- c.DetachDisk(t.Context(), detachingVolumeID, defaultNodeID)
+	}()
+	<-detachStarted
+
+	// The node is full until the detach completes
+	_, err := c.AttachDisk(t.Context(), defaultVolumeID, defaultNodeID)
+	require.ErrorIs(t, err, ErrLimitExceeded)
+
+	close(detachDone)
+	require.NoError(t, <-detachErr)
+	assert.Empty(t, c.detaching.get(defaultNodeID))
+
+	// The slot is free once EC2 reports the volume as detached
+	instance, err := c.getInstance(t.Context(), defaultNodeID)
+	require.NoError(t, err)
+	require.NoError(t, c.checkAttachmentLimits(t.Context(), defaultVolumeID, instance))
+}
+
+func TestCheckInstanceAttachmentLimit(t *testing.T) {
+	// m5.large allows 27 attachments
+	newInstance := func(attached int) *types.Instance {
+		instance := &types.Instance{
+			InstanceId:        aws.String(defaultNodeID),
+			InstanceType:      types.InstanceTypeM5Large,
+			NetworkInterfaces: []types.InstanceNetworkInterface{{}},
+		}
+		for i := range attached {
+			instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+				DeviceName: aws.String(fmt.Sprintf("/dev/xvd%d", i)),
+				Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String(fmt.Sprintf("vol-%d", i))},
+			})
+		}
+		return instance
+	}
+
+	testCases := []struct {
+		name      string
+		instance  *types.Instance
+		detaching []string
+		// stillAttached are the volumes of detaching that EC2 still reports as attached to the instance
+		stillAttached []string
+		expErr        error
+	}{
+		{
+			name:     "no volumes being detached",
+			instance: newInstance(26),
+		},
+		{
+			name:          "volume being detached takes the last free slot",
+			instance:      newInstance(26),
+			detaching:     []string{"vol-detaching"},
+			stillAttached: []string{"vol-detaching"},
+			expErr:        ErrLimitExceeded,
 		},
 		{
-			name:     "fail: HyperPod attachment generic error",
-			volumeID: "vol-test",
-			nodeID:   "hyperpod-cluster1-i-1234567890",
-			setupMocks: func(mockEC2 *MockEC2API, mockSM *MockSageMakerAPI, volumeID, nodeID string) {
-				// Setup SageMaker mock to return error
-				instanceID := getInstanceIDFromHyperPodNode(nodeID)
-				mockSM.EXPECT().AttachClusterNodeVolume(
-					testutil.AnyContext(),
-					gomock.Eq(&sagemaker.AttachClusterNodeVolumeInput{
-						ClusterArn: aws.String("arn:aws:sagemaker:us-west-2:123456789012:cluster/cluster1"),
-						NodeId:     aws.String(instanceID),
-						VolumeId:   aws.String(volumeID),
-					}),
-				).Return(nil, errors.New("AttachVolume error"))
-			},
-			expErr: errors.New("could not attach volume \"vol-test\" to node \"hyperpod-cluster1-i-1234567890\": AttachVolume error"),
+			name:          "volume being detached leaves a free slot",
+			instance:      newInstance(25),
+			detaching:     []string{"vol-detaching"},
+			stillAttached: []string{"vol-detaching"},
+		},
+		{
+			name:      "volume being detached no longer exists",
+			instance:  newInstance(26),
+			detaching: []string{"vol-deleted"},
+		},
+		{
+			name:          "only the volumes that still exist are counted",
+			instance:      newInstance(25),
+			detaching:     []string{"vol-deleted", "vol-detaching-0", "vol-detaching-1"},
+			stillAttached: []string{"vol-detaching-0", "vol-detaching-1"},
+			expErr:        ErrLimitExceeded,
+		},
+		{
+			name:          "instance full without the volumes being detached is left to EC2",
+			instance:      newInstance(27),
+			detaching:     []string{"vol-detaching"},
+			stillAttached: []string{"vol-detaching"},
 		},
 	}
 
-	for _, tc := range hyperPodTestCases {
+	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create mock EC2 client (needed for base cloud struct)
 			mockCtrl := gomock.NewController(t)
 			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2).(*cloud)
 
-			// Create mock SageMaker client
-			mockSM := NewMockSageMakerAPI(mockCtrl)
-
-			// Create cloud with both mocks
-			c := &cloud{
-				region:    "us-west-2",
-				accountID: "123456789012",
-				ec2:       mockEC2,
-				sm:        mockSM,
-				dm:        dm.NewDeviceManager(),
-				rm:        newRetryManager(),
-				vwp:       testVolumeWaitParameters(),
+			for _, volumeID := range tc.detaching {
+				c.detaching.add(defaultNodeID, volumeID)
+			}
+			if len(tc.detaching) > 0 {
+				volumes := make([]types.Volume, 0, len(tc.stillAttached))
+				for _, volumeID := range tc.stillAttached {
+					volumes = append(volumes, types.Volume{VolumeId: aws.String(volumeID)})
+				}
+				mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).DoAndReturn(
+					func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+						require.Empty(t, input.VolumeIds)
+						require.ElementsMatch(t, tc.detaching, input.Filters[0].Values)
+						return &ec2.DescribeVolumesOutput{Volumes: volumes}, nil
+					})
 			}
 
-			tc.setupMocks(mockEC2, mockSM, tc.volumeID, tc.nodeID)
-
-			ctx := t.Context()
-			devicePath, err := c.AttachDisk(ctx, tc.volumeID, tc.nodeID)
-
-			// Verify results
+			err := c.checkAttachmentLimits(t.Context(), defaultVolumeID, tc.instance)
 			if tc.expErr != nil {
-				require.Error(t, err)
-				assert.Contains(t, err.Error(), tc.expErr.Error())
+				require.ErrorIs(t, err, tc.expErr)
 			} else {
 				require.NoError(t, err)
-				assert.Equal(t, tc.expDevice, devicePath)
 			}
+		})
+	}
+}
 
-			mockCtrl.Finish()
+func TestAttachDiskRespectsInstanceLimitWhileDetaching(t *testing.T) {
+	const (
+		// m5.large allows 27 attachments
+		attachedVolumes  = 25
+		detachingVolumes = 2
+		attachingVolumes = 10
+	)
+
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	c.vwp.attachmentBackoff = wait.Backoff{Duration: 10 * time.Millisecond, Factor: 1, Steps: 100}
+
+	// DescribeInstances no longer returns the volumes being detached
+	mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).DoAndReturn(
+		func(_ context.Context, _ *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+			instance := types.Instance{
+				InstanceId:        aws.String(defaultNodeID),
+				InstanceType:      types.InstanceTypeM5Large,
+				NetworkInterfaces: []types.InstanceNetworkInterface{{}},
+			}
+			for i := range attachedVolumes {
+				instance.BlockDeviceMappings = append(instance.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+					DeviceName: aws.String(fmt.Sprintf("/dev/xvd%d", i)),
+					Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String(fmt.Sprintf("vol-attached-%d", i))},
+				})
+			}
+			return &ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: []types.Instance{instance}}}}, nil
+		}).AnyTimes()
+
+	detachDone := make(chan struct{})
+	detachErrs := make(chan error, detachingVolumes)
+	for i := range detachingVolumes {
+		volumeID := fmt.Sprintf("vol-detaching-%d", i)
+		mockEC2.EXPECT().DetachVolume(testutil.AnyContext(), gomock.Eq(createDetachRequest(volumeID, defaultNodeID)), testutil.EC2Options()).Return(&ec2.DetachVolumeOutput{}, nil)
+		mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createVolumeRequest(volumeID))).DoAndReturn(
+			func(_ context.Context, _ *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+				select {
+				case <-detachDone:
+					return createDescribeVolumesOutput([]*string{&volumeID}, defaultNodeID, "", "detached"), nil
+				default:
+					return createDescribeVolumesOutput([]*string{&volumeID}, defaultNodeID, "", "detaching"), nil
+				}
+			}).MinTimes(1)
+		go func() {
+			detachErrs <- c.DetachDisk(t.Context(), volumeID, defaultNodeID)
+		}()
+	}
+	// EC2 still reports the volumes being detached as attached
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).DoAndReturn(
+		func(_ context.Context, input *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+			var volumes []types.Volume
+			for _, volumeID := range input.Filters[0].Values {
+				volumes = append(volumes, types.Volume{VolumeId: aws.String(volumeID)})
+			}
+			return &ec2.DescribeVolumesOutput{Volumes: volumes}, nil
+		}).Times(attachingVolumes)
+
+	require.Eventually(t, func() bool {
+		return len(c.detaching.get(defaultNodeID)) == detachingVolumes
+	}, 5*time.Second, time.Millisecond)
+
+	// AttachVolume is never called, every attachment is rejected while the detaching volumes hold the free slots
+	var wg sync.WaitGroup
+	attachErrs := make(chan error, attachingVolumes)
+	for i := range attachingVolumes {
+		wg.Go(func() {
+			_, err := c.AttachDisk(t.Context(), fmt.Sprintf("vol-attaching-%d", i), defaultNodeID)
+			attachErrs <- err
 		})
 	}
+	wg.Wait()
+	close(attachErrs)
+	for err := range attachErrs {
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	}
+
+	close(detachDone)
+	for range detachingVolumes {
+		require.NoError(t, <-detachErrs)
+	}
+	assert.Empty(t, c.detaching.get(defaultNodeID))
+}
+
+func TestAttachDiskVolumeInUse(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+	cards := 1
+	c.cardCountCache.Set("", &cards)
+
+	mockEC2.EXPECT().DescribeInstances(testutil.AnyContext(), gomock.Eq(createInstanceRequest(defaultNodeID))).Return(newDescribeInstancesOutput(defaultNodeID), nil)
+	mockEC2.EXPECT().AttachVolume(testutil.AnyContext(), gomock.Eq(createAttachRequest(defaultVolumeID, defaultNodeID, defaultPath)), testutil.EC2Options()).Return(nil, &smithy.GenericAPIError{
+		Code:    "VolumeInUse",
+		Message: "vol-test is already attached to an instance",
+	})
+
+	_, err := c.AttachDisk(t.Context(), defaultVolumeID, defaultNodeID)
+	require.ErrorIs(t, err, ErrVolumeInUse)
+}
+
+func TestWaitForAttachmentStateTerminalError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	mockEC2 := NewMockEC2API(mockCtrl)
+	c := newCloud(mockEC2).(*cloud)
+
+	// A terminal error stops the wait right away instead of polling until the backoff is exhausted
+	mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), gomock.Eq(createVolumeRequest(defaultVolumeID))).Return(nil, &smithy.GenericAPIError{Code: "UnauthorizedOperation"}).Times(1)
+
+	_, err := c.WaitForAttachmentState(t.Context(), types.VolumeAttachmentStateDetached, defaultVolumeID, defaultNodeID, "", false, nil)
+	require.Error(t, err)
+	assert.True(t, isAWSErrorUnauthorized(err))
 }
 
 func TestDetachDisk(t *testing.T) {
@@ -2923,6 +4186,7 @@ func TestGetDiskByID(t *testing.T) {
 		volumeID         string
 		availabilityZone string
 		outpostArn       string
+		volumeType       types.VolumeType
 		attachments      []types.VolumeAttachment
 		expDisk          *Disk
 		expErr           error
@@ -2931,10 +4195,12 @@ func TestGetDiskByID(t *testing.T) {
 			name:             "success: normal",
 			volumeID:         "vol-test-1234",
 			availabilityZone: expZone,
+			volumeType:       types.VolumeTypeGp3,
 			attachments:      []types.VolumeAttachment{},
 			expDisk: &Disk{
 				VolumeID:         "vol-test-1234",
 				AvailabilityZone: expZone,
+				VolumeType:       VolumeTypeGP3,
 			},
 			expErr: nil,
 		},
@@ -2992,6 +4258,7 @@ func TestGetDiskByID(t *testing.T) {
 							VolumeId:         aws.String(tc.volumeID),
 							AvailabilityZone: aws.String(tc.availabilityZone),
 							OutpostArn:       aws.String(tc.outpostArn),
+							VolumeType:       tc.volumeType,
 							Attachments:      tc.attachments,
 						},
 					},
@@ -3020,6 +4287,9 @@ func TestGetDiskByID(t *testing.T) {
 				if disk.OutpostArn != tc.expDisk.OutpostArn {
 					t.Fatalf("GetDiskByID() failed: expected outpost ARN %q, got %q", tc.expDisk.OutpostArn, disk.OutpostArn)
 				}
+				if disk.VolumeType != tc.expDisk.VolumeType {
+					t.Fatalf("GetDiskByID() failed: expected volume type %q, got %q", tc.expDisk.VolumeType, disk.VolumeType)
+				}
 				if len(disk.Attachments) != len(tc.expDisk.Attachments) {
 					t.Fatalf("GetDiskByID() failed: expected attachments length %d, got %d", len(tc.expDisk.Attachments), len(disk.Attachments))
 				}
@@ -3177,6 +4447,7 @@ func TestCreateSnapshot(t *testing.T) {
 
 			ctx := t.Context()
 
+			mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesModificationsInput{}), testutil.EC2Options()).Return(&ec2.DescribeVolumesModificationsOutput{}, nil)
 			mockEC2.EXPECT().CreateSnapshot(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateSnapshotInput{}), testutil.EC2Options()).DoAndReturn(
 				func(ctx context.Context, input *ec2.CreateSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotOutput, error) {
 					if input.VolumeId == nil || *input.VolumeId != tc.expSnapshot.SourceVolumeID {
@@ -3241,6 +4512,100 @@ func TestCreateSnapshot(t *testing.T) {
 	}
 }
 
+func TestCreateSnapshotVolumeBeingModified(t *testing.T) {
+	volumeID := "vol-test"
+	modifications := func(states ...types.VolumeModificationState) []*ec2.DescribeVolumesModificationsOutput {
+		outputs := make([]*ec2.DescribeVolumesModificationsOutput, 0, len(states))
+		for _, state := range states {
+			outputs = append(outputs, &ec2.DescribeVolumesModificationsOutput{
+				VolumesModifications: []types.VolumeModification{
+					{
+						VolumeId:          aws.String(volumeID),
+						ModificationState: state,
+					},
+				},
+			})
+		}
+		return outputs
+	}
+
+	testCases := []struct {
+		name          string
+		wait          bool
+		modifications []*ec2.DescribeVolumesModificationsOutput
+		describeErr   error
+		expErr        bool
+	}{
+		{
+			name:          "success: proceed with warning while modifying",
+			wait:          false,
+			modifications: modifications(types.VolumeModificationStateModifying),
+		},
+		{
+			name:          "success: wait for modification to complete",
+			wait:          true,
+			modifications: modifications(types.VolumeModificationStateModifying, types.VolumeModificationStateModifying, types.VolumeModificationStateOptimizing),
+		},
+		{
+			name:          "success: no wait when modification failed",
+			wait:          true,
+			modifications: modifications(types.VolumeModificationStateFailed),
+		},
+		{
+			name:        "success: proceed when modifications cannot be described",
+			wait:        true,
+			describeErr: errors.New("DescribeVolumesModifications generic error"),
+		},
+		{
+			name:          "fail: modification does not finish",
+			wait:          true,
+			modifications: modifications(types.VolumeModificationStateModifying),
+			expErr:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			ctx := t.Context()
+
+			var calls []*gomock.Call
+			if tc.describeErr != nil {
+				calls = append(calls, mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesModificationsInput{}), testutil.EC2Options()).Return(nil, tc.describeErr))
+			} else {
+				for i, output := range tc.modifications {
+					call := mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesModificationsInput{}), testutil.EC2Options()).Return(output, nil)
+					// Without waiting, the modification is described once and never polled
+					if tc.wait && i == len(tc.modifications)-1 {
+						call.AnyTimes()
+					}
+					calls = append(calls, call)
+				}
+			}
+
+			if !tc.expErr {
+				calls = append(calls, mockEC2.EXPECT().CreateSnapshot(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateSnapshotInput{}), testutil.EC2Options()).Return(&ec2.CreateSnapshotOutput{
+					SnapshotId: aws.String("snap-test"),
+					VolumeId:   aws.String(volumeID),
+					VolumeSize: aws.Int32(10),
+					State:      types.SnapshotStatePending,
+				}, nil))
+			}
+			gomock.InOrder(calls...)
+
+			_, err := c.CreateSnapshot(ctx, volumeID, &SnapshotOptions{WaitForVolumeModification: tc.wait})
+			if tc.expErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestEnableFastSnapshotRestores(t *testing.T) {
 	testCases := []struct {
 		name              string
@@ -3669,6 +5034,74 @@ func TestResizeOrModifyDisk(t *testing.T) {
 			modifiedVolumeError: errors.New("InvalidParameterValue: iops value 9999999 is not valid"),
 			expErr:              errors.New("InvalidParameterValue: iops value 9999999 is not valid"),
 		},
+		{
+			name:     "failure: throughput on io2",
+			volumeID: "vol-test",
+			existingVolume: &types.Volume{
+				VolumeId:         aws.String("vol-test"),
+				AvailabilityZone: aws.String(defaultZone),
+				VolumeType:       types.VolumeTypeIo2,
+				Iops:             aws.Int32(3000),
+				Size:             aws.Int32(100),
+			},
+			modifyDiskOptions: &ModifyDiskOptions{
+				Throughput: 500,
+			},
+			expErr: ErrInvalidArgument,
+		},
+		{
+			name:     "failure: shrink",
+			volumeID: "vol-test",
+			existingVolume: &types.Volume{
+				VolumeId:         aws.String("vol-test"),
+				AvailabilityZone: aws.String(defaultZone),
+				VolumeType:       types.VolumeTypeGp3,
+				Size:             aws.Int32(10),
+			},
+			reqSizeGiB:        5,
+			modifyDiskOptions: &ModifyDiskOptions{},
+			expErr:            ErrInvalidArgument,
+		},
+		{
+			name:     "success: io1 to io2 keeps provisioned IOPS",
+			volumeID: "vol-test",
+			existingVolume: &types.Volume{
+				VolumeId:         aws.String("vol-test"),
+				AvailabilityZone: aws.String(defaultZone),
+				VolumeType:       types.VolumeTypeIo1,
+				Iops:             aws.Int32(5000),
+				Size:             aws.Int32(100),
+			},
+			modifyDiskOptions: &ModifyDiskOptions{
+				VolumeType: VolumeTypeIO2,
+			},
+			modifiedVolume: &ec2.ModifyVolumeOutput{
+				VolumeModification: &types.VolumeModification{
+					VolumeId:          aws.String("vol-test"),
+					TargetVolumeType:  types.VolumeTypeIo2,
+					TargetIops:        aws.Int32(5000),
+					ModificationState: types.VolumeModificationStateCompleted,
+				},
+			},
+			reqSizeGiB:         100,
+			shouldCallDescribe: true,
+		},
+		{
+			name:     "failure: io1 to io2 with IOPS too high for volume size",
+			volumeID: "vol-test",
+			existingVolume: &types.Volume{
+				VolumeId:         aws.String("vol-test"),
+				AvailabilityZone: aws.String(defaultZone),
+				VolumeType:       types.VolumeTypeIo1,
+				Iops:             aws.Int32(500),
+				Size:             aws.Int32(10),
+			},
+			modifyDiskOptions: &ModifyDiskOptions{
+				VolumeType: VolumeTypeIO2,
+				IOPS:       20000,
+			},
+			expErr: ErrInvalidArgument,
+		},
 		{
 			name:     "success: does not call ModifyVolume when no modification required",
 			volumeID: "vol-test",
@@ -3775,6 +5208,130 @@ func TestResizeOrModifyDisk(t *testing.T) {
 	}
 }
 
+func TestResizeOrModifyDiskOngoingModification(t *testing.T) {
+	testCases := []struct {
+		name              string
+		modifyDiskOptions *ModifyDiskOptions
+		latestMod         types.VolumeModification
+		completedMod      *types.VolumeModification
+		expErr            string
+	}{
+		{
+			name: "success: retry while modifying towards the same target",
+			modifyDiskOptions: &ModifyDiskOptions{
+				IOPS:       4000,
+				Throughput: 500,
+			},
+			latestMod: types.VolumeModification{
+				VolumeId:          aws.String("vol-test"),
+				TargetSize:        aws.Int32(10),
+				TargetVolumeType:  types.VolumeTypeGp3,
+				TargetIops:        aws.Int32(4000),
+				TargetThroughput:  aws.Int32(500),
+				ModificationState: types.VolumeModificationStateModifying,
+			},
+			completedMod: &types.VolumeModification{
+				VolumeId:          aws.String("vol-test"),
+				TargetSize:        aws.Int32(10),
+				TargetVolumeType:  types.VolumeTypeGp3,
+				TargetIops:        aws.Int32(4000),
+				TargetThroughput:  aws.Int32(500),
+				ModificationState: types.VolumeModificationStateCompleted,
+			},
+		},
+		{
+			name: "failure: modifying towards a different target",
+			modifyDiskOptions: &ModifyDiskOptions{
+				IOPS:       4000,
+				Throughput: 500,
+			},
+			latestMod: types.VolumeModification{
+				VolumeId:          aws.String("vol-test"),
+				TargetSize:        aws.Int32(10),
+				TargetVolumeType:  types.VolumeTypeGp3,
+				TargetIops:        aws.Int32(6000),
+				TargetThroughput:  aws.Int32(500),
+				ModificationState: types.VolumeModificationStateModifying,
+			},
+			expErr: "already being modified to a different target",
+		},
+		{
+			name: "success: retry while optimizing towards the same target",
+			modifyDiskOptions: &ModifyDiskOptions{
+				IOPS:       4000,
+				Throughput: 500,
+			},
+			latestMod: types.VolumeModification{
+				VolumeId:          aws.String("vol-test"),
+				TargetSize:        aws.Int32(10),
+				TargetVolumeType:  types.VolumeTypeGp3,
+				TargetIops:        aws.Int32(4000),
+				TargetThroughput:  aws.Int32(500),
+				ModificationState: types.VolumeModificationStateOptimizing,
+			},
+		},
+		{
+			name: "failure: optimizing towards a different target",
+			modifyDiskOptions: &ModifyDiskOptions{
+				IOPS:       4000,
+				Throughput: 500,
+			},
+			latestMod: types.VolumeModification{
+				VolumeId:          aws.String("vol-test"),
+				TargetSize:        aws.Int32(10),
+				TargetVolumeType:  types.VolumeTypeGp3,
+				TargetIops:        aws.Int32(4000),
+				TargetThroughput:  aws.Int32(250),
+				ModificationState: types.VolumeModificationStateOptimizing,
+			},
+			expErr: "OPTIMIZING state",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			mockEC2 := NewMockEC2API(mockCtrl)
+			c := newCloud(mockEC2)
+
+			// DescribeVolumes still reports the volume before the modification
+			existingVolume := types.Volume{
+				VolumeId:         aws.String("vol-test"),
+				Size:             aws.Int32(10),
+				AvailabilityZone: aws.String(defaultZone),
+				VolumeType:       types.VolumeTypeGp3,
+				Iops:             aws.Int32(3000),
+				Throughput:       aws.Int32(125),
+			}
+			mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(
+				&ec2.DescribeVolumesOutput{Volumes: []types.Volume{existingVolume}}, nil).Times(1)
+			latestModCall := mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesModificationsInput{}), testutil.EC2Options()).Return(
+				&ec2.DescribeVolumesModificationsOutput{VolumesModifications: []types.VolumeModification{tc.latestMod}}, nil).MinTimes(1)
+			mockEC2.EXPECT().CreateVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.CreateVolumeInput{}), testutil.EC2Options()).Return(nil, &smithy.GenericAPIError{Code: "DryRunOperation"}).AnyTimes()
+			if tc.completedMod != nil {
+				latestModCall.Times(1)
+				mockEC2.EXPECT().DescribeVolumesModifications(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesModificationsInput{}), testutil.EC2Options()).Return(
+					&ec2.DescribeVolumesModificationsOutput{VolumesModifications: []types.VolumeModification{*tc.completedMod}}, nil).Times(1)
+				modifiedVolume := existingVolume
+				modifiedVolume.Iops = tc.completedMod.TargetIops
+				modifiedVolume.Throughput = tc.completedMod.TargetThroughput
+				mockEC2.EXPECT().DescribeVolumes(testutil.AnyContext(), testutil.EC2Input(&ec2.DescribeVolumesInput{})).Return(
+					&ec2.DescribeVolumesOutput{Volumes: []types.Volume{modifiedVolume}}, nil).Times(1)
+			}
+			// ModifyVolume must never be called while a modification is ongoing
+			mockEC2.EXPECT().ModifyVolume(testutil.AnyContext(), testutil.EC2Input(&ec2.ModifyVolumeInput{}), testutil.EC2Options()).Times(0)
+
+			newSize, err := c.ResizeOrModifyDisk(t.Context(), "vol-test", 0, tc.modifyDiskOptions)
+			if tc.expErr != "" {
+				require.ErrorContains(t, err, tc.expErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int32(10), newSize)
+		})
+	}
+}
+
 func TestModifyTags(t *testing.T) {
 	validTagsToAddInput := map[string]string{
 		"key1": "value1",
@@ -5185,6 +6742,8 @@ func newCloud(mockEC2 util.EC2API) Cloud {
 		volumeInitializations: expiringcache.New[string, volumeInitialization](cacheForgetDelay),
 		latestIOPSLimits:      expiringcache.New[string, iopsLimits](iopsLimitCacheForgetDelay),
 		cardCountCache:        expiringcache.New[string, int](cacheForgetDelay),
+		nitroCache:            expiringcache.New[string, bool](cacheForgetDelay),
+		maxIOPSCache:          expiringcache.New[string, instanceTypeMaxIOPS](cacheForgetDelay),
 	}
 	return c
 }
@@ -5234,6 +6793,15 @@ func createVolumeRequest(volumeID string) *ec2.DescribeVolumesInput {
 	}
 }
 
+func createDetachingVolumesRequest(volumeIDs []string, nodeID string) *ec2.DescribeVolumesInput {
+	return &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("volume-id"), Values: volumeIDs},
+			{Name: aws.String("attachment.instance-id"), Values: []string{nodeID}},
+		},
+	}
+}
+
 func createInstanceRequest(nodeID string) *ec2.DescribeInstancesInput {
 	return &ec2.DescribeInstancesInput{
 		InstanceIds: []string{nodeID},
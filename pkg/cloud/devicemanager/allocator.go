@@ -38,7 +38,13 @@ type NameAllocator interface {
 	GetNext(existingNames ExistingNames, likelyBadNames *sync.Map) (name string, err error)
 }
 
-type nameAllocator struct{}
+// ErrNoDeviceNamesAvailable is returned when every legal device name is already assigned on the instance.
+var ErrNoDeviceNamesAvailable = errors.New("there are no names available")
+
+type nameAllocator struct {
+	// reservedNames are device names used by tooling outside the driver. They are never returned.
+	reservedNames map[string]struct{}
+}
 
 var _ NameAllocator = &nameAllocator{}
 
@@ -51,7 +57,8 @@ func (d *nameAllocator) GetNext(existingNames ExistingNames, likelyBadNames *syn
 	for _, name := range deviceNames {
 		_, existing := existingNames[name]
 		_, likelyBad := likelyBadNames.Load(name)
-		if !existing && !likelyBad {
+		_, reserved := d.reservedNames[name]
+		if !existing && !likelyBad && !reserved {
 			return name, nil
 		}
 	}
@@ -59,7 +66,9 @@ func (d *nameAllocator) GetNext(existingNames ExistingNames, likelyBadNames *syn
 	finalResortName := ""
 	likelyBadNames.Range(func(name, _ any) bool {
 		if name, ok := name.(string); ok {
-			if _, existing := existingNames[name]; !existing {
+			_, existing := existingNames[name]
+			_, reserved := d.reservedNames[name]
+			if !existing && !reserved {
 				finalResortName = name
 				return false
 			}
@@ -70,5 +79,5 @@ func (d *nameAllocator) GetNext(existingNames ExistingNames, likelyBadNames *syn
 		return finalResortName, nil
 	}
 
-	return "", errors.New("there are no names available")
+	return "", ErrNoDeviceNamesAvailable
 }
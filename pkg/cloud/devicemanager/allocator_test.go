@@ -17,6 +17,7 @@ limitations under the License.
 package devicemanager
 
 import (
+	"errors"
 	"sync"
 	"testing"
 )
@@ -95,7 +96,36 @@ func TestNameAllocatorError(t *testing.T) {
 		existingNames[name] = ""
 	}
 	name, err := allocator.GetNext(existingNames, new(sync.Map))
-	if err == nil {
-		t.Errorf("expected error, got device  %q", name)
+	if !errors.Is(err, ErrNoDeviceNamesAvailable) {
+		t.Errorf("expected %v, got device %q (err: %v)", ErrNoDeviceNamesAvailable, name, err)
+	}
+}
+
+func TestNameAllocatorReservedNames(t *testing.T) {
+	reservedName := deviceNames[0]
+	reservedLikelyBadName := deviceNames[5]
+	likelyBadNames := new(sync.Map)
+	likelyBadNames.Store(reservedLikelyBadName, struct{}{})
+	allocator := nameAllocator{reservedNames: map[string]struct{}{
+		reservedName:          {},
+		reservedLikelyBadName: {},
+	}}
+	existingNames := map[string]string{}
+
+	for range len(deviceNames) - 2 {
+		name, err := allocator.GetNext(existingNames, likelyBadNames)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if name == reservedName || name == reservedLikelyBadName {
+			t.Fatalf("reserved name %q was allocated", name)
+		}
+		existingNames[name] = ""
+	}
+
+	// Reserved names are not used as a last resort, even when they are likely bad names
+	name, err := allocator.GetNext(existingNames, likelyBadNames)
+	if !errors.Is(err, ErrNoDeviceNamesAvailable) {
+		t.Errorf("expected %v, got device %q (err: %v)", ErrNoDeviceNamesAvailable, name, err)
 	}
 }
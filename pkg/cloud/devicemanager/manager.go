@@ -97,6 +97,9 @@ func (i inFlightAttaching) Add(nodeID, volumeID, deviceName string, cardIndex *i
 
 func (i inFlightAttaching) Del(nodeID, volumeID string) {
 	delete(i[nodeID], volumeID)
+	if len(i[nodeID]) == 0 {
+		delete(i, nodeID)
+	}
 }
 
 func (i inFlightAttaching) GetNames(nodeID string) map[string]string {
@@ -116,9 +119,14 @@ func (i inFlightAttaching) GetEntries(nodeID string) map[string]inFlightEntry {
 	return i[nodeID]
 }
 
-func NewDeviceManager() DeviceManager {
+// NewDeviceManager returns a DeviceManager that never assigns any of reservedNames to a volume.
+func NewDeviceManager(reservedNames ...string) DeviceManager {
+	reserved := make(map[string]struct{}, len(reservedNames))
+	for _, name := range reservedNames {
+		reserved[name] = struct{}{}
+	}
 	return &deviceManager{
-		nameAllocator: &nameAllocator{},
+		nameAllocator: &nameAllocator{reservedNames: reserved},
 		inFlight:      make(inFlightAttaching),
 	}
 }
@@ -147,7 +155,7 @@ func (d *deviceManager) NewDevice(instance *types.Instance, volumeID string, lik
 
 	name, err := d.nameAllocator.GetNext(inUse, likelyBadNames)
 	if err != nil {
-		return nil, fmt.Errorf("could not get a free device name to assign to node %s", nodeID)
+		return nil, fmt.Errorf("could not get a free device name to assign to node %s: %w", nodeID, err)
 	}
 
 	// Calculate card index for new volume
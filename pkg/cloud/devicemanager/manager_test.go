@@ -224,6 +224,64 @@ func TestReleaseDevice(t *testing.T) {
 	}
 }
 
+func TestNewDeviceMultiAttach(t *testing.T) {
+	const volumeID = "vol-io2"
+	dm := NewDeviceManager()
+	manager, ok := dm.(*deviceManager)
+	if !ok {
+		t.Fatalf("Expected *deviceManager, got %T", dm)
+	}
+
+	instance1 := newFakeInstance("instance-1", "vol-1", "/dev/xvdba")
+	instance2 := newFakeInstance("instance-2", "vol-2", "/dev/xvdba")
+
+	// A multi-attach volume takes a slot on each node it is attached to
+	dev1, err := dm.NewDevice(instance1, volumeID, new(sync.Map), 2)
+	assertDevice(t, dev1, false /*IsAlreadyAssigned*/, err)
+	dev2, err := dm.NewDevice(instance2, volumeID, new(sync.Map), 2)
+	assertDevice(t, dev2, false /*IsAlreadyAssigned*/, err)
+
+	for _, nodeID := range []string{"instance-1", "instance-2"} {
+		if entries := manager.inFlight.GetEntries(nodeID); len(entries) != 1 {
+			t.Fatalf("Expected 1 attachment in progress on %s, got %v", nodeID, entries)
+		}
+	}
+
+	// Attaching again to a node does not take another slot
+	dev3, err := dm.NewDevice(instance1, volumeID, new(sync.Map), 2)
+	assertDevice(t, dev3, true /*IsAlreadyAssigned*/, err)
+	if dev3.Path != dev1.Path {
+		t.Fatalf("Expected equal paths, got %v and %v", dev1.Path, dev3.Path)
+	}
+	if entries := manager.inFlight.GetEntries("instance-1"); len(entries) != 1 {
+		t.Fatalf("Expected 1 attachment in progress on instance-1, got %v", entries)
+	}
+
+	// Releasing the volume on one node leaves the other node untouched
+	dev1.Release(true)
+	if _, exists := manager.inFlight["instance-1"]; exists {
+		t.Fatalf("Expected no attachments in progress on instance-1, got %v", manager.inFlight["instance-1"])
+	}
+	if _, exists := manager.inFlight.GetEntry("instance-2", volumeID); !exists {
+		t.Fatalf("Expected %s to still be attaching to instance-2", volumeID)
+	}
+
+	// Once attached to both nodes, each node reports its own device
+	instance1.BlockDeviceMappings = append(instance1.BlockDeviceMappings, types.InstanceBlockDeviceMapping{
+		DeviceName: aws.String(dev1.Path),
+		Ebs:        &types.EbsInstanceBlockDevice{VolumeId: aws.String(volumeID), EbsCardIndex: dev1.CardIndex},
+	})
+	dev4, err := dm.GetDevice(instance1, volumeID)
+	assertDevice(t, dev4, true /*IsAlreadyAssigned*/, err)
+	dev5, err := dm.GetDevice(instance2, volumeID)
+	assertDevice(t, dev5, true /*IsAlreadyAssigned*/, err)
+
+	dev2.Release(true)
+	if len(manager.inFlight) != 0 {
+		t.Fatalf("Expected no attachments in progress, got %v", manager.inFlight)
+	}
+}
+
 func newFakeInstance(instanceID, volumeID, devicePath string) *types.Instance {
 	return &types.Instance{
 		InstanceId: aws.String(instanceID),
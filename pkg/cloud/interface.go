@@ -28,8 +28,12 @@ type Cloud interface {
 	DetachDisk(ctx context.Context, volumeID string, nodeID string) (err error)
 	ModifyTags(ctx context.Context, volumeID string, tagOptions ModifyTagsOptions) (err error)
 	ResizeOrModifyDisk(ctx context.Context, volumeID string, newSizeBytes int64, options *ModifyDiskOptions) (newSize int32, err error)
+	GetVolumeModificationProgress(ctx context.Context, volumeID string) (state string, percent int, err error)
 	WaitForAttachmentState(ctx context.Context, expectedState types.VolumeAttachmentState, volumeID string, expectedInstance string, expectedDevice string, alreadyAssigned bool, expectedCardIndex *int32) (*types.VolumeAttachment, error)
 	IsVolumeInitialized(ctx context.Context, volumeID string) (bool, error)
+	IsNitroInstanceType(ctx context.Context, instanceType string) bool
+	GetInstanceTypeMaxIOPS(ctx context.Context, instanceType string) (maxIOPS int32, err error)
+	GetInstanceTypeAcceleratorCount(ctx context.Context, instanceType string) (count int, err error)
 	GetDiskByName(ctx context.Context, name string, capacityBytes int64) (disk *Disk, err error)
 	GetDiskByID(ctx context.Context, volumeID string) (disk *Disk, err error)
 	GetVolumeIDByNodeAndDevice(ctx context.Context, nodeID string, deviceName string) (volumeID string, err error)
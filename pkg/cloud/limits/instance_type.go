@@ -0,0 +1,109 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidInstanceType is returned for strings that are not, or do not contain, an instance type.
+var ErrInvalidInstanceType = errors.New("invalid instance type")
+
+// parsedInstanceType is an instance type split into its family and size, for example
+// "m5d" and "metal" for "m5d.metal", or "u7i-12tb" and "224xlarge" for "u7i-12tb.224xlarge".
+type parsedInstanceType struct {
+	family  string
+	size    string
+	isMetal bool
+}
+
+// NormalizeInstanceType returns the canonical form of an instance type as used by the limits tables.
+// AWS instance types are lower-case, but some metadata sources return them with a different case or
+// surrounding whitespace such as a trailing newline.
+func NormalizeInstanceType(instanceType string) string {
+	return strings.ToLower(strings.TrimSpace(instanceType))
+}
+
+// parseInstanceType parses an instance type of the form <family>.<size> after normalizing it.
+func parseInstanceType(instanceType string) (parsedInstanceType, error) {
+	family, size, found := strings.Cut(NormalizeInstanceType(instanceType), ".")
+	if !found || family == "" || size == "" || strings.Contains(size, ".") {
+		return parsedInstanceType{}, fmt.Errorf("%w %q: expected <family>.<size>", ErrInvalidInstanceType, instanceType)
+	}
+	return parsedInstanceType{
+		family: family,
+		size:   size,
+		// Bare metal sizes are either "metal" or "metal-<n>xl"
+		isMetal: size == "metal" || strings.HasPrefix(size, "metal-"),
+	}, nil
+}
+
+// IsBareMetal reports whether instanceType is a bare metal instance type, such as m5d.metal or i7i.metal-24xl.
+// Malformed instance types are not bare metal.
+func IsBareMetal(instanceType string) bool {
+	parsed, err := parseInstanceType(instanceType)
+	return err == nil && parsed.isMetal
+}
+
+// ExtractInstanceType returns the instance type contained in s, for identifiers that embed the instance type
+// in a larger string. The accepted formats are:
+//   - a bare instance type such as "m5.large", matched case-insensitively with surrounding whitespace ignored
+//   - an instance type delimited by whitespace, quotes or any of / : , ; = ( ) [ ] within a larger string,
+//     such as "arn:aws:ec2:us-east-1::instance-type/m5.large" or "us-east-1a:m5.large"
+//
+// When several tokens look like instance types, the one in the limits tables is returned. An error wrapping
+// ErrInvalidInstanceType is returned when s contains no instance type, or several that are not in the tables.
+func ExtractInstanceType(s string) (string, error) {
+	tokens := strings.FieldsFunc(NormalizeInstanceType(s), func(r rune) bool {
+		return strings.ContainsRune(" \t\n\r/:,;=()[]\"'", r)
+	})
+
+	var candidates []string
+	for _, token := range tokens {
+		if !isInstanceTypeToken(token) {
+			continue
+		}
+		if !isNitro(token) || hasTableLimit(token) {
+			return token, nil
+		}
+		candidates = append(candidates, token)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%w: no <family>.<size> in %q", ErrInvalidInstanceType, s)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("%w: %q contains several candidates %v", ErrInvalidInstanceType, s, candidates)
+	}
+}
+
+// isInstanceTypeToken reports whether token has the form of an instance type: a family starting with
+// a letter and a size, both made of lower-case letters, digits and dashes.
+func isInstanceTypeToken(token string) bool {
+	parsed, err := parseInstanceType(token)
+	if err != nil || parsed.family[0] < 'a' || parsed.family[0] > 'z' {
+		return false
+	}
+	for _, r := range parsed.family + parsed.size {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package limits
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     parsedInstanceType
+		expectErr    bool
+	}{
+		{instanceType: "m5.large", expected: parsedInstanceType{family: "m5", size: "large"}},
+		{instanceType: "m5d.metal", expected: parsedInstanceType{family: "m5d", size: "metal", isMetal: true}},
+		{instanceType: "i7i.metal-24xl", expected: parsedInstanceType{family: "i7i", size: "metal-24xl", isMetal: true}},
+		{instanceType: "u7i-12tb.224xlarge", expected: parsedInstanceType{family: "u7i-12tb", size: "224xlarge"}},
+		{instanceType: "u7in-24tb.224xlarge", expected: parsedInstanceType{family: "u7in-24tb", size: "224xlarge"}},
+		{instanceType: "mac2-m2pro.metal", expected: parsedInstanceType{family: "mac2-m2pro", size: "metal", isMetal: true}},
+		{instanceType: "M7I.24XLARGE\n", expected: parsedInstanceType{family: "m7i", size: "24xlarge"}},
+		{instanceType: " I7I.Metal-24xl\t", expected: parsedInstanceType{family: "i7i", size: "metal-24xl", isMetal: true}},
+		{instanceType: "", expectErr: true},
+		{instanceType: "m5", expectErr: true},
+		{instanceType: ".large", expectErr: true},
+		{instanceType: "m5.", expectErr: true},
+		{instanceType: "m5.large.extra", expectErr: true},
+		{instanceType: " \n", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			parsed, err := parseInstanceType(tc.instanceType)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("parseInstanceType(%q) = %+v, expected error", tc.instanceType, parsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInstanceType(%q) returned unexpected error: %v", tc.instanceType, err)
+			}
+			if parsed != tc.expected {
+				t.Errorf("parseInstanceType(%q) = %+v, expected %+v", tc.instanceType, parsed, tc.expected)
+			}
+		})
+	}
+}
+
+func TestIsBareMetal(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     bool
+	}{
+		{instanceType: "m5d.metal", expected: true},
+		{instanceType: "i7i.metal-24xl", expected: true},
+		{instanceType: "I7I.Metal-48xl", expected: true},
+		{instanceType: "mac2-m2pro.metal", expected: true},
+		{instanceType: "m5.large"},
+		{instanceType: "u7i-12tb.224xlarge"},
+		{instanceType: "metal"},
+		{instanceType: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			if isMetal := IsBareMetal(tc.instanceType); isMetal != tc.expected {
+				t.Errorf("IsBareMetal(%q) = %t, expected %t", tc.instanceType, isMetal, tc.expected)
+			}
+		})
+	}
+}
+
+func TestExtractInstanceType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		expErr   bool
+	}{
+		{name: "clean", input: "m5.large", expected: "m5.large"},
+		{name: "case and whitespace", input: " I7I.Metal-24xl\n", expected: "i7i.metal-24xl"},
+		{name: "ARN", input: "arn:aws:ec2:us-east-1::instance-type/m7i.48xlarge", expected: "m7i.48xlarge"},
+		{name: "zone prefix", input: "us-east-1a:u7i-12tb.224xlarge", expected: "u7i-12tb.224xlarge"},
+		{name: "key value", input: "node=ip-10-0-0-1, type=c4.large", expected: "c4.large"},
+		{name: "hostname next to a table instance type", input: "ec2.internal m7i.large", expected: "m7i.large"},
+		{name: "instance type missing from the tables", input: "type=m5.large", expected: "m5.large"},
+		{name: "several candidates", input: "m5.large ec2.internal", expErr: true},
+		{name: "no instance type", input: "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0", expErr: true},
+		{name: "empty", input: "", expErr: true},
+		{name: "too many dots", input: "m5.large.extra", expErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			instanceType, err := ExtractInstanceType(tc.input)
+			if tc.expErr {
+				if !errors.Is(err, ErrInvalidInstanceType) {
+					t.Errorf("ExtractInstanceType(%q) = (%q, %v), expected ErrInvalidInstanceType", tc.input, instanceType, err)
+				}
+				return
+			}
+			if err != nil || instanceType != tc.expected {
+				t.Errorf("ExtractInstanceType(%q) = (%q, %v), expected %q", tc.input, instanceType, err, tc.expected)
+			}
+		})
+	}
+}
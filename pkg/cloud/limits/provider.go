@@ -0,0 +1,328 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package limits
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+)
+
+// VolumeLimitProvider provides the volume attachment limits of instance types.
+// Implementations can return limits that differ from the static tables, for example
+// to account for account or region specific quotas, or to fake limits in tests.
+type VolumeLimitProvider interface {
+	// GetVolumeLimits returns the volume limit and attachment type for a given instance type.
+	GetVolumeLimits(instanceType string) (int, string)
+	// HasDedicatedEBSLimit reports whether the instance type has a dedicated EBS attachment limit.
+	HasDedicatedEBSLimit(instanceType string) bool
+	// IsNitroInstanceType reports whether the instance type is built on the Nitro System.
+	IsNitroInstanceType(instanceType string) bool
+	// GetCardCount returns the number of EBS cards for a given instance type.
+	GetCardCount(instanceType string) int
+	// KnownInstanceTypes returns all instance types the provider has limits for.
+	KnownInstanceTypes() []string
+}
+
+// limitSourcer is implemented by the VolumeLimitProviders of this package to report where they take the limit
+// of an instance type from.
+type limitSourcer interface {
+	limitSource(instanceType string) string
+}
+
+// ProviderLimitSource returns where p takes the limit of instanceType from. For the VolumeLimitProvider of the
+// static tables and the providers wrapping it, it is the table reported by LimitSource. The limit set of
+// NewVolumeLimitProvider is reported as "limit-set", and other providers as "provider" for the instance types
+// they report as known. Instance types with the default limit are reported as "default", and empty or malformed
+// instance types as "unrecognized".
+func ProviderLimitSource(p VolumeLimitProvider, instanceType string) string {
+	if _, err := parseInstanceType(instanceType); err != nil {
+		return "unrecognized"
+	}
+	if sourcer, ok := p.(limitSourcer); ok {
+		return sourcer.limitSource(instanceType)
+	}
+	if slices.Contains(p.KnownInstanceTypes(), NormalizeInstanceType(instanceType)) {
+		return "provider"
+	}
+	return "default"
+}
+
+// hasDefaultLimit reports whether p has no limit of its own for instanceType.
+func hasDefaultLimit(p VolumeLimitProvider, instanceType string) bool {
+	source := ProviderLimitSource(p, instanceType)
+	return source == "default" || source == "unrecognized"
+}
+
+// tableVolumeLimitProvider is the VolumeLimitProvider backed by the static limits tables.
+type tableVolumeLimitProvider struct{}
+
+var _ VolumeLimitProvider = tableVolumeLimitProvider{}
+
+// DefaultVolumeLimitProvider returns the VolumeLimitProvider backed by the static limits tables.
+func DefaultVolumeLimitProvider() VolumeLimitProvider {
+	return tableVolumeLimitProvider{}
+}
+
+func (tableVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	return GetVolumeLimits(instanceType)
+}
+
+func (tableVolumeLimitProvider) HasDedicatedEBSLimit(instanceType string) bool {
+	return HasDedicatedEBSLimit(instanceType)
+}
+
+func (tableVolumeLimitProvider) IsNitroInstanceType(instanceType string) bool {
+	return isNitro(instanceType)
+}
+
+func (tableVolumeLimitProvider) GetCardCount(instanceType string) int {
+	return GetCardCount(instanceType)
+}
+
+func (tableVolumeLimitProvider) KnownInstanceTypes() []string {
+	return KnownInstanceTypes()
+}
+
+func (tableVolumeLimitProvider) limitSource(instanceType string) string {
+	return LimitSource(instanceType)
+}
+
+// InstanceTypeLimit is the attachment limit of an instance type in the limit set of NewVolumeLimitProvider.
+type InstanceTypeLimit struct {
+	// MaxAttachments is the attachment limit of the instance type.
+	MaxAttachments int
+	// AttachmentType is either util.AttachmentShared or util.AttachmentDedicated.
+	AttachmentType string
+}
+
+// setVolumeLimitProvider is the VolumeLimitProvider backed by a limit set instead of the static limits tables.
+type setVolumeLimitProvider struct {
+	limits map[string]InstanceTypeLimit
+}
+
+// NewVolumeLimitProvider returns a VolumeLimitProvider that resolves attachment limits only against limitSet,
+// keyed by instance type. Instance types that are not in limitSet have a shared limit of NitroMaxAttachments.
+// limitSet is copied, so the provider is unaffected by later changes to it, and it never reads or changes the
+// package level tables for attachment limits, which makes it suitable for tests of alternate limit scenarios.
+// Whether an instance type is built on the Nitro System and its number of EBS cards still come from the
+// static tables.
+func NewVolumeLimitProvider(limitSet map[string]InstanceTypeLimit) VolumeLimitProvider {
+	p := setVolumeLimitProvider{limits: make(map[string]InstanceTypeLimit, len(limitSet))}
+	for instanceType, limit := range limitSet {
+		p.limits[NormalizeInstanceType(instanceType)] = limit
+	}
+	return p
+}
+
+func (p setVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	if limit, exists := p.limits[NormalizeInstanceType(instanceType)]; exists {
+		return limit.MaxAttachments, limit.AttachmentType
+	}
+	return NitroMaxAttachments, util.AttachmentShared
+}
+
+func (p setVolumeLimitProvider) HasDedicatedEBSLimit(instanceType string) bool {
+	_, attachmentType := p.GetVolumeLimits(instanceType)
+	return attachmentType == util.AttachmentDedicated
+}
+
+func (setVolumeLimitProvider) IsNitroInstanceType(instanceType string) bool {
+	return isNitro(instanceType)
+}
+
+func (setVolumeLimitProvider) GetCardCount(instanceType string) int {
+	return GetCardCount(instanceType)
+}
+
+func (p setVolumeLimitProvider) KnownInstanceTypes() []string {
+	return slices.Sorted(maps.Keys(p.limits))
+}
+
+func (p setVolumeLimitProvider) limitSource(instanceType string) string {
+	if _, exists := p.limits[NormalizeInstanceType(instanceType)]; exists {
+		return "limit-set"
+	}
+	return "default"
+}
+
+// sharedFamiliesVolumeLimitProvider reports the attachment limit of the instance types of some families as shared,
+// regardless of the attachment type reported by the wrapped VolumeLimitProvider.
+type sharedFamiliesVolumeLimitProvider struct {
+	VolumeLimitProvider
+	families map[string]struct{}
+}
+
+// WithSharedFamilies returns a VolumeLimitProvider that reports the limits of p, except that the attachment limit
+// of instance types of the given families is always shared. This corrects instance families whose attachment
+// limit was changed from dedicated to shared for an account before the limits tables are updated.
+func WithSharedFamilies(p VolumeLimitProvider, families []string) VolumeLimitProvider {
+	familySet := make(map[string]struct{}, len(families))
+	for _, family := range families {
+		familySet[NormalizeInstanceType(family)] = struct{}{}
+	}
+	return sharedFamiliesVolumeLimitProvider{VolumeLimitProvider: p, families: familySet}
+}
+
+func (p sharedFamiliesVolumeLimitProvider) isShared(instanceType string) bool {
+	parsed, err := parseInstanceType(instanceType)
+	if err != nil {
+		return false
+	}
+	_, shared := p.families[parsed.family]
+	return shared
+}
+
+func (p sharedFamiliesVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	limit, attachmentType := p.VolumeLimitProvider.GetVolumeLimits(instanceType)
+	if p.isShared(instanceType) {
+		attachmentType = util.AttachmentShared
+	}
+	return limit, attachmentType
+}
+
+func (p sharedFamiliesVolumeLimitProvider) HasDedicatedEBSLimit(instanceType string) bool {
+	return !p.isShared(instanceType) && p.VolumeLimitProvider.HasDedicatedEBSLimit(instanceType)
+}
+
+func (p sharedFamiliesVolumeLimitProvider) limitSource(instanceType string) string {
+	return ProviderLimitSource(p.VolumeLimitProvider, instanceType)
+}
+
+// maxAttachmentsVolumeLimitProvider replaces the default attachment limits of non-Nitro instance types and of
+// Nitro instance types that are not in the limits tables.
+type maxAttachmentsVolumeLimitProvider struct {
+	VolumeLimitProvider
+	nonNitroMaxAttachments int
+	nitroMaxAttachments    int
+}
+
+// WithMaxAttachments returns a VolumeLimitProvider that reports the limits of p, except that non-Nitro instance
+// types can attach nonNitroMaxAttachments volumes, and Nitro instance types without a limit in the tables
+// nitroMaxAttachments volumes, instead of NonNitroMaxAttachments and NitroMaxAttachments. A value of 0 keeps
+// the limit reported by p.
+func WithMaxAttachments(p VolumeLimitProvider, nonNitroMaxAttachments, nitroMaxAttachments int) VolumeLimitProvider {
+	return maxAttachmentsVolumeLimitProvider{
+		VolumeLimitProvider:    p,
+		nonNitroMaxAttachments: nonNitroMaxAttachments,
+		nitroMaxAttachments:    nitroMaxAttachments,
+	}
+}
+
+func (p maxAttachmentsVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	limit, attachmentType := p.VolumeLimitProvider.GetVolumeLimits(instanceType)
+	switch {
+	case !p.IsNitroInstanceType(instanceType):
+		if p.nonNitroMaxAttachments > 0 {
+			limit = p.nonNitroMaxAttachments
+		}
+	case hasDefaultLimit(p.VolumeLimitProvider, instanceType):
+		if p.nitroMaxAttachments > 0 {
+			limit = p.nitroMaxAttachments
+		}
+	}
+	return limit, attachmentType
+}
+
+func (p maxAttachmentsVolumeLimitProvider) limitSource(instanceType string) string {
+	return ProviderLimitSource(p.VolumeLimitProvider, instanceType)
+}
+
+// nitroDetectorVolumeLimitProvider decides whether instance types are built on the Nitro System with a function
+// instead of the static table.
+type nitroDetectorVolumeLimitProvider struct {
+	VolumeLimitProvider
+	isNitro func(instanceType string) bool
+}
+
+// WithNitroDetector returns a VolumeLimitProvider that reports the limits of p, except that isNitro decides whether
+// an instance type is built on the Nitro System, for example from the hypervisor reported by DescribeInstanceTypes.
+// Instance types that p considers Nitro but isNitro does not have the dedicated limit of non-Nitro instance types,
+// and instance types that p considers non-Nitro but isNitro does not have the default limit of Nitro instance types.
+func WithNitroDetector(p VolumeLimitProvider, isNitro func(instanceType string) bool) VolumeLimitProvider {
+	return nitroDetectorVolumeLimitProvider{VolumeLimitProvider: p, isNitro: isNitro}
+}
+
+func (p nitroDetectorVolumeLimitProvider) IsNitroInstanceType(instanceType string) bool {
+	return p.isNitro(instanceType)
+}
+
+func (p nitroDetectorVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	nitro := p.isNitro(instanceType)
+	switch {
+	case nitro == p.VolumeLimitProvider.IsNitroInstanceType(instanceType):
+		return p.VolumeLimitProvider.GetVolumeLimits(instanceType)
+	case !nitro:
+		return NonNitroMaxAttachments, util.AttachmentDedicated
+	default:
+		// Non-Nitro instance types are in none of the other limits tables
+		return NitroMaxAttachments, util.AttachmentShared
+	}
+}
+
+func (p nitroDetectorVolumeLimitProvider) HasDedicatedEBSLimit(instanceType string) bool {
+	_, attachmentType := p.GetVolumeLimits(instanceType)
+	return attachmentType == util.AttachmentDedicated
+}
+
+func (p nitroDetectorVolumeLimitProvider) limitSource(instanceType string) string {
+	nitro := p.isNitro(instanceType)
+	switch {
+	case nitro == p.VolumeLimitProvider.IsNitroInstanceType(instanceType):
+		return ProviderLimitSource(p.VolumeLimitProvider, instanceType)
+	case !nitro:
+		return "non-nitro"
+	default:
+		return "default"
+	}
+}
+
+// unknownFamilyVolumeLimitProvider reports a fixed shared attachment limit for instance types of families that
+// are in none of the limits tables.
+type unknownFamilyVolumeLimitProvider struct {
+	VolumeLimitProvider
+	maxAttachments int
+}
+
+// WithUnknownFamilyLimit returns a VolumeLimitProvider that reports the limits of p, except that instance types
+// of families unknown to the limits tables, as reported by IsKnownInstanceFamily, have a shared limit of
+// maxAttachments instead of the default limit of Nitro instance types. A value of 0 keeps the limit reported by p.
+func WithUnknownFamilyLimit(p VolumeLimitProvider, maxAttachments int) VolumeLimitProvider {
+	return unknownFamilyVolumeLimitProvider{VolumeLimitProvider: p, maxAttachments: maxAttachments}
+}
+
+func (p unknownFamilyVolumeLimitProvider) isUnknown(instanceType string) bool {
+	if p.maxAttachments <= 0 {
+		return false
+	}
+	_, err := parseInstanceType(instanceType)
+	return err == nil && !IsKnownInstanceFamily(instanceType)
+}
+
+func (p unknownFamilyVolumeLimitProvider) GetVolumeLimits(instanceType string) (int, string) {
+	if p.isUnknown(instanceType) {
+		return p.maxAttachments, util.AttachmentShared
+	}
+	return p.VolumeLimitProvider.GetVolumeLimits(instanceType)
+}
+
+func (p unknownFamilyVolumeLimitProvider) HasDedicatedEBSLimit(instanceType string) bool {
+	return !p.isUnknown(instanceType) && p.VolumeLimitProvider.HasDedicatedEBSLimit(instanceType)
+}
+
+func (p unknownFamilyVolumeLimitProvider) limitSource(instanceType string) string {
+	return ProviderLimitSource(p.VolumeLimitProvider, instanceType)
+}
@@ -14,7 +14,16 @@
 
 package limits
 
-import "github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+	"k8s.io/klog/v2"
+)
 
 // Instance types for where the API incorrectly returns shared
 // when they actually are dedicated attachment limits.
@@ -49,14 +58,146 @@ var dedicatedInstances = map[string]struct{}{
 	"c8ib.metal-96xl": {},
 }
 
+// Instance types that the API does not return, so they are missing from the generated table.
+// Limits of shared instance types must already exclude the attachments taken by instance store volumes.
+var missingInstanceTypes = map[string]volumeLimit{
+	// Bare metal limit of 31 attachments minus 8 NVMe instance store volumes
+	"i3.metal": {23, util.AttachmentShared},
+}
+
+// Nitro instance families whose instance types all have the default limit, so none of them is in the
+// generated table. Together with the families in the tables, these are the families IsKnownInstanceFamily
+// recognizes.
+var defaultLimitInstanceFamilies = []string{
+	"c5a",
+	"c6gn",
+	"hpc6a",
+	"hpc7g",
+	"m5a",
+	"r5a",
+	"t3",
+	"t3a",
+	"t4g",
+}
+
+// Number of NVMe instance store volumes of shared instance types, whose attachment limit in the tables
+// already excludes the attachments taken by these volumes. Instance types not listed here either have no
+// instance store volumes or an instance store volume count that is not known to be part of their limit.
+var instanceStoreVolumes = map[string]int{
+	"c6gd.medium":   1,
+	"c6gd.large":    1,
+	"c6gd.xlarge":   1,
+	"c6gd.2xlarge":  1,
+	"c6gd.4xlarge":  1,
+	"c6gd.8xlarge":  1,
+	"c6gd.12xlarge": 2,
+	"c6gd.16xlarge": 2,
+	"d3.xlarge":     3,
+	"d3.2xlarge":    6,
+	"d3.4xlarge":    12,
+	"d3.8xlarge":    24,
+	"d3en.xlarge":   2,
+	"d3en.2xlarge":  4,
+	"d3en.4xlarge":  8,
+	"d3en.6xlarge":  12,
+	"d3en.8xlarge":  16,
+	"d3en.12xlarge": 24,
+	"g4ad.xlarge":   1,
+	"g4ad.2xlarge":  1,
+	"g4ad.4xlarge":  1,
+	"g4ad.8xlarge":  1,
+	"g4ad.16xlarge": 2,
+	"g4dn.xlarge":   1,
+	"g4dn.2xlarge":  1,
+	"g4dn.4xlarge":  1,
+	"g4dn.8xlarge":  1,
+	"g4dn.12xlarge": 1,
+	"g4dn.16xlarge": 1,
+	"g5.xlarge":     1,
+	"g5.2xlarge":    1,
+	"g5.4xlarge":    1,
+	"g5.8xlarge":    1,
+	"g5.12xlarge":   1,
+	"g5.16xlarge":   1,
+	"g5.24xlarge":   1,
+	"i3.metal":      8,
+	"i4i.large":     1,
+	"i4i.xlarge":    1,
+	"i4i.2xlarge":   1,
+	"i4i.4xlarge":   1,
+	"i4i.8xlarge":   2,
+	"i4i.12xlarge":  3,
+	"i4i.16xlarge":  4,
+	"i4i.24xlarge":  6,
+	"i4i.32xlarge":  8,
+	"p3dn.24xlarge": 2,
+}
+
+// Number of GPUs of shared instance types, whose attachment limit in the tables already excludes the
+// attachments taken by the GPUs.
+var gpusPerInstance = map[string]int{
+	"g4dn.xlarge":   1,
+	"g4dn.2xlarge":  1,
+	"g4dn.4xlarge":  1,
+	"g4dn.8xlarge":  1,
+	"g4dn.12xlarge": 4,
+	"g4dn.16xlarge": 1,
+	"g5.xlarge":     1,
+	"g5.2xlarge":    1,
+	"g5.4xlarge":    1,
+	"g5.8xlarge":    1,
+	"g5.12xlarge":   4,
+	"g5.16xlarge":   1,
+	"g5.24xlarge":   4,
+}
+
+// Instance types with more than one network card. Every network card needs its own ENI, which
+// takes an attachment slot on shared instance types even before it is attached to the instance.
+var networkCardsPerInstance = map[string]int{
+	"c6in.32xlarge":  2,
+	"c6in.metal":     2,
+	"m6in.32xlarge":  2,
+	"m6in.metal":     2,
+	"m6idn.32xlarge": 2,
+	"m6idn.metal":    2,
+	"r6in.32xlarge":  2,
+	"r6in.metal":     2,
+	"r6idn.32xlarge": 2,
+	"r6idn.metal":    2,
+	"dl1.24xlarge":   4,
+	"trn1.32xlarge":  8,
+	"trn1n.32xlarge": 16,
+}
+
+const (
+	// NonNitroMaxAttachments is the attachment limit of instance types not built on the Nitro System.
+	NonNitroMaxAttachments = 39
+	// NitroMaxAttachments is the shared attachment limit of Nitro instance types that are not in the limits tables.
+	NitroMaxAttachments = 27
+)
+
 // GetVolumeLimits returns the volume limit and attachment type for a given instance type.
 // Returns (limit, attachmentType) where limit is the maximum number of volumes
 // and attachmentType is either "shared" or "dedicated".
+// The instance type is matched case-insensitively and surrounding whitespace is ignored,
+// the tables only contain the canonical lower-case form used by AWS.
+//
+// When an instance type is in more than one table, the first match wins, in this order:
+// nonNitroInstanceTypes, volumeLimits, missingInstanceTypes. The exception is an instance type in both
+// volumeLimits and missingInstanceTypes, which gets the lower of the two limits. dedicatedInstances never
+// changes the limit taken from volumeLimits, only its attachment type.
 func GetVolumeLimits(instanceType string) (int, string) {
-	// Check non-nitro instances first (limit of 39)
+	instanceType = NormalizeInstanceType(instanceType)
+
+	// Malformed instance types are not in any table, skip straight to the default
+	if _, err := parseInstanceType(instanceType); err != nil {
+		return NitroMaxAttachments, util.AttachmentShared
+	}
+
+	// Check non-nitro instances first
 	// The API calls these shared, but we treat them as dedicated
 	if _, exists := nonNitroInstanceTypes[instanceType]; exists {
-		return 39, util.AttachmentDedicated
+		return NonNitroMaxAttachments, util.AttachmentDedicated
 	}
 
 	// Check volume limits table
@@ -65,28 +206,415 @@ func GetVolumeLimits(instanceType string) (int, string) {
 		if _, shouldBeDedicated := dedicatedInstances[instanceType]; shouldBeDedicated {
 			limit.attachmentType = util.AttachmentDedicated
 		}
+		if missingLimit, lower := lowerMissingLimit(instanceType, limit); lower {
+			return missingLimit.maxAttachments, missingLimit.attachmentType
+		}
+		return limit.maxAttachments, limit.attachmentType
+	}
+
+	if limit, exists := missingInstanceTypes[instanceType]; exists {
 		return limit.maxAttachments, limit.attachmentType
 	}
 
-	// Default to shared limit of 27
-	return 27, util.AttachmentShared
+	// Default to shared limit of 27. The generated table leaves out Nitro instance types with exactly
+	// this limit, so a miss is not necessarily an unknown instance type.
+	return NitroMaxAttachments, util.AttachmentShared
 }
 
-// KnownInstanceTypes returns all known instance types from the limits table.
-func KnownInstanceTypes() []string {
-	knownTypes := []string{}
+// LimitSource returns which table GetVolumeLimits takes the limit of instanceType from: "non-nitro" for
+// non-Nitro instance types, "volume-limits" for the generated table, "missing-instance-types" for instance
+// types missing from the generated table, "default" for instance types with the default limit and
+// "unrecognized" for empty or malformed instance types, which also get the default limit.
+func LimitSource(instanceType string) string {
+	instanceType = NormalizeInstanceType(instanceType)
+	if _, err := parseInstanceType(instanceType); err != nil {
+		return "unrecognized"
+	}
+	if _, exists := nonNitroInstanceTypes[instanceType]; exists {
+		return "non-nitro"
+	}
+	if limit, exists := volumeLimits[instanceType]; exists {
+		if _, lower := lowerMissingLimit(instanceType, limit); lower {
+			return "missing-instance-types"
+		}
+		return "volume-limits"
+	}
+	if _, exists := missingInstanceTypes[instanceType]; exists {
+		return "missing-instance-types"
+	}
+	return "default"
+}
+
+// overlapWarnings holds the instance types in both volumeLimits and missingInstanceTypes that were logged.
+var overlapWarnings sync.Map
+
+// lowerMissingLimit returns the limit of instanceType in missingInstanceTypes and whether it is lower than limit,
+// its limit in volumeLimits. The tables should not overlap, so the first lookup of an instance type in both logs
+// a warning.
+func lowerMissingLimit(instanceType string, limit volumeLimit) (volumeLimit, bool) {
+	missingLimit, exists := missingInstanceTypes[instanceType]
+	if !exists {
+		return volumeLimit{}, false
+	}
+	if _, logged := overlapWarnings.LoadOrStore(instanceType, struct{}{}); !logged {
+		klog.Warningf("Instance type %s is in both the generated limits table (limit %d) and missingInstanceTypes (limit %d), using the lower limit",
+			instanceType, limit.maxAttachments, missingLimit.maxAttachments)
+	}
+	return missingLimit, missingLimit.maxAttachments < limit.maxAttachments
+}
+
+// hasTableLimit reports whether GetVolumeLimits takes the limit of instanceType from a table
+// of Nitro instance types instead of returning NitroMaxAttachments.
+func hasTableLimit(instanceType string) bool {
+	instanceType = NormalizeInstanceType(instanceType)
+	if _, exists := volumeLimits[instanceType]; exists {
+		return true
+	}
+	_, exists := missingInstanceTypes[instanceType]
+	return exists
+}
+
+// knownInstanceFamilies holds the instance families with at least one instance type in the limits tables,
+// and the families of defaultLimitInstanceFamilies.
+var knownInstanceFamilies = sync.OnceValue(func() map[string]struct{} {
+	families := make(map[string]struct{})
+	for _, family := range defaultLimitInstanceFamilies {
+		families[family] = struct{}{}
+	}
+	for _, table := range [][]string{
+		slices.Collect(maps.Keys(volumeLimits)),
+		slices.Collect(maps.Keys(missingInstanceTypes)),
+		slices.Collect(maps.Keys(nonNitroInstanceTypes)),
+	} {
+		for _, instanceType := range table {
+			if parsed, err := parseInstanceType(instanceType); err == nil {
+				families[parsed.family] = struct{}{}
+			}
+		}
+	}
+	return families
+})
+
+// IsKnownInstanceFamily reports whether the family of instanceType has an instance type in the limits tables
+// or is listed in defaultLimitInstanceFamilies. Instance types of unknown families, for example families released after the tables were generated, are
+// assumed to be built on the Nitro System and get the default limit.
+func IsKnownInstanceFamily(instanceType string) bool {
+	parsed, err := parseInstanceType(instanceType)
+	if err != nil {
+		return false
+	}
+	_, known := knownInstanceFamilies()[parsed.family]
+	return known
+}
+
+// NitroInstanceType reports whether the instance type is built on the Nitro System according
+// to the static limits table. Callers with EC2 API access should prefer the hypervisor reported
+// by DescribeInstanceTypes and only use this as an offline fallback.
+// Returns an error for malformed instance types.
+func NitroInstanceType(instanceType string) (bool, error) {
+	if _, err := parseInstanceType(instanceType); err != nil {
+		return false, err
+	}
+	return isNitro(instanceType), nil
+}
+
+// IsNitroInstanceType is like NitroInstanceType, but returns false for malformed instance types.
+//
+// Deprecated: Use NitroInstanceType, which reports malformed instance types as an error.
+func IsNitroInstanceType(instanceType string) bool {
+	nitro, err := NitroInstanceType(instanceType)
+	if err != nil {
+		klog.Warningf("Cannot tell whether instance type %s is built on the Nitro System, assuming it is not: %v", instanceType, err)
+		return false
+	}
+	return nitro
+}
+
+// isNitro reports whether instanceType is missing from the table of non-Nitro instance types.
+// Instance types the driver does not know, including malformed ones, are assumed to be Nitro.
+func isNitro(instanceType string) bool {
+	_, nonNitro := nonNitroInstanceTypes[NormalizeInstanceType(instanceType)]
+	return !nonNitro
+}
+
+// HasDedicatedEBSLimit reports whether the instance type has an EBS attachment limit that is
+// not shared with other attachments such as ENIs, GPUs or instance store volumes.
+func HasDedicatedEBSLimit(instanceType string) bool {
+	_, attachmentType := GetVolumeLimits(instanceType)
+	return attachmentType == util.AttachmentDedicated
+}
+
+// VolumeLimit describes how the number of volumes that can be attached to an instance type is derived.
+type VolumeLimit struct {
+	// MaxAttachments is the attachment limit of the instance type as listed in the limits table.
+	MaxAttachments int
+	// AttachmentType is either "shared" or "dedicated".
+	AttachmentType string
+	// ReservedAttachments is the number of attachments reserved for volumes not managed by the driver.
+	ReservedAttachments int
+	// ENIAttachments is the number of attachments consumed by ENIs beyond the primary ENI.
+	// Instance types with several network cards reserve one ENI per network card, even when fewer
+	// ENIs are attached. Always 0 for instance types with a dedicated EBS limit.
+	ENIAttachments int
+	// Limit is the number of volumes the driver can attach, never below 1.
+	Limit int
+}
+
+// ReservedSlots returns the number of attachment slots of the instance type that are not available to
+// volumes attached by the driver.
+func (vl VolumeLimit) ReservedSlots() int {
+	return vl.ReservedAttachments + vl.ENIAttachments
+}
+
+// GetVolumeLimit computes the number of volumes the driver can attach to an instance type
+// given the number of reserved attachments and the number of attached ENIs.
+func GetVolumeLimit(instanceType string, reservedAttachments, attachedENIs int) VolumeLimit {
+	return GetVolumeLimitFromProvider(DefaultVolumeLimitProvider(), instanceType, reservedAttachments, attachedENIs)
+}
+
+// GetVolumeLimitFromProvider is like GetVolumeLimit, but uses the limits returned by p.
+func GetVolumeLimitFromProvider(p VolumeLimitProvider, instanceType string, reservedAttachments, attachedENIs int) VolumeLimit {
+	maxAttachments, attachmentType := p.GetVolumeLimits(instanceType)
+	vl := VolumeLimit{
+		MaxAttachments:      maxAttachments,
+		AttachmentType:      attachmentType,
+		ReservedAttachments: reservedAttachments,
+	}
 
+	// For shared attachment types, ENIs other than the primary ENI consume attachment slots.
+	// The attached ENIs already include EFA and network card ENIs, so the network cards only
+	// raise the count when their ENIs are not attached yet.
+	if !p.HasDedicatedEBSLimit(instanceType) {
+		vl.ENIAttachments = attachedENIs - 1
+		if networkCards := GetNetworkCardCount(instanceType); networkCards > 1 && attachedENIs < networkCards {
+			vl.ENIAttachments = networkCards - 1
+		}
+	}
+
+	// The limits tables have a single limit per instance type, which is both the EBS limit and the attachment limit
+	vl.Limit = UsableVolumeLimit(maxAttachments, maxAttachments, vl.ReservedSlots())
+	if maxAttachments-vl.ReservedSlots() <= 0 {
+		klog.Warningf("Reserved attachments (%d reserved, %d ENIs) use up the whole attachment limit %d of instance type %s, reporting a limit of 1",
+			vl.ReservedAttachments, vl.ENIAttachments, maxAttachments, instanceType)
+	}
+	return vl
+}
+
+// CanAttachAnotherVolume reports whether another EBS volume can be attached to an instance of instanceType that
+// has currentEBS EBS volumes, including the root volume, and currentENIs ENIs, including the primary ENI, attached.
+// The reason explains the decision with the figures it is based on, for troubleshooting rejected attachments.
+// Attachments are rejected when the counts are impossible, as every instance has at least its primary ENI.
+func CanAttachAnotherVolume(instanceType string, currentEBS, currentENIs int) (bool, string) {
+	switch {
+	case currentEBS < 0:
+		return false, fmt.Sprintf("invalid number of attached EBS volumes: %d", currentEBS)
+	case currentENIs < 1:
+		return false, fmt.Sprintf("invalid number of attached ENIs: %d, the primary ENI is always attached", currentENIs)
+	}
+
+	vl := GetVolumeLimit(instanceType, currentEBS, currentENIs)
+	if free := vl.MaxAttachments - vl.ReservedSlots(); free > 0 {
+		return true, fmt.Sprintf("%d of %d %s attachment slots are free", free, vl.MaxAttachments, vl.AttachmentType)
+	}
+
+	switch {
+	case vl.AttachmentType == util.AttachmentDedicated:
+		return false, fmt.Sprintf("dedicated EBS limit of %d reached: %d EBS volumes attached", vl.MaxAttachments, currentEBS)
+	case vl.ENIAttachments >= vl.MaxAttachments:
+		return false, fmt.Sprintf("shared attachment limit of %d is reserved for ENIs: %d slots taken by ENIs and network cards", vl.MaxAttachments, vl.ENIAttachments)
+	default:
+		return false, fmt.Sprintf("shared attachment limit of %d reached: %d EBS volumes attached, %d slots taken by ENIs and network cards",
+			vl.MaxAttachments, currentEBS, vl.ENIAttachments)
+	}
+}
+
+// UsableVolumeLimit returns the number of volumes the driver can attach to an instance that can attach at most
+// ebsLimit EBS volumes and at most attachmentLimit devices in total, reservedSlots of which are taken by devices
+// not attached by the driver:
+//
+//	min(ebsLimit, attachmentLimit - reservedSlots)
+//
+// On instance types with a dedicated EBS limit, reservedSlots only holds the EBS volumes not attached by the
+// driver, and both limits are the dedicated limit. Never returns less than 1, as Kubernetes treats a limit
+// of 0 as unlimited.
+func UsableVolumeLimit(ebsLimit, attachmentLimit, reservedSlots int) int {
+	return max(min(ebsLimit, attachmentLimit-reservedSlots), 1)
+}
+
+// VolumeLimitForInstanceType returns the number of volumes the driver would report as attachable
+// for an instance type that does not need to exist yet, for example to plan capacity before a node
+// is launched. It only consults the static limits tables and assumes a freshly launched instance:
+// the root volume is the only attached volume and the primary ENI is the only attached ENI.
+// Nodes with more block device mappings or ENIs report a lower limit.
+// An error is returned if instanceType is not of the form <family>.<size>.
+func VolumeLimitForInstanceType(instanceType string) (int, error) {
+	if _, err := parseInstanceType(instanceType); err != nil {
+		return 0, err
+	}
+	return GetVolumeLimit(instanceType, 1, 1).Limit, nil
+}
+
+// VolumeLimitsForInstanceTypes is like VolumeLimitForInstanceType for many instance types at once. It returns
+// the limit of every instance type that could be resolved, keyed by the instance type as passed, and the
+// instance types that could not be resolved, in the order they were passed. Like VolumeLimitForInstanceType,
+// well-formed instance types that are not in the tables resolve to the default limit, because the tables
+// leave out Nitro instance types with the default limit.
+func VolumeLimitsForInstanceTypes(instanceTypes []string) (map[string]int, []string) {
+	limitsByType := make(map[string]int, len(instanceTypes))
+	var unknownTypes []string
+	for _, instanceType := range instanceTypes {
+		if _, resolved := limitsByType[instanceType]; resolved || slices.Contains(unknownTypes, instanceType) {
+			continue
+		}
+		limit, err := VolumeLimitForInstanceType(instanceType)
+		if err != nil {
+			unknownTypes = append(unknownTypes, instanceType)
+			continue
+		}
+		limitsByType[instanceType] = limit
+	}
+	return limitsByType, unknownTypes
+}
+
+// vcpuVolumeLimits maps vCPU counts to the dedicated attachment limit that most instance types of the dedicated
+// families in the generated table have at that size, for example 32 up to 12xlarge (48 vCPUs) and 48 at 16xlarge
+// (64 vCPUs). Each entry applies to instance types with up to maxVCPUs vCPUs.
+var vcpuVolumeLimits = []struct {
+	maxVCPUs       int
+	maxAttachments int
+}{
+	{maxVCPUs: 48, maxAttachments: 32},
+	{maxVCPUs: 64, maxAttachments: 48},
+	{maxVCPUs: 96, maxAttachments: 64},
+	{maxVCPUs: 128, maxAttachments: 88},
+}
+
+// maxVCPUVolumeLimit is the dedicated attachment limit of the largest instance types of the dedicated families.
+const maxVCPUVolumeLimit = 128
+
+// EstimateVolumeLimitFromVCPUs estimates the dedicated attachment limit of an instance type with vcpus vCPUs from
+// the limits of the dedicated families of the same size. It is a last resort for instance types that none of
+// the limits tables knows, as the estimate is only as good as the assumption that a new instance type follows
+// the current dedicated families. The estimate never decreases as vcpus grows. An error is returned if vcpus
+// is not positive.
+func EstimateVolumeLimitFromVCPUs(vcpus int) (int, string, error) {
+	if vcpus <= 0 {
+		return 0, "", fmt.Errorf("invalid vCPU count %d: must be positive", vcpus)
+	}
+	for _, limit := range vcpuVolumeLimits {
+		if vcpus <= limit.maxVCPUs {
+			return limit.maxAttachments, util.AttachmentDedicated, nil
+		}
+	}
+	return maxVCPUVolumeLimit, util.AttachmentDedicated, nil
+}
+
+// KnownInstanceTypes returns the sorted, de-duplicated list of all instance types the
+// limits tables have data for. Nitro instance types with the default shared limit of 27
+// are not in the tables and therefore not returned.
+func KnownInstanceTypes() []string {
+	seen := make(map[string]struct{}, len(volumeLimits))
 	for instanceType := range volumeLimits {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range nonNitroInstanceTypes {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range missingInstanceTypes {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range dedicatedInstances {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range ebsCardCounts {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range networkCardsPerInstance {
+		seen[instanceType] = struct{}{}
+	}
+	for instanceType := range instanceStoreVolumes {
+		seen[instanceType] = struct{}{}
+	}
+
+	knownTypes := make([]string, 0, len(seen))
+	for instanceType := range seen {
 		knownTypes = append(knownTypes, instanceType)
 	}
+	sort.Strings(knownTypes)
 
 	return knownTypes
 }
 
+// DedicatedVolumeLimits returns the attachment limit of every known instance type that GetVolumeLimits
+// reports as dedicated, including the non-Nitro instance types and the types in DedicatedInstanceTypeOverrides.
+// The returned map is a copy and can be modified by the caller.
+func DedicatedVolumeLimits() map[string]int {
+	dedicatedLimits := make(map[string]int)
+	for _, instanceType := range KnownInstanceTypes() {
+		if limit, attachmentType := GetVolumeLimits(instanceType); attachmentType == util.AttachmentDedicated {
+			dedicatedLimits[instanceType] = limit
+		}
+	}
+	return dedicatedLimits
+}
+
+// DedicatedInstanceTypeOverrides returns the instance types that are treated as dedicated even though
+// the EC2 API reports their attachment limit as shared. The returned map is a copy.
+func DedicatedInstanceTypeOverrides() map[string]struct{} {
+	return maps.Clone(dedicatedInstances)
+}
+
+// MissingInstanceTypeLimits returns the attachment limits of the instance types that the EC2 API
+// does not return and are therefore added by hand. The returned map is a copy.
+func MissingInstanceTypeLimits() map[string]int {
+	missingLimits := make(map[string]int, len(missingInstanceTypes))
+	for instanceType, limit := range missingInstanceTypes {
+		missingLimits[instanceType] = limit.maxAttachments
+	}
+	return missingLimits
+}
+
+// GetNetworkCardCount returns the number of network cards for a given instance type.
+// Returns 1 if the instance type is not in the table.
+func GetNetworkCardCount(instanceType string) int {
+	if count, exists := networkCardsPerInstance[NormalizeInstanceType(instanceType)]; exists {
+		return count
+	}
+	return 1
+}
+
+// GetInstanceStoreVolumeCount returns the number of NVMe instance store volumes that the attachment limit
+// of a shared instance type already excludes. Returns 0 if the instance type is not in the table.
+func GetInstanceStoreVolumeCount(instanceType string) int {
+	return instanceStoreVolumes[NormalizeInstanceType(instanceType)]
+}
+
+// GetGPUCount returns the number of GPUs that the attachment limit of a shared instance type already excludes.
+// Returns 0 if the instance type is not in the table.
+func GetGPUCount(instanceType string) int {
+	return gpusPerInstance[NormalizeInstanceType(instanceType)]
+}
+
+// InstanceTypeHasGPUs reports whether the GPU count of instanceType is known, and how many GPUs it has.
+// Only instance types whose limit in the tables excludes their GPUs are known.
+func InstanceTypeHasGPUs(instanceType string) (count int, ok bool) {
+	count, ok = gpusPerInstance[NormalizeInstanceType(instanceType)]
+	return count, ok
+}
+
+// InstanceTypeHasInstanceStore reports whether instanceType is known to have NVMe instance store volumes
+// that take up EBS attachments, and how many. Only instance types with a shared attachment limit are known,
+// so ok is false for instance types whose instance store volumes do not reduce their EBS attachments.
+// It is safe for concurrent use.
+func InstanceTypeHasInstanceStore(instanceType string) (count int, ok bool) {
+	count, ok = instanceStoreVolumes[NormalizeInstanceType(instanceType)]
+	return count, ok
+}
+
 // GetCardCount returns the number of EBS cards for a given instance type.
 // Returns 1 (the default) if the instance type is not in the table.
 func GetCardCount(instanceType string) int {
-	if count, exists := ebsCardCounts[instanceType]; exists {
+	if count, exists := ebsCardCounts[NormalizeInstanceType(instanceType)]; exists {
 		return count
 	}
 	return 1
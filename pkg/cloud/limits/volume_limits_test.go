@@ -0,0 +1,1310 @@
+// Copyright 2024 The Kubernetes Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the 'License');
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an 'AS IS' BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package limits
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+)
+
+func TestHasDedicatedEBSLimit(t *testing.T) {
+	testCases := []struct {
+		name         string
+		instanceType string
+		expected     bool
+	}{
+		{
+			name:         "dedicated nitro instance",
+			instanceType: "m7i.48xlarge",
+			expected:     true,
+		},
+		{
+			name:         "shared instance with GPUs and instance store",
+			instanceType: "g4dn.metal",
+			expected:     false,
+		},
+		{
+			name:         "API reports shared but overridden as dedicated",
+			instanceType: "i7i.metal-24xl",
+			expected:     true,
+		},
+		{
+			name:         "non-nitro instance",
+			instanceType: "t2.medium",
+			expected:     true,
+		},
+		{
+			name:         "unknown instance defaults to shared",
+			instanceType: "unknown.large",
+			expected:     false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := HasDedicatedEBSLimit(tc.instanceType); got != tc.expected {
+				t.Errorf("HasDedicatedEBSLimit(%q) = %v, expected %v", tc.instanceType, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetVolumeLimitsInstanceFamilies(t *testing.T) {
+	// Representative instance types of each family. TestVolumeLimitsMatchDescribeInstanceTypesFixtures and
+	// TestLimitsTablesInvariants check the consistency of the tables as a whole.
+	testCases := []struct {
+		instanceType           string
+		expectedLimit          int
+		expectedAttachmentType string
+		instanceStoreVolumes   int
+	}{
+		// Older Nitro generations share a limit of 27 (31 on bare metal) with ENIs and instance store volumes
+		{instanceType: "c5.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m5.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m5.metal", expectedLimit: 31, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "t3.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "a1.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "c6g.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m6i.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m6i.metal", expectedLimit: 31, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m7g.large", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		// Newer generations have a dedicated limit per size instead of a raised shared limit
+		{instanceType: "m7i.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.2xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.12xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.24xlarge", expectedLimit: 64, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.48xlarge", expectedLimit: 128, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.metal-24xl", expectedLimit: 39, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i.metal-48xl", expectedLimit: 79, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c7i.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c7i.metal-24xl", expectedLimit: 39, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c7i.metal-48xl", expectedLimit: 79, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "r7i.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m8g.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c8g.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		// Flex families stop at 16xlarge
+		{instanceType: "c7i-flex.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c7i-flex.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i-flex.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m7i-flex.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c8i-flex.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c8i-flex.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m8i-flex.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m8i-flex.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "r8i-flex.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "r8i-flex.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		// Mac instances run on dedicated hosts and have their own limits, hyphenated families included
+		{instanceType: "mac1.metal", expectedLimit: 16, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac2.metal", expectedLimit: 10, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac2-m1ultra.metal", expectedLimit: 10, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac2-m2.metal", expectedLimit: 10, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac2-m2pro.metal", expectedLimit: 10, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac-m4.metal", expectedLimit: 31, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac-m4pro.metal", expectedLimit: 31, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "mac-m4max.metal", expectedLimit: 10, expectedAttachmentType: util.AttachmentShared},
+		// The shared limits of i4i, d3 and d3en exclude their instance store volumes from the default of 27
+		{instanceType: "i4i.large", expectedLimit: 26, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 1},
+		{instanceType: "i4i.8xlarge", expectedLimit: 25, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 2},
+		{instanceType: "i4i.12xlarge", expectedLimit: 24, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 3},
+		{instanceType: "i4i.16xlarge", expectedLimit: 23, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 4},
+		{instanceType: "i4i.24xlarge", expectedLimit: 21, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 6},
+		{instanceType: "i4i.32xlarge", expectedLimit: 19, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 8},
+		{instanceType: "d3.xlarge", expectedLimit: 24, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 3},
+		{instanceType: "d3.2xlarge", expectedLimit: 21, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 6},
+		{instanceType: "d3.4xlarge", expectedLimit: 15, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 12},
+		{instanceType: "d3.8xlarge", expectedLimit: 3, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 24},
+		{instanceType: "d3en.xlarge", expectedLimit: 25, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 2},
+		{instanceType: "d3en.2xlarge", expectedLimit: 23, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 4},
+		{instanceType: "d3en.4xlarge", expectedLimit: 19, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 8},
+		{instanceType: "d3en.6xlarge", expectedLimit: 15, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 12},
+		{instanceType: "d3en.8xlarge", expectedLimit: 11, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 16},
+		{instanceType: "d3en.12xlarge", expectedLimit: 3, expectedAttachmentType: util.AttachmentShared, instanceStoreVolumes: 24},
+		// Instance store volumes and GPUs do not reduce dedicated limits
+		{instanceType: "i7ie.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i7ie.18xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i7ie.24xlarge", expectedLimit: 64, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i7ie.48xlarge", expectedLimit: 128, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i7ie.metal-24xl", expectedLimit: 39, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i7ie.metal-48xl", expectedLimit: 79, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "x8g.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "x8g.24xlarge", expectedLimit: 64, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "x8g.48xlarge", expectedLimit: 128, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "x8g.metal-48xl", expectedLimit: 79, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i8g.large", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i8g.24xlarge", expectedLimit: 64, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "i8g.48xlarge", expectedLimit: 128, expectedAttachmentType: util.AttachmentDedicated},
+		// Reported as shared by the API, overridden as dedicated
+		{instanceType: "i8g.metal-48xl", expectedLimit: 79, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "g6e.xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "g6e.12xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "g6e.16xlarge", expectedLimit: 48, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "g6e.24xlarge", expectedLimit: 64, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "g6e.48xlarge", expectedLimit: 128, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "gr6.4xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "gr6.8xlarge", expectedLimit: 32, expectedAttachmentType: util.AttachmentDedicated},
+		// hpc6a and hpc7g have the default shared limit, the limit of hpc6id excludes its 4 instance store volumes
+		{instanceType: "hpc6a.48xlarge", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "hpc6id.32xlarge", expectedLimit: 23, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "hpc7g.4xlarge", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "hpc7g.16xlarge", expectedLimit: 27, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "hpc7a.12xlarge", expectedLimit: 27, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "hpc7a.96xlarge", expectedLimit: 27, expectedAttachmentType: util.AttachmentDedicated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			limit, attachmentType := GetVolumeLimits(tc.instanceType)
+			if limit != tc.expectedLimit || attachmentType != tc.expectedAttachmentType {
+				t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (%d, %q)", tc.instanceType, limit, attachmentType, tc.expectedLimit, tc.expectedAttachmentType)
+			}
+			if count := GetInstanceStoreVolumeCount(tc.instanceType); count != tc.instanceStoreVolumes {
+				t.Errorf("GetInstanceStoreVolumeCount(%q) = %d, expected %d", tc.instanceType, count, tc.instanceStoreVolumes)
+			}
+			if tc.expectedAttachmentType != util.AttachmentDedicated {
+				return
+			}
+			// Root volume reserved, ENIs are not deducted from dedicated limits
+			if vl := GetVolumeLimit(tc.instanceType, 1, 4); vl.Limit != tc.expectedLimit-1 {
+				t.Errorf("GetVolumeLimit(%q, 1, 4).Limit = %d, expected %d", tc.instanceType, vl.Limit, tc.expectedLimit-1)
+			}
+		})
+	}
+
+	// An EFA interface next to the primary ENI takes one attachment on shared instance types only
+	if vl := GetVolumeLimit("hpc7g.16xlarge", 1, 2); vl.Limit != 25 {
+		t.Errorf("GetVolumeLimit(hpc7g.16xlarge) with EFA = %d, expected 25", vl.Limit)
+	}
+}
+func TestIsNitroInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     bool
+	}{
+		{instanceType: "m5.large", expected: true},
+		{instanceType: "m7i.48xlarge", expected: true},
+		{instanceType: "c4.large", expected: false},
+		{instanceType: "t2.micro", expected: false},
+		{instanceType: "m5", expected: false},
+		{instanceType: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			if got := IsNitroInstanceType(tc.instanceType); got != tc.expected {
+				t.Errorf("IsNitroInstanceType(%q) = %v, expected %v", tc.instanceType, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNitroInstanceType(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     bool
+		expectErr    bool
+	}{
+		{instanceType: "m5.large", expected: true},
+		{instanceType: " M7I.48xlarge\n", expected: true},
+		{instanceType: "c4.large", expected: false},
+		{instanceType: "t2.micro", expected: false},
+		{instanceType: "m5", expectErr: true},
+		{instanceType: "m5.large.extra", expectErr: true},
+		{instanceType: "", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			nitro, err := NitroInstanceType(tc.instanceType)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("NitroInstanceType(%q) error = %v, expected error: %t", tc.instanceType, err, tc.expectErr)
+			}
+			if nitro != tc.expected {
+				t.Errorf("NitroInstanceType(%q) = %t, expected %t", tc.instanceType, nitro, tc.expected)
+			}
+		})
+	}
+}
+
+func TestKnownInstanceTypes(t *testing.T) {
+	knownTypes := KnownInstanceTypes()
+	if len(knownTypes) == 0 {
+		t.Fatal("KnownInstanceTypes() returned no instance types")
+	}
+
+	seen := make(map[string]struct{}, len(knownTypes))
+	for _, instanceType := range knownTypes {
+		if _, exists := seen[instanceType]; exists {
+			t.Errorf("KnownInstanceTypes() returned duplicate instance type %q", instanceType)
+		}
+		seen[instanceType] = struct{}{}
+	}
+
+	// Every table must be covered
+	for _, instanceType := range []string{"m5.metal", "c4.large", "i7i.metal-24xl"} {
+		if _, exists := seen[instanceType]; !exists {
+			t.Errorf("KnownInstanceTypes() is missing instance type %q", instanceType)
+		}
+	}
+	for instanceType := range ebsCardCounts {
+		if _, exists := seen[instanceType]; !exists {
+			t.Errorf("KnownInstanceTypes() is missing instance type %q", instanceType)
+		}
+	}
+}
+
+func TestDedicatedVolumeLimits(t *testing.T) {
+	dedicatedLimits := DedicatedVolumeLimits()
+	for instanceType, limit := range dedicatedLimits {
+		if expLimit, attachmentType := GetVolumeLimits(instanceType); attachmentType != util.AttachmentDedicated || limit != expLimit {
+			t.Errorf("DedicatedVolumeLimits()[%q] = %d, but GetVolumeLimits() = (%d, %q)", instanceType, limit, expLimit, attachmentType)
+		}
+	}
+	for _, instanceType := range []string{"c4.large", "i7i.metal-24xl"} {
+		if _, exists := dedicatedLimits[instanceType]; !exists {
+			t.Errorf("DedicatedVolumeLimits() is missing instance type %q", instanceType)
+		}
+	}
+	if _, exists := dedicatedLimits["i3.metal"]; exists {
+		t.Errorf("DedicatedVolumeLimits() contains shared instance type %q", "i3.metal")
+	}
+
+	// Mutating the returned maps must not affect the tables
+	dedicatedLimits["i7i.metal-24xl"] = 1
+	delete(dedicatedLimits, "c4.large")
+	if limit := DedicatedVolumeLimits()["i7i.metal-24xl"]; limit == 1 {
+		t.Error("DedicatedVolumeLimits() returned a map sharing state with the tables")
+	}
+	if _, exists := DedicatedVolumeLimits()["c4.large"]; !exists {
+		t.Error("DedicatedVolumeLimits() returned a map sharing state with the tables")
+	}
+
+	overrides := DedicatedInstanceTypeOverrides()
+	delete(overrides, "i7i.metal-24xl")
+	if _, attachmentType := GetVolumeLimits("i7i.metal-24xl"); attachmentType != util.AttachmentDedicated {
+		t.Error("DedicatedInstanceTypeOverrides() returned a map sharing state with the tables")
+	}
+
+	missingLimits := MissingInstanceTypeLimits()
+	if missingLimits["i3.metal"] != 23 {
+		t.Errorf("MissingInstanceTypeLimits()[%q] = %d, expected 23", "i3.metal", missingLimits["i3.metal"])
+	}
+	missingLimits["i3.metal"] = 1
+	if limit, _ := GetVolumeLimits("i3.metal"); limit != 23 {
+		t.Error("MissingInstanceTypeLimits() returned a map sharing state with the tables")
+	}
+}
+
+func TestGetVolumeLimitNetworkCards(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instanceType  string
+		attachedENIs  int
+		expectedLimit int
+	}{
+		// 28 - 1 root volume - 7 ENIs of the network cards beyond the primary one
+		{name: "trn1 with only the primary ENI", instanceType: "trn1.32xlarge", attachedENIs: 1, expectedLimit: 20},
+		{name: "trn1 with an ENI on every network card", instanceType: "trn1.32xlarge", attachedENIs: 8, expectedLimit: 20},
+		// ENIs beyond one per network card, such as additional EFA interfaces, still count
+		{name: "trn1 with more ENIs than network cards", instanceType: "trn1.32xlarge", attachedENIs: 10, expectedLimit: 18},
+		{name: "trn1n", instanceType: "trn1n.32xlarge", attachedENIs: 1, expectedLimit: 12},
+		{name: "c6in with 2 network cards", instanceType: "c6in.32xlarge", attachedENIs: 1, expectedLimit: 25},
+		{name: "single network card", instanceType: "m5.large", attachedENIs: 1, expectedLimit: 26},
+		// Dedicated limits are not affected by ENIs
+		{name: "dedicated", instanceType: "trn2.48xlarge", attachedENIs: 1, expectedLimit: 63},
+		// Nor by the EFA interfaces, GPUs and instance store volumes of accelerated instance types
+		{name: "p6-b200 with an EFA interface on every network card", instanceType: "p6-b200.48xlarge", attachedENIs: 9, expectedLimit: 63},
+		{name: "p6-b300 with an EFA interface on every network card", instanceType: "p6-b300.48xlarge", attachedENIs: 9, expectedLimit: 63},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vl := GetVolumeLimit(tc.instanceType, 1, tc.attachedENIs)
+			if vl.Limit != tc.expectedLimit {
+				t.Errorf("GetVolumeLimit(%q, 1, %d).Limit = %d, expected %d", tc.instanceType, tc.attachedENIs, vl.Limit, tc.expectedLimit)
+			}
+		})
+	}
+}
+
+func TestVolumeLimitReservedSlotsNetworkHeavyAccelerators(t *testing.T) {
+	testCases := []struct {
+		instanceType          string
+		expectedReservedSlots int
+		expectedLimit         int
+	}{
+		// 1 root volume + 15 ENIs of the network cards beyond the primary one, out of a shared limit of 28
+		{instanceType: "trn1n.32xlarge", expectedReservedSlots: 16, expectedLimit: 12},
+		// Dedicated limits are not reduced by the GPUs, instance store volumes and network cards
+		{instanceType: "p4de.24xlarge", expectedReservedSlots: 1, expectedLimit: 27},
+		{instanceType: "p5en.48xlarge", expectedReservedSlots: 1, expectedLimit: 63},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			vl := GetVolumeLimit(tc.instanceType, 1, 1)
+			if vl.ReservedSlots() != tc.expectedReservedSlots {
+				t.Errorf("GetVolumeLimit(%q, 1, 1).ReservedSlots() = %d, expected %d", tc.instanceType, vl.ReservedSlots(), tc.expectedReservedSlots)
+			}
+			if vl.Limit != tc.expectedLimit {
+				t.Errorf("GetVolumeLimit(%q, 1, 1).Limit = %d, expected %d", tc.instanceType, vl.Limit, tc.expectedLimit)
+			}
+			if vl.MaxAttachments-vl.ReservedSlots() != vl.Limit {
+				t.Errorf("GetVolumeLimit(%q, 1, 1) = %+v, expected the reserved slots to account for the whole difference to the limit", tc.instanceType, vl)
+			}
+		})
+	}
+}
+
+func TestInstanceStoreVolumesOnlyOnSharedInstanceTypes(t *testing.T) {
+	// Dedicated limits do not include instance store volumes, so there is nothing to give back
+	for instanceType, count := range instanceStoreVolumes {
+		if HasDedicatedEBSLimit(instanceType) {
+			t.Errorf("instance type %q has a dedicated limit but %d instance store volumes in the table", instanceType, count)
+		}
+		if count < 1 {
+			t.Errorf("instance type %q has %d instance store volumes in the table, expected at least 1", instanceType, count)
+		}
+	}
+	if count := GetInstanceStoreVolumeCount("m5.large"); count != 0 {
+		t.Errorf("GetInstanceStoreVolumeCount(\"m5.large\") = %d, expected 0", count)
+	}
+}
+
+func TestGetGPUCount(t *testing.T) {
+	testCases := map[string]int{
+		"g5.xlarge":     1,
+		"G4DN.12XLARGE": 4,
+		"g6.xlarge":     0,
+		"m5.large":      0,
+		"":              0,
+	}
+	for instanceType, expected := range testCases {
+		if count := GetGPUCount(instanceType); count != expected {
+			t.Errorf("GetGPUCount(%q) = %d, expected %d", instanceType, count, expected)
+		}
+	}
+}
+
+func TestInstanceTypeHasInstanceStore(t *testing.T) {
+	testCases := []struct {
+		instanceType  string
+		expectedCount int
+		expectedOK    bool
+	}{
+		{instanceType: "i3.metal", expectedCount: 8, expectedOK: true},
+		{instanceType: "G5.XLARGE", expectedCount: 1, expectedOK: true},
+		{instanceType: "m5.large"},
+		// Dedicated limits are not reduced by instance store volumes
+		{instanceType: "g6e.48xlarge"},
+		{instanceType: "m5"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			count, ok := InstanceTypeHasInstanceStore(tc.instanceType)
+			if count != tc.expectedCount || ok != tc.expectedOK {
+				t.Errorf("InstanceTypeHasInstanceStore(%q) = (%d, %t), expected (%d, %t)", tc.instanceType, count, ok, tc.expectedCount, tc.expectedOK)
+			}
+		})
+	}
+}
+
+func TestNonCanonicalInstanceTypes(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		canonical    string
+	}{
+		{instanceType: "M7I.24XLARGE\n", canonical: "m7i.24xlarge"},
+		{instanceType: " t2.Medium ", canonical: "t2.medium"},
+		{instanceType: "TRN1.32xlarge\t", canonical: "trn1.32xlarge"},
+		{instanceType: "I3.METAL", canonical: "i3.metal"},
+		{instanceType: "G4DN.xlarge\r\n", canonical: "g4dn.xlarge"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.canonical, func(t *testing.T) {
+			limit, attachmentType := GetVolumeLimits(tc.instanceType)
+			expectedLimit, expectedAttachmentType := GetVolumeLimits(tc.canonical)
+			if limit != expectedLimit || attachmentType != expectedAttachmentType {
+				t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (%d, %q) like %q", tc.instanceType, limit, attachmentType, expectedLimit, expectedAttachmentType, tc.canonical)
+			}
+			if vl, expected := GetVolumeLimit(tc.instanceType, 1, 1), GetVolumeLimit(tc.canonical, 1, 1); vl != expected {
+				t.Errorf("GetVolumeLimit(%q, 1, 1) = %+v, expected %+v like %q", tc.instanceType, vl, expected, tc.canonical)
+			}
+			if IsNitroInstanceType(tc.instanceType) != IsNitroInstanceType(tc.canonical) {
+				t.Errorf("IsNitroInstanceType(%q) differs from %q", tc.instanceType, tc.canonical)
+			}
+			if GetCardCount(tc.instanceType) != GetCardCount(tc.canonical) {
+				t.Errorf("GetCardCount(%q) differs from %q", tc.instanceType, tc.canonical)
+			}
+			if GetNetworkCardCount(tc.instanceType) != GetNetworkCardCount(tc.canonical) {
+				t.Errorf("GetNetworkCardCount(%q) differs from %q", tc.instanceType, tc.canonical)
+			}
+			if GetInstanceStoreVolumeCount(tc.instanceType) != GetInstanceStoreVolumeCount(tc.canonical) {
+				t.Errorf("GetInstanceStoreVolumeCount(%q) differs from %q", tc.instanceType, tc.canonical)
+			}
+		})
+	}
+}
+
+func TestGetVolumeLimitsTablePrecedence(t *testing.T) {
+	const instanceType = "x99.large"
+	t.Cleanup(func() {
+		delete(volumeLimits, instanceType)
+		delete(dedicatedInstances, instanceType)
+		delete(missingInstanceTypes, instanceType)
+		delete(nonNitroInstanceTypes, instanceType)
+	})
+
+	missingInstanceTypes[instanceType] = volumeLimit{40, util.AttachmentShared}
+	if limit, attachmentType := GetVolumeLimits(instanceType); limit != 40 || attachmentType != util.AttachmentShared {
+		t.Errorf("missingInstanceTypes only: GetVolumeLimits() = (%d, %q), expected (40, %q)", limit, attachmentType, util.AttachmentShared)
+	}
+
+	// The generated table wins over the hand-maintained missing instance types when its limit is lower
+	volumeLimits[instanceType] = volumeLimit{30, util.AttachmentShared}
+	if limit, attachmentType := GetVolumeLimits(instanceType); limit != 30 || attachmentType != util.AttachmentShared {
+		t.Errorf("volumeLimits and missingInstanceTypes: GetVolumeLimits() = (%d, %q), expected (30, %q)", limit, attachmentType, util.AttachmentShared)
+	}
+
+	// The dedicated override keeps the limit of the table and only changes the attachment type
+	dedicatedInstances[instanceType] = struct{}{}
+	if limit, attachmentType := GetVolumeLimits(instanceType); limit != 30 || attachmentType != util.AttachmentDedicated {
+		t.Errorf("volumeLimits and dedicatedInstances: GetVolumeLimits() = (%d, %q), expected (30, %q)", limit, attachmentType, util.AttachmentDedicated)
+	}
+
+	// Non-Nitro instance types always get the non-Nitro limit
+	nonNitroInstanceTypes[instanceType] = struct{}{}
+	if limit, attachmentType := GetVolumeLimits(instanceType); limit != 39 || attachmentType != util.AttachmentDedicated {
+		t.Errorf("nonNitroInstanceTypes and all other tables: GetVolumeLimits() = (%d, %q), expected (39, %q)", limit, attachmentType, util.AttachmentDedicated)
+	}
+}
+
+func TestGetVolumeLimitMetalWithInstanceStore(t *testing.T) {
+	limit, attachmentType := GetVolumeLimits("i3.metal")
+	if limit != 23 || attachmentType != util.AttachmentShared {
+		t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (23, %q)", "i3.metal", limit, attachmentType, util.AttachmentShared)
+	}
+
+	// Root volume reserved, 2 ENIs
+	vl := GetVolumeLimit("i3.metal", 1, 2)
+	if vl.Limit != 21 {
+		t.Errorf("GetVolumeLimit(%q, 1, 2).Limit = %d, expected 21", "i3.metal", vl.Limit)
+	}
+}
+
+func TestVolumeLimitForInstanceType(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instanceType  string
+		expectedLimit int
+		expectErr     bool
+	}{
+		{name: "dedicated", instanceType: "m7i.24xlarge", expectedLimit: 63},
+		{name: "shared default", instanceType: "m5.large", expectedLimit: 26},
+		{name: "non-nitro", instanceType: "t2.medium", expectedLimit: 38},
+		{name: "dedicated override", instanceType: "i7i.metal-24xl", expectedLimit: 38},
+		{name: "missing instance type", instanceType: "i3.metal", expectedLimit: 22},
+		{name: "malformed", instanceType: "m5", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limit, err := VolumeLimitForInstanceType(tc.instanceType)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("VolumeLimitForInstanceType(%q) error = %v, expectErr %v", tc.instanceType, err, tc.expectErr)
+			}
+			if limit != tc.expectedLimit {
+				t.Errorf("VolumeLimitForInstanceType(%q) = %d, expected %d", tc.instanceType, limit, tc.expectedLimit)
+			}
+		})
+	}
+}
+
+func TestNewVolumeLimitProvider(t *testing.T) {
+	t.Parallel()
+
+	limitSet := map[string]InstanceTypeLimit{
+		"M7I.LARGE":    {MaxAttachments: 16, AttachmentType: util.AttachmentShared},
+		"m5.large":     {MaxAttachments: 40, AttachmentType: util.AttachmentDedicated},
+		"example.huge": {MaxAttachments: 100, AttachmentType: util.AttachmentDedicated},
+	}
+	p := NewVolumeLimitProvider(limitSet)
+	// The provider keeps its own copy of the limit set
+	delete(limitSet, "m5.large")
+
+	testCases := []struct {
+		instanceType           string
+		reservedAttachments    int
+		attachedENIs           int
+		expectedLimit          int
+		expectedAttachmentType string
+	}{
+		// 16 - 1 root volume - 2 ENIs
+		{instanceType: "m7i.large", reservedAttachments: 1, attachedENIs: 3, expectedLimit: 13, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m5.large", reservedAttachments: 1, attachedENIs: 3, expectedLimit: 39, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "example.huge", reservedAttachments: 1, attachedENIs: 1, expectedLimit: 99, expectedAttachmentType: util.AttachmentDedicated},
+		// Instance types missing from the limit set get the default, not the limit of the tables
+		{instanceType: "m7i.48xlarge", reservedAttachments: 1, attachedENIs: 1, expectedLimit: NitroMaxAttachments - 1, expectedAttachmentType: util.AttachmentShared},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			t.Parallel()
+			vl := GetVolumeLimitFromProvider(p, tc.instanceType, tc.reservedAttachments, tc.attachedENIs)
+			if vl.Limit != tc.expectedLimit || vl.AttachmentType != tc.expectedAttachmentType {
+				t.Errorf("GetVolumeLimitFromProvider(%q) = %+v, expected limit %d and attachment type %q", tc.instanceType, vl, tc.expectedLimit, tc.expectedAttachmentType)
+			}
+		})
+	}
+
+	if known := p.KnownInstanceTypes(); !slices.Equal(known, []string{"example.huge", "m5.large", "m7i.large"}) {
+		t.Errorf("KnownInstanceTypes() = %v, expected [example.huge m5.large m7i.large]", known)
+	}
+	// The package level tables are untouched
+	if limit, attachmentType := GetVolumeLimits("m7i.large"); limit != 32 || attachmentType != util.AttachmentDedicated {
+		t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (32, %q)", "m7i.large", limit, attachmentType, util.AttachmentDedicated)
+	}
+}
+
+func TestWithSharedFamilies(t *testing.T) {
+	p := WithSharedFamilies(DefaultVolumeLimitProvider(), []string{"M7I", "c7i"})
+
+	testCases := []struct {
+		instanceType           string
+		expectedMaxAttachments int
+		expectedAttachmentType string
+	}{
+		{instanceType: "m7i.large", expectedMaxAttachments: 32, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "c7i.48xlarge", expectedMaxAttachments: 128, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m7i-flex.large", expectedMaxAttachments: 32, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "m5.large", expectedMaxAttachments: 27, expectedAttachmentType: util.AttachmentShared},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			maxAttachments, attachmentType := p.GetVolumeLimits(tc.instanceType)
+			if maxAttachments != tc.expectedMaxAttachments || attachmentType != tc.expectedAttachmentType {
+				t.Errorf("GetVolumeLimits(%q) = %d, %q, expected %d, %q", tc.instanceType, maxAttachments, attachmentType, tc.expectedMaxAttachments, tc.expectedAttachmentType)
+			}
+			if dedicated := p.HasDedicatedEBSLimit(tc.instanceType); dedicated != (tc.expectedAttachmentType == util.AttachmentDedicated) {
+				t.Errorf("HasDedicatedEBSLimit(%q) = %t, expected attachment type %q", tc.instanceType, dedicated, tc.expectedAttachmentType)
+			}
+		})
+	}
+
+	vl := GetVolumeLimitFromProvider(p, "m7i.large", 1, 3)
+	if vl.Limit != 29 {
+		t.Errorf("GetVolumeLimitFromProvider(m7i.large) limit = %d, expected 29 (2 ENIs deducted)", vl.Limit)
+	}
+}
+
+func TestLimitSource(t *testing.T) {
+	testCases := []struct {
+		instanceType   string
+		expectedSource string
+	}{
+		{instanceType: "t2.medium", expectedSource: "non-nitro"},
+		{instanceType: "M7I.Large", expectedSource: "volume-limits"},
+		{instanceType: "i3.metal", expectedSource: "missing-instance-types"},
+		{instanceType: "m5.large", expectedSource: "default"},
+		{instanceType: "m5", expectedSource: "unrecognized"},
+		{instanceType: "", expectedSource: "unrecognized"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			if source := LimitSource(tc.instanceType); source != tc.expectedSource {
+				t.Errorf("LimitSource(%q) = %q, expected %q", tc.instanceType, source, tc.expectedSource)
+			}
+		})
+	}
+}
+
+func TestWithMaxAttachments(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		nonNitroMaxAttachments int
+		nitroMaxAttachments    int
+		instanceType           string
+		expectedLimit          int
+	}{
+		{name: "non-Nitro", nonNitroMaxAttachments: 30, nitroMaxAttachments: 28, instanceType: "t2.medium", expectedLimit: 30},
+		{name: "Nitro without table limit", nonNitroMaxAttachments: 30, nitroMaxAttachments: 28, instanceType: "m5.large", expectedLimit: 28},
+		{name: "Nitro with table limit", nonNitroMaxAttachments: 30, nitroMaxAttachments: 28, instanceType: "m7i.large", expectedLimit: 32},
+		{name: "missing instance type", nonNitroMaxAttachments: 30, nitroMaxAttachments: 28, instanceType: "i3.metal", expectedLimit: 23},
+		{name: "non-Nitro kept", nitroMaxAttachments: 28, instanceType: "t2.medium", expectedLimit: NonNitroMaxAttachments},
+		{name: "Nitro kept", nonNitroMaxAttachments: 30, instanceType: "m5.large", expectedLimit: NitroMaxAttachments},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := WithMaxAttachments(DefaultVolumeLimitProvider(), tc.nonNitroMaxAttachments, tc.nitroMaxAttachments)
+			if limit, _ := p.GetVolumeLimits(tc.instanceType); limit != tc.expectedLimit {
+				t.Errorf("GetVolumeLimits(%q) = %d, expected %d", tc.instanceType, limit, tc.expectedLimit)
+			}
+			// Root volume reserved, only the primary ENI attached
+			if vl := GetVolumeLimitFromProvider(p, tc.instanceType, 1, 1); vl.Limit != tc.expectedLimit-1 {
+				t.Errorf("GetVolumeLimitFromProvider(%q).Limit = %d, expected %d", tc.instanceType, vl.Limit, tc.expectedLimit-1)
+			}
+		})
+	}
+}
+
+func TestWithMaxAttachmentsLimitSet(t *testing.T) {
+	limitSet := NewVolumeLimitProvider(map[string]InstanceTypeLimit{
+		"m5.large": {MaxAttachments: 40, AttachmentType: util.AttachmentShared},
+	})
+	p := WithMaxAttachments(limitSet, 30, 28)
+
+	// The limit of the limit set is kept, even though the static tables have none for m5.large
+	if limit, _ := p.GetVolumeLimits("m5.large"); limit != 40 {
+		t.Errorf("GetVolumeLimits(m5.large) = %d, expected 40", limit)
+	}
+	// Instance types missing from the limit set get the replaced default, even if the static tables have a limit
+	if limit, _ := p.GetVolumeLimits("m7i.large"); limit != 28 {
+		t.Errorf("GetVolumeLimits(m7i.large) = %d, expected 28", limit)
+	}
+}
+
+func TestProviderLimitSource(t *testing.T) {
+	limitSet := NewVolumeLimitProvider(map[string]InstanceTypeLimit{
+		"m5.large": {MaxAttachments: 40, AttachmentType: util.AttachmentShared},
+	})
+	nitroDetector := WithNitroDetector(DefaultVolumeLimitProvider(), func(instanceType string) bool {
+		return instanceType != "m7i.large"
+	})
+
+	testCases := []struct {
+		name           string
+		provider       VolumeLimitProvider
+		instanceType   string
+		expectedSource string
+	}{
+		{name: "table", provider: DefaultVolumeLimitProvider(), instanceType: "m7i.large", expectedSource: "volume-limits"},
+		{name: "table missing instance type", provider: DefaultVolumeLimitProvider(), instanceType: "i3.metal", expectedSource: "missing-instance-types"},
+		{name: "table wrapped", provider: WithSharedFamilies(DefaultVolumeLimitProvider(), []string{"m7i"}), instanceType: "m7i.large", expectedSource: "volume-limits"},
+		{name: "limit set", provider: limitSet, instanceType: "m5.large", expectedSource: "limit-set"},
+		{name: "limit set default", provider: limitSet, instanceType: "m7i.large", expectedSource: "default"},
+		{name: "limit set wrapped", provider: WithMaxAttachments(limitSet, 30, 28), instanceType: "m5.large", expectedSource: "limit-set"},
+		{name: "limit set malformed", provider: limitSet, instanceType: "m5", expectedSource: "unrecognized"},
+		{name: "Nitro detector agrees", provider: nitroDetector, instanceType: "i3.metal", expectedSource: "missing-instance-types"},
+		{name: "Nitro detector disagrees", provider: nitroDetector, instanceType: "m7i.large", expectedSource: "non-nitro"},
+		{name: "other provider", provider: fixedVolumeLimitProvider{known: []string{"m5.large"}}, instanceType: "M5.Large", expectedSource: "provider"},
+		{name: "other provider default", provider: fixedVolumeLimitProvider{known: []string{"m5.large"}}, instanceType: "m7i.large", expectedSource: "default"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if source := ProviderLimitSource(tc.provider, tc.instanceType); source != tc.expectedSource {
+				t.Errorf("ProviderLimitSource(%q) = %q, expected %q", tc.instanceType, source, tc.expectedSource)
+			}
+		})
+	}
+}
+
+// fixedVolumeLimitProvider is a VolumeLimitProvider outside of this package, with the same limit for every instance type.
+type fixedVolumeLimitProvider struct {
+	known []string
+}
+
+func (fixedVolumeLimitProvider) GetVolumeLimits(_ string) (int, string) {
+	return 50, util.AttachmentDedicated
+}
+
+func (fixedVolumeLimitProvider) HasDedicatedEBSLimit(_ string) bool {
+	return true
+}
+
+func (fixedVolumeLimitProvider) IsNitroInstanceType(_ string) bool {
+	return true
+}
+
+func (fixedVolumeLimitProvider) GetCardCount(_ string) int {
+	return 1
+}
+
+func (p fixedVolumeLimitProvider) KnownInstanceTypes() []string {
+	return p.known
+}
+
+func TestWithNitroDetector(t *testing.T) {
+	// The hypervisor reported for c5.large, m7i.large and t2.medium disagrees with the static table
+	p := WithNitroDetector(DefaultVolumeLimitProvider(), func(instanceType string) bool {
+		return instanceType != "c5.large" && instanceType != "m7i.large" || instanceType == "t2.medium"
+	})
+
+	testCases := []struct {
+		instanceType           string
+		expectedNitro          bool
+		expectedLimit          int
+		expectedAttachmentType string
+	}{
+		{instanceType: "m5.large", expectedNitro: true, expectedLimit: NitroMaxAttachments, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "m7i.large", expectedNitro: false, expectedLimit: NonNitroMaxAttachments, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "c5.large", expectedNitro: false, expectedLimit: NonNitroMaxAttachments, expectedAttachmentType: util.AttachmentDedicated},
+		{instanceType: "t2.medium", expectedNitro: true, expectedLimit: NitroMaxAttachments, expectedAttachmentType: util.AttachmentShared},
+		{instanceType: "i3.metal", expectedNitro: true, expectedLimit: 23, expectedAttachmentType: util.AttachmentShared},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			if nitro := p.IsNitroInstanceType(tc.instanceType); nitro != tc.expectedNitro {
+				t.Errorf("IsNitroInstanceType(%q) = %t, expected %t", tc.instanceType, nitro, tc.expectedNitro)
+			}
+			limit, attachmentType := p.GetVolumeLimits(tc.instanceType)
+			if limit != tc.expectedLimit || attachmentType != tc.expectedAttachmentType {
+				t.Errorf("GetVolumeLimits(%q) = %d, %s, expected %d, %s", tc.instanceType, limit, attachmentType, tc.expectedLimit, tc.expectedAttachmentType)
+			}
+			if dedicated := p.HasDedicatedEBSLimit(tc.instanceType); dedicated != (tc.expectedAttachmentType == util.AttachmentDedicated) {
+				t.Errorf("HasDedicatedEBSLimit(%q) = %t", tc.instanceType, dedicated)
+			}
+		})
+	}
+}
+
+func TestIsKnownInstanceFamily(t *testing.T) {
+	testCases := []struct {
+		instanceType string
+		expected     bool
+	}{
+		{instanceType: "m7i.large", expected: true},
+		{instanceType: "t2.medium", expected: true},
+		{instanceType: "i3.metal", expected: true},
+		// No instance type of these families is in the tables
+		{instanceType: "t3.large", expected: true},
+		{instanceType: "hpc7g.16xlarge", expected: true},
+		{instanceType: " M5A.Large\n", expected: true},
+		{instanceType: "zz9.large", expected: false},
+		{instanceType: "m5", expected: false},
+		{instanceType: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.instanceType, func(t *testing.T) {
+			if known := IsKnownInstanceFamily(tc.instanceType); known != tc.expected {
+				t.Errorf("IsKnownInstanceFamily(%q) = %t, expected %t", tc.instanceType, known, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWithUnknownFamilyLimit(t *testing.T) {
+	testCases := []struct {
+		name               string
+		maxAttachments     int
+		instanceType       string
+		expectedLimit      int
+		expectedAttachType string
+	}{
+		{name: "unknown family", maxAttachments: 16, instanceType: "zz9.large", expectedLimit: 16, expectedAttachType: util.AttachmentShared},
+		{name: "unknown family kept", instanceType: "zz9.large", expectedLimit: NitroMaxAttachments, expectedAttachType: util.AttachmentShared},
+		{name: "known family with table limit", maxAttachments: 16, instanceType: "m7i.large", expectedLimit: 32, expectedAttachType: util.AttachmentDedicated},
+		{name: "known family without table limit", maxAttachments: 16, instanceType: "m5.large", expectedLimit: NitroMaxAttachments, expectedAttachType: util.AttachmentShared},
+		{name: "malformed", maxAttachments: 16, instanceType: "zz9", expectedLimit: NitroMaxAttachments, expectedAttachType: util.AttachmentShared},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := WithUnknownFamilyLimit(DefaultVolumeLimitProvider(), tc.maxAttachments)
+			limit, attachType := p.GetVolumeLimits(tc.instanceType)
+			if limit != tc.expectedLimit || attachType != tc.expectedAttachType {
+				t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (%d, %q)", tc.instanceType, limit, attachType, tc.expectedLimit, tc.expectedAttachType)
+			}
+			if dedicated := p.HasDedicatedEBSLimit(tc.instanceType); dedicated != (tc.expectedAttachType == util.AttachmentDedicated) {
+				t.Errorf("HasDedicatedEBSLimit(%q) = %t, expected %t", tc.instanceType, dedicated, !dedicated)
+			}
+		})
+	}
+}
+
+func TestEstimateVolumeLimitFromVCPUs(t *testing.T) {
+	testCases := []struct {
+		vcpus         int
+		expectedLimit int
+	}{
+		// medium
+		{vcpus: 1, expectedLimit: 32},
+		// 12xlarge
+		{vcpus: 48, expectedLimit: 32},
+		// 16xlarge
+		{vcpus: 64, expectedLimit: 48},
+		// 24xlarge
+		{vcpus: 96, expectedLimit: 64},
+		// 32xlarge
+		{vcpus: 128, expectedLimit: 88},
+		// 48xlarge
+		{vcpus: 192, expectedLimit: 128},
+		{vcpus: 896, expectedLimit: 128},
+	}
+	for _, tc := range testCases {
+		limit, attachmentType, err := EstimateVolumeLimitFromVCPUs(tc.vcpus)
+		if err != nil {
+			t.Fatalf("EstimateVolumeLimitFromVCPUs(%d) returned error: %v", tc.vcpus, err)
+		}
+		if limit != tc.expectedLimit || attachmentType != util.AttachmentDedicated {
+			t.Errorf("EstimateVolumeLimitFromVCPUs(%d) = (%d, %q), expected (%d, %q)", tc.vcpus, limit, attachmentType, tc.expectedLimit, util.AttachmentDedicated)
+		}
+	}
+
+	// The estimate never decreases and stays within the limits of the dedicated families
+	previous := 0
+	for vcpus := 1; vcpus <= 1024; vcpus++ {
+		limit, _, err := EstimateVolumeLimitFromVCPUs(vcpus)
+		if err != nil {
+			t.Fatalf("EstimateVolumeLimitFromVCPUs(%d) returned error: %v", vcpus, err)
+		}
+		if limit < previous {
+			t.Fatalf("EstimateVolumeLimitFromVCPUs(%d) = %d, lower than %d for %d vCPUs", vcpus, limit, previous, vcpus-1)
+		}
+		if limit < NitroMaxAttachments || limit > maxVCPUVolumeLimit {
+			t.Fatalf("EstimateVolumeLimitFromVCPUs(%d) = %d, expected between %d and %d", vcpus, limit, NitroMaxAttachments, maxVCPUVolumeLimit)
+		}
+		previous = limit
+	}
+
+	for _, vcpus := range []int{0, -4} {
+		if _, _, err := EstimateVolumeLimitFromVCPUs(vcpus); err == nil {
+			t.Errorf("EstimateVolumeLimitFromVCPUs(%d) expected error, got nil", vcpus)
+		}
+	}
+}
+
+func TestVolumeLimitsForInstanceTypes(t *testing.T) {
+	instanceTypes := []string{"m7i.24xlarge", "m5", "m5.large", "t2.medium", "", "i3.metal", "m5", "m5.large", "x99.large"}
+
+	limitsByType, unknownTypes := VolumeLimitsForInstanceTypes(instanceTypes)
+
+	expectedLimits := map[string]int{
+		"m7i.24xlarge": 63,
+		"m5.large":     26,
+		"t2.medium":    38,
+		"i3.metal":     22,
+		// Well-formed instance types that are not in the tables get the default limit
+		"x99.large": 26,
+	}
+	if !maps.Equal(limitsByType, expectedLimits) {
+		t.Errorf("VolumeLimitsForInstanceTypes(%q) limits = %v, expected %v", instanceTypes, limitsByType, expectedLimits)
+	}
+	if expectedUnknown := []string{"m5", ""}; !slices.Equal(unknownTypes, expectedUnknown) {
+		t.Errorf("VolumeLimitsForInstanceTypes(%q) unknown types = %q, expected %q", instanceTypes, unknownTypes, expectedUnknown)
+	}
+	for instanceType, limit := range limitsByType {
+		if expected, _ := VolumeLimitForInstanceType(instanceType); limit != expected {
+			t.Errorf("limit of %q = %d, VolumeLimitForInstanceType returns %d", instanceType, limit, expected)
+		}
+	}
+}
+
+func FuzzVolumeLimitForInstanceType(f *testing.F) {
+	for _, seed := range []string{
+		"m5.large", "m7i.24xlarge", "t2.medium", "i3.metal", "i7i.metal-24xl", "u7i-12tb.224xlarge", "mac2-m2pro.metal",
+		"", ".", "m5", "m5.", ".large", "m5..large", "m5.large.extra", " m5.large", "M5.LARGE", "m5.large\x00",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, instanceType string) {
+		limit, err := VolumeLimitForInstanceType(instanceType)
+		if err != nil {
+			if limit != 0 {
+				t.Errorf("VolumeLimitForInstanceType(%q) = (%d, %v), expected a limit of 0 with an error", instanceType, limit, err)
+			}
+			return
+		}
+		if limit < 1 {
+			t.Errorf("VolumeLimitForInstanceType(%q) = %d, expected a limit of at least 1", instanceType, limit)
+		}
+
+		maxAttachments, attachmentType := GetVolumeLimits(instanceType)
+		if maxAttachments < 1 || (attachmentType != util.AttachmentShared && attachmentType != util.AttachmentDedicated) {
+			t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected a positive limit and a known attachment type", instanceType, maxAttachments, attachmentType)
+		}
+	})
+}
+
+func TestUsableVolumeLimit(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ebsLimit        int
+		attachmentLimit int
+		reservedSlots   int
+		expectedLimit   int
+	}{
+		{name: "EBS limit lower than attachments minus reserved", ebsLimit: 20, attachmentLimit: 28, reservedSlots: 3, expectedLimit: 20},
+		{name: "attachments minus reserved lower than EBS limit", ebsLimit: 27, attachmentLimit: 28, reservedSlots: 3, expectedLimit: 25},
+		{name: "equal", ebsLimit: 25, attachmentLimit: 28, reservedSlots: 3, expectedLimit: 25},
+		{name: "reserved slots use up the attachment limit", ebsLimit: 27, attachmentLimit: 28, reservedSlots: 30, expectedLimit: 1},
+		{name: "no EBS volumes", ebsLimit: 0, attachmentLimit: 28, reservedSlots: 3, expectedLimit: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if limit := UsableVolumeLimit(tc.ebsLimit, tc.attachmentLimit, tc.reservedSlots); limit != tc.expectedLimit {
+				t.Errorf("UsableVolumeLimit(%d, %d, %d) = %d, expected %d", tc.ebsLimit, tc.attachmentLimit, tc.reservedSlots, limit, tc.expectedLimit)
+			}
+		})
+	}
+}
+
+func TestGetVolumeLimitsOverlappingTables(t *testing.T) {
+	testCases := []struct {
+		name               string
+		missingLimit       volumeLimit
+		expectedLimit      int
+		expectedAttachment string
+		expectedSource     string
+	}{
+		{
+			name:               "lower limit in missingInstanceTypes wins",
+			missingLimit:       volumeLimit{8, util.AttachmentShared},
+			expectedLimit:      8,
+			expectedAttachment: util.AttachmentShared,
+			expectedSource:     "missing-instance-types",
+		},
+		{
+			name:               "lower limit in volumeLimits wins",
+			missingLimit:       volumeLimit{40, util.AttachmentDedicated},
+			expectedLimit:      32,
+			expectedAttachment: util.AttachmentDedicated,
+			expectedSource:     "volume-limits",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			missingInstanceTypes["m7i.large"] = tc.missingLimit
+			t.Cleanup(func() {
+				delete(missingInstanceTypes, "m7i.large")
+			})
+
+			limit, attachmentType := GetVolumeLimits("m7i.large")
+			if limit != tc.expectedLimit || attachmentType != tc.expectedAttachment {
+				t.Errorf("GetVolumeLimits(m7i.large) = (%d, %s), expected (%d, %s)", limit, attachmentType, tc.expectedLimit, tc.expectedAttachment)
+			}
+			if source := LimitSource("m7i.large"); source != tc.expectedSource {
+				t.Errorf("LimitSource(m7i.large) = %q, expected %q", source, tc.expectedSource)
+			}
+		})
+	}
+}
+
+func TestCanAttachAnotherVolume(t *testing.T) {
+	testCases := []struct {
+		name           string
+		instanceType   string
+		currentEBS     int
+		currentENIs    int
+		expectedOK     bool
+		expectedReason string
+	}{
+		{
+			name:           "shared slots free",
+			instanceType:   "m5.large",
+			currentEBS:     24,
+			currentENIs:    3,
+			expectedOK:     true,
+			expectedReason: "1 of 27 shared attachment slots are free",
+		},
+		{
+			name:           "shared limit reached",
+			instanceType:   "m5.large",
+			currentEBS:     25,
+			currentENIs:    3,
+			expectedReason: "shared attachment limit of 27 reached: 25 EBS volumes attached, 2 slots taken by ENIs and network cards",
+		},
+		{
+			name:           "shared limit reserved for ENIs",
+			instanceType:   "m5.large",
+			currentENIs:    28,
+			expectedReason: "shared attachment limit of 27 is reserved for ENIs: 27 slots taken by ENIs and network cards",
+		},
+		{
+			name:           "network cards reserve slots",
+			instanceType:   "trn1n.32xlarge",
+			currentEBS:     13,
+			currentENIs:    1,
+			expectedReason: "shared attachment limit of 28 reached: 13 EBS volumes attached, 15 slots taken by ENIs and network cards",
+		},
+		{
+			name:           "dedicated slots free regardless of ENIs",
+			instanceType:   "m7i.large",
+			currentEBS:     31,
+			currentENIs:    10,
+			expectedOK:     true,
+			expectedReason: "1 of 32 dedicated attachment slots are free",
+		},
+		{
+			name:           "dedicated limit reached",
+			instanceType:   "m7i.large",
+			currentEBS:     32,
+			currentENIs:    1,
+			expectedReason: "dedicated EBS limit of 32 reached: 32 EBS volumes attached",
+		},
+		{
+			name:           "no ENI attached",
+			instanceType:   "m5.large",
+			currentEBS:     1,
+			expectedReason: "invalid number of attached ENIs: 0, the primary ENI is always attached",
+		},
+		{
+			name:           "negative ENIs on dedicated instance type",
+			instanceType:   "m7i.large",
+			currentEBS:     1,
+			currentENIs:    -1,
+			expectedReason: "invalid number of attached ENIs: -1, the primary ENI is always attached",
+		},
+		{
+			name:           "negative EBS volumes",
+			instanceType:   "m5.large",
+			currentEBS:     -1,
+			currentENIs:    1,
+			expectedReason: "invalid number of attached EBS volumes: -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, reason := CanAttachAnotherVolume(tc.instanceType, tc.currentEBS, tc.currentENIs)
+			if ok != tc.expectedOK || reason != tc.expectedReason {
+				t.Errorf("CanAttachAnotherVolume(%q, %d, %d) = (%t, %q), expected (%t, %q)",
+					tc.instanceType, tc.currentEBS, tc.currentENIs, ok, reason, tc.expectedOK, tc.expectedReason)
+			}
+		})
+	}
+}
+
+func TestGetVolumeLimitClampsReservedAttachments(t *testing.T) {
+	testCases := []struct {
+		name                string
+		instanceType        string
+		reservedAttachments int
+		attachedENIs        int
+	}{
+		{name: "reserved attachments exceed shared limit", instanceType: "m5.large", reservedAttachments: 40, attachedENIs: 1},
+		{name: "reserved attachments and ENIs exceed shared limit", instanceType: "m5.large", reservedAttachments: 20, attachedENIs: 10},
+		{name: "reserved attachments equal dedicated limit", instanceType: "m7i.24xlarge", reservedAttachments: 64, attachedENIs: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vl := GetVolumeLimit(tc.instanceType, tc.reservedAttachments, tc.attachedENIs)
+			if vl.Limit != 1 {
+				t.Errorf("GetVolumeLimit(%q, %d, %d).Limit = %d, expected 1", tc.instanceType, tc.reservedAttachments, tc.attachedENIs, vl.Limit)
+			}
+			// The breakdown still reports the inconsistent inputs so they can be diagnosed
+			if vl.ReservedAttachments != tc.reservedAttachments {
+				t.Errorf("GetVolumeLimit(%q, %d, %d).ReservedAttachments = %d, expected %d", tc.instanceType, tc.reservedAttachments, tc.attachedENIs, vl.ReservedAttachments, tc.reservedAttachments)
+			}
+		})
+	}
+}
+
+// TestVolumeLimitsMatchDescribeInstanceTypesFixtures checks the limits tables against
+// DescribeInstanceTypes responses recorded in testdata, trimmed to the fields the tables are
+// generated from. When adding an instance type, record it with:
+//
+//	aws ec2 describe-instance-types --instance-types <type> \
+//	  --query 'InstanceTypes[].{InstanceType:InstanceType,Hypervisor:Hypervisor,BareMetal:BareMetal,EbsInfo:{MaximumEbsAttachments:EbsInfo.MaximumEbsAttachments,AttachmentLimitType:EbsInfo.AttachmentLimitType,MaximumEbsCards:EbsInfo.MaximumEbsCards}}'
+func TestVolumeLimitsMatchDescribeInstanceTypesFixtures(t *testing.T) {
+	data, err := os.ReadFile("testdata/describe_instance_types.json")
+	if err != nil {
+		t.Fatalf("failed to read fixtures: %v", err)
+	}
+	var fixtures struct {
+		InstanceTypes []types.InstanceTypeInfo
+	}
+	if err = json.Unmarshal(data, &fixtures); err != nil {
+		t.Fatalf("failed to parse fixtures: %v", err)
+	}
+
+	covered := map[string]bool{}
+	for _, info := range fixtures.InstanceTypes {
+		instanceType := string(info.InstanceType)
+		t.Run(instanceType, func(t *testing.T) {
+			isNitro := info.Hypervisor != types.InstanceTypeHypervisorXen
+			if IsNitroInstanceType(instanceType) != isNitro {
+				t.Errorf("IsNitroInstanceType(%q) = %t, expected %t", instanceType, !isNitro, isNitro)
+			}
+
+			limit, attachmentType := GetVolumeLimits(instanceType)
+			if !isNitro {
+				covered["xen"] = true
+				// Non-Nitro limits are not reported by the API, the driver always uses 39
+				if limit != 39 || attachmentType != util.AttachmentDedicated {
+					t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (39, %q)", instanceType, limit, attachmentType, util.AttachmentDedicated)
+				}
+				return
+			}
+
+			if info.EbsInfo == nil || info.EbsInfo.MaximumEbsAttachments == nil {
+				t.Fatalf("fixture for %q has no EBS attachment limit", instanceType)
+			}
+			expLimit := int(*info.EbsInfo.MaximumEbsAttachments)
+			expAttachmentType := string(info.EbsInfo.AttachmentLimitType)
+			// The API is known to report the wrong attachment type for these
+			if _, isOverride := dedicatedInstances[instanceType]; isOverride {
+				expAttachmentType = util.AttachmentDedicated
+			}
+			if limit != expLimit || attachmentType != expAttachmentType {
+				t.Errorf("GetVolumeLimits(%q) = (%d, %q), expected (%d, %q)", instanceType, limit, attachmentType, expLimit, expAttachmentType)
+			}
+
+			expCards := 1
+			if info.EbsInfo.MaximumEbsCards != nil {
+				expCards = int(*info.EbsInfo.MaximumEbsCards)
+			}
+			if cards := GetCardCount(instanceType); cards != expCards {
+				t.Errorf("GetCardCount(%q) = %d, expected %d", instanceType, cards, expCards)
+			}
+
+			covered[attachmentType] = true
+			if info.BareMetal != nil && *info.BareMetal {
+				covered["metal"] = true
+			}
+			if it, err := parseInstanceType(instanceType); err == nil && isAcceleratedFamily(it.family) {
+				covered["accelerator"] = true
+			}
+		})
+	}
+
+	for _, category := range []string{util.AttachmentDedicated, util.AttachmentShared, "metal", "accelerator", "xen"} {
+		if !covered[category] {
+			t.Errorf("fixtures do not cover any %s instance type", category)
+		}
+	}
+}
+
+func isAcceleratedFamily(family string) bool {
+	for _, prefix := range []string{"g", "p", "inf", "trn", "dl"} {
+		if generation, found := strings.CutPrefix(family, prefix); found && generation != "" && generation[0] >= '0' && generation[0] <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// TestLimitsTablesInvariants guards the consistency of the limits tables, so that an entry added to one
+// table that contradicts the others fails here instead of producing a wrong limit on some node.
+func TestLimitsTablesInvariants(t *testing.T) {
+	t.Run("instance types are canonical", func(t *testing.T) {
+		tables := map[string][]string{
+			"volumeLimits":            slices.Collect(maps.Keys(volumeLimits)),
+			"missingInstanceTypes":    slices.Collect(maps.Keys(missingInstanceTypes)),
+			"dedicatedInstances":      slices.Collect(maps.Keys(dedicatedInstances)),
+			"nonNitroInstanceTypes":   slices.Collect(maps.Keys(nonNitroInstanceTypes)),
+			"instanceStoreVolumes":    slices.Collect(maps.Keys(instanceStoreVolumes)),
+			"networkCardsPerInstance": slices.Collect(maps.Keys(networkCardsPerInstance)),
+			"ebsCardCounts":           slices.Collect(maps.Keys(ebsCardCounts)),
+		}
+		for table, instanceTypes := range tables {
+			for _, instanceType := range instanceTypes {
+				if _, err := parseInstanceType(instanceType); err != nil || NormalizeInstanceType(instanceType) != instanceType {
+					t.Errorf("%s: %q is not a canonical instance type", table, instanceType)
+				}
+			}
+		}
+	})
+
+	t.Run("missing instance types are not in the generated table", func(t *testing.T) {
+		for instanceType, missingLimit := range missingInstanceTypes {
+			if limit, exists := volumeLimits[instanceType]; exists {
+				t.Errorf("%q is in missingInstanceTypes with %+v and in volumeLimits with %+v", instanceType, missingLimit, limit)
+			}
+		}
+	})
+
+	t.Run("dedicated overrides are in the generated table", func(t *testing.T) {
+		for instanceType := range dedicatedInstances {
+			if _, exists := volumeLimits[instanceType]; !exists {
+				t.Errorf("%q is in dedicatedInstances but not in volumeLimits, the override has no effect", instanceType)
+			}
+		}
+	})
+
+	t.Run("instance store volumes fit in the shared limit", func(t *testing.T) {
+		for instanceType, count := range instanceStoreVolumes {
+			limit, attachmentType := GetVolumeLimits(instanceType)
+			switch {
+			case !hasTableLimit(instanceType):
+				t.Errorf("%q is in instanceStoreVolumes but has no limit in the tables", instanceType)
+			case attachmentType != util.AttachmentShared:
+				t.Errorf("%q is in instanceStoreVolumes but has a %s limit", instanceType, attachmentType)
+			case count < 1:
+				t.Errorf("%q has %d instance store volumes", instanceType, count)
+			case !IsBareMetal(instanceType) && limit+count > NitroMaxAttachments:
+				// The limit excludes the instance store volumes, and GPUs on accelerated instance types
+				t.Errorf("%q has a limit of %d and %d instance store volumes, more than the %d attachments of the instance type", instanceType, limit, count, NitroMaxAttachments)
+			}
+			// The root volume and the primary ENI must leave room for a volume
+			if vl := GetVolumeLimit(instanceType, 1, 1); vl.MaxAttachments-vl.ReservedSlots() < 1 {
+				t.Errorf("%q reserves %d of its %d attachments with only the root volume attached", instanceType, vl.ReservedSlots(), vl.MaxAttachments)
+			}
+		}
+	})
+
+	t.Run("network cards fit in the shared limit", func(t *testing.T) {
+		for instanceType := range networkCardsPerInstance {
+			if HasDedicatedEBSLimit(instanceType) {
+				continue
+			}
+			if vl := GetVolumeLimit(instanceType, 1, 1); vl.MaxAttachments-vl.ReservedSlots() < 1 {
+				t.Errorf("%q reserves %d of its %d attachments with only the root volume attached", instanceType, vl.ReservedSlots(), vl.MaxAttachments)
+			}
+		}
+	})
+
+	t.Run("flex families stop at 16xlarge", func(t *testing.T) {
+		// Larger sizes only exist in the non-flex family
+		sizes := []string{"large", "xlarge", "2xlarge", "4xlarge", "8xlarge", "12xlarge", "16xlarge"}
+		for _, instanceType := range KnownInstanceTypes() {
+			parsed, err := parseInstanceType(instanceType)
+			if err == nil && strings.HasSuffix(parsed.family, "-flex") && !slices.Contains(sizes, parsed.size) {
+				t.Errorf("unexpected flex instance type %q in the limits tables", instanceType)
+			}
+		}
+	})
+
+	t.Run("families do not mix attachment types", func(t *testing.T) {
+		// Bare metal sizes are excluded, some families have shared limits only on bare metal
+		familyTypes := make(map[string]map[string][]string)
+		for _, instanceType := range KnownInstanceTypes() {
+			parsed, err := parseInstanceType(instanceType)
+			if err != nil || parsed.isMetal || !isNitro(instanceType) {
+				continue
+			}
+			_, attachmentType := GetVolumeLimits(instanceType)
+			if familyTypes[parsed.family] == nil {
+				familyTypes[parsed.family] = make(map[string][]string)
+			}
+			familyTypes[parsed.family][attachmentType] = append(familyTypes[parsed.family][attachmentType], instanceType)
+		}
+		for family, attachmentTypes := range familyTypes {
+			if len(attachmentTypes) > 1 {
+				t.Errorf("family %s has dedicated limits for %v and shared limits for %v", family, attachmentTypes[util.AttachmentDedicated], attachmentTypes[util.AttachmentShared])
+			}
+		}
+	})
+}
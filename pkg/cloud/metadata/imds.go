@@ -37,10 +37,17 @@ const (
 
 	// BlockDevicesEndpoint is the IMDS endpoint to query the number of attached block devices.
 	BlockDevicesEndpoint string = "block-device-mapping"
+
+	// InstanceTypeEndpoint is the IMDS endpoint to query the instance type.
+	InstanceTypeEndpoint string = "instance-type"
 )
 
 type IMDSClient func() (IMDS, error)
 
+// DefaultIMDSClient creates an IMDS client from the default AWS configuration.
+// The client caches its IMDSv2 token for the TTL returned with it and fetches a new token when IMDS rejects
+// the cached one with 401 Unauthorized, so the Metadata keeps the client for UpdateMetadata instead of creating
+// a new one for each refresh.
 var DefaultIMDSClient = func() (IMDS, error) {
 	cfg, err := config.LoadDefaultConfig(context.Background())
 	if err != nil {
@@ -137,3 +144,18 @@ func getAttachedENIs(svc IMDS) (int, error) {
 	attachedENIs := util.CountMACAddresses(string(enis))
 	return attachedENIs, nil
 }
+
+func getInstanceType(svc IMDS) (string, error) {
+	instanceTypeOutput, err := svc.GetMetadata(context.Background(), &imds.GetMetadataInput{Path: InstanceTypeEndpoint})
+	if err != nil {
+		return "", fmt.Errorf("could not get metadata for instance type: %w", err)
+	}
+	instanceType, err := io.ReadAll(instanceTypeOutput.Content)
+	if err != nil {
+		return "", fmt.Errorf("could not read instance type metadata content: %w", err)
+	}
+	if len(instanceType) == 0 {
+		return "", errors.New("could not get valid EC2 instance type")
+	}
+	return strings.TrimSpace(string(instanceType)), nil
+}
@@ -19,6 +19,7 @@ package metadata
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
@@ -37,6 +38,9 @@ type Metadata struct {
 	OutpostArn             arn.ARN
 	IMDSClient             IMDS
 	K8sAPIClient           kubernetes.Interface
+
+	// mux guards the fields refreshed by UpdateMetadata, which is called while the getters are in use.
+	mux sync.RWMutex
 }
 
 type MetadataServiceConfig struct {
@@ -114,19 +118,43 @@ func (m *Metadata) UpdateMetadata() error {
 		if err != nil {
 			return fmt.Errorf("failed to update ENI count via IMDS metadata source: %w", err)
 		}
+		m.mux.Lock()
 		m.NumAttachedENIs = attachedENIs
+		m.mux.Unlock()
+		// The instance type changes if the instance is stopped, resized and started again
+		instanceType, err := getInstanceType(m.IMDSClient)
+		if err != nil {
+			return fmt.Errorf("failed to update instance type via IMDS metadata source: %w", err)
+		}
+		m.setInstanceType(instanceType)
 	case m.K8sAPIClient != nil:
 		updatedMetadata, err := KubernetesAPIInstanceInfo(m.K8sAPIClient, true /* metadataLabeler */)
 		if updatedMetadata == nil || err != nil {
 			return fmt.Errorf("failed to update ENI and Block Device count via metadataLabeler source: %w", err)
 		}
+		m.mux.Lock()
 		m.NumAttachedENIs = updatedMetadata.NumAttachedENIs
 		m.NumBlockDeviceMappings = updatedMetadata.NumBlockDeviceMappings
+		m.mux.Unlock()
+		// The instance type label may be added to the Node after the driver started
+		if updatedMetadata.InstanceType != "" {
+			m.setInstanceType(updatedMetadata.InstanceType)
+		}
 	}
 
 	return nil
 }
 
+// setInstanceType replaces the instance type with instanceType, logging when it changed.
+func (m *Metadata) setInstanceType(instanceType string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if instanceType != m.InstanceType {
+		klog.InfoS("Instance type changed", "oldInstanceType", m.InstanceType, "newInstanceType", instanceType)
+		m.InstanceType = instanceType
+	}
+}
+
 func retrieveIMDSMetadata(imdsClient IMDSClient) (*Metadata, error) {
 	svc, err := imdsClient()
 	if err != nil {
@@ -165,6 +193,8 @@ func (m *Metadata) GetInstanceID() string {
 
 // GetInstanceType returns the instance type.
 func (m *Metadata) GetInstanceType() string {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
 	return m.InstanceType
 }
 
@@ -180,11 +210,15 @@ func (m *Metadata) GetAvailabilityZone() string {
 
 // GetNumAttachedENIs returns the number of attached ENIs.
 func (m *Metadata) GetNumAttachedENIs() int {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
 	return m.NumAttachedENIs
 }
 
 // GetNumBlockDeviceMappings returns the number of block device mappings.
 func (m *Metadata) GetNumBlockDeviceMappings() int {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
 	return m.NumBlockDeviceMappings
 }
 
@@ -17,11 +17,18 @@ limitations under the License.
 package metadata
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
@@ -77,6 +84,57 @@ func TestNewMetadataService(t *testing.T) {
 				NumBlockDeviceMappings: 2,
 			},
 		},
+		{
+			name:            "TestNewMetadataService: Default MetadataSources, IMDS preferred over instance type label",
+			metadataSources: DefaultMetadataSources,
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Labels: map[string]string{
+						corev1.LabelInstanceTypeStable: "m5.large",
+						corev1.LabelTopologyRegion:     "us-west-2",
+						corev1.LabelTopologyZone:       "us-west-2a",
+					},
+				},
+				Spec: corev1.NodeSpec{
+					ProviderID: "aws:///us-west-2a/i-1234567890abcdef0",
+				},
+			},
+			expectedMetadata: &Metadata{
+				InstanceID:             "i-1234567890abcdef0",
+				InstanceType:           "c5.xlarge",
+				Region:                 "us-west-2",
+				AvailabilityZone:       "us-west-2a",
+				NumAttachedENIs:        1,
+				NumBlockDeviceMappings: 2,
+			},
+		},
+		{
+			name:            "TestNewMetadataService: Default MetadataSources, IMDS error, instance type read from label",
+			metadataSources: DefaultMetadataSources,
+			IMDSError:       errors.New("IMDS error"),
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-node",
+					Labels: map[string]string{
+						corev1.LabelInstanceTypeStable: "m5.large",
+						corev1.LabelTopologyRegion:     "us-west-2",
+						corev1.LabelTopologyZone:       "us-west-2a",
+					},
+				},
+				Spec: corev1.NodeSpec{
+					ProviderID: "aws:///us-west-2a/i-1234567890abcdef0",
+				},
+			},
+			expectedMetadata: &Metadata{
+				InstanceID:             "i-1234567890abcdef0",
+				InstanceType:           "m5.large",
+				Region:                 "us-west-2",
+				AvailabilityZone:       "us-west-2a",
+				NumAttachedENIs:        1,
+				NumBlockDeviceMappings: 0,
+			},
+		},
 		{
 			name:            "TestNewMetadataService: Default MetadataSources, AWS_EC2_METADATA_DISABLED=true, K8s API available",
 			metadataSources: DefaultMetadataSources,
@@ -833,6 +891,126 @@ func TestMetadataLabelerInstanceInfo(t *testing.T) {
 	}
 }
 
+func TestUpdateMetadataIMDS(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		mockIMDS             func(m *MockIMDS)
+		expectedInstanceType string
+		expectedENIs         int
+		expectErr            bool
+	}{
+		{
+			name: "instance type changed after resize",
+			mockIMDS: func(m *MockIMDS) {
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: EnisEndpoint}).Return(&imds.GetMetadataOutput{
+					Content: io.NopCloser(strings.NewReader("01:23:45:67:89:ab\n01:23:45:67:89:cd")),
+				}, nil)
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: InstanceTypeEndpoint}).Return(&imds.GetMetadataOutput{
+					Content: io.NopCloser(strings.NewReader("m5.2xlarge")),
+				}, nil)
+			},
+			expectedInstanceType: "m5.2xlarge",
+			expectedENIs:         2,
+		},
+		{
+			name: "instance type unchanged",
+			mockIMDS: func(m *MockIMDS) {
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: EnisEndpoint}).Return(&imds.GetMetadataOutput{
+					Content: io.NopCloser(strings.NewReader("01:23:45:67:89:ab")),
+				}, nil)
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: InstanceTypeEndpoint}).Return(&imds.GetMetadataOutput{
+					Content: io.NopCloser(strings.NewReader("m5.large")),
+				}, nil)
+			},
+			expectedInstanceType: "m5.large",
+			expectedENIs:         1,
+		},
+		{
+			name: "IMDS unreachable keeps last known values",
+			mockIMDS: func(m *MockIMDS) {
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: EnisEndpoint}).Return(nil, errors.New("connection refused"))
+			},
+			expectedInstanceType: "m5.large",
+			expectedENIs:         1,
+			expectErr:            true,
+		},
+		{
+			name: "instance type unavailable keeps last known instance type",
+			mockIMDS: func(m *MockIMDS) {
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: EnisEndpoint}).Return(&imds.GetMetadataOutput{
+					Content: io.NopCloser(strings.NewReader("01:23:45:67:89:ab")),
+				}, nil)
+				m.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: InstanceTypeEndpoint}).Return(nil, errors.New("connection refused"))
+			},
+			expectedInstanceType: "m5.large",
+			expectedENIs:         1,
+			expectErr:            true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockIMDS := NewMockIMDS(ctrl)
+			tc.mockIMDS(mockIMDS)
+
+			m := &Metadata{
+				InstanceType:    "m5.large",
+				NumAttachedENIs: 1,
+				IMDSClient:      mockIMDS,
+			}
+			err := m.UpdateMetadata()
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedInstanceType, m.GetInstanceType())
+			assert.Equal(t, tc.expectedENIs, m.GetNumAttachedENIs())
+		})
+	}
+}
+
+func TestUpdateMetadataConcurrentReads(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockIMDS := NewMockIMDS(ctrl)
+	instanceTypes := []string{"m5.large", "m5.2xlarge"}
+	var calls atomic.Int32
+	mockIMDS.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: EnisEndpoint}).DoAndReturn(
+		func(_ context.Context, _ *imds.GetMetadataInput, _ ...func(*imds.Options)) (*imds.GetMetadataOutput, error) {
+			return &imds.GetMetadataOutput{Content: io.NopCloser(strings.NewReader("01:23:45:67:89:ab"))}, nil
+		}).AnyTimes()
+	// The instance type alternates, so that every refresh rewrites it
+	mockIMDS.EXPECT().GetMetadata(testutil.AnyContext(), &imds.GetMetadataInput{Path: InstanceTypeEndpoint}).DoAndReturn(
+		func(_ context.Context, _ *imds.GetMetadataInput, _ ...func(*imds.Options)) (*imds.GetMetadataOutput, error) {
+			instanceType := instanceTypes[calls.Add(1)%2]
+			return &imds.GetMetadataOutput{Content: io.NopCloser(strings.NewReader(instanceType))}, nil
+		}).AnyTimes()
+
+	m := &Metadata{
+		InstanceType:    "m5.large",
+		NumAttachedENIs: 1,
+		IMDSClient:      mockIMDS,
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for range 100 {
+			assert.NoError(t, m.UpdateMetadata())
+		}
+	})
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				assert.Contains(t, instanceTypes, m.GetInstanceType())
+				assert.Equal(t, 1, m.GetNumAttachedENIs())
+				m.GetNumBlockDeviceMappings()
+			}
+		})
+	}
+	wg.Wait()
+}
+
 func TestGetInstanceID(t *testing.T) {
 	metadata := &Metadata{
 		InstanceID: "i-1234567890abcdef0",
@@ -894,3 +1072,82 @@ type errReader struct{}
 func (e errReader) Read(p []byte) (n int, err error) {
 	return 0, errors.New("failed to read")
 }
+
+// fakeIMDS serves the instance type and ENIs of an instance, accepting only the last IMDSv2 token it issued.
+type fakeIMDS struct {
+	mu       sync.Mutex
+	tokenTTL string
+	tokens   int
+	token    string
+}
+
+func (f *fakeIMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+		f.tokens++
+		f.token = fmt.Sprintf("token-%d", f.tokens)
+		w.Header().Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", f.tokenTTL)
+		_, _ = w.Write([]byte(f.token))
+		return
+	}
+	if r.Header.Get("X-Aws-Ec2-Metadata-Token") != f.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.URL.Path {
+	case "/latest/meta-data/" + InstanceTypeEndpoint:
+		_, _ = w.Write([]byte("m5.large"))
+	case "/latest/meta-data/" + EnisEndpoint:
+		_, _ = w.Write([]byte("0e:00:00:00:00:01/"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// revoke invalidates the issued token, as IMDS does when the token expired earlier than the client expects.
+func (f *fakeIMDS) revoke() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = ""
+}
+
+func (f *fakeIMDS) tokenCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokens
+}
+
+func TestUpdateMetadataIMDSTokenReuse(t *testing.T) {
+	fake := &fakeIMDS{tokenTTL: "300"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	m := &Metadata{IMDSClient: imds.New(imds.Options{Endpoint: server.URL})}
+	for range 3 {
+		require.NoError(t, m.UpdateMetadata())
+	}
+	assert.Equal(t, 1, fake.tokenCount(), "token fetched more than once within its TTL")
+	assert.Equal(t, "m5.large", m.GetInstanceType())
+	assert.Equal(t, 1, m.GetNumAttachedENIs())
+
+	// A rejected token is fetched again and the request retried
+	fake.revoke()
+	require.NoError(t, m.UpdateMetadata())
+	assert.Equal(t, 2, fake.tokenCount(), "token not fetched again after 401 Unauthorized")
+}
+
+func TestUpdateMetadataIMDSTokenExpiry(t *testing.T) {
+	fake := &fakeIMDS{tokenTTL: "1"}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	m := &Metadata{IMDSClient: imds.New(imds.Options{Endpoint: server.URL})}
+	require.NoError(t, m.UpdateMetadata())
+	assert.Equal(t, 1, fake.tokenCount())
+
+	time.Sleep(1100 * time.Millisecond)
+	require.NoError(t, m.UpdateMetadata())
+	assert.Equal(t, 2, fake.tokenCount(), "token not fetched again after its TTL")
+}
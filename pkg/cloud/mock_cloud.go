@@ -199,6 +199,36 @@ func (mr *MockCloudMockRecorder) GetDiskByName(ctx, name, capacityBytes interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDiskByName", reflect.TypeOf((*MockCloud)(nil).GetDiskByName), ctx, name, capacityBytes)
 }
 
+// GetInstanceTypeAcceleratorCount mocks base method.
+func (m *MockCloud) GetInstanceTypeAcceleratorCount(ctx context.Context, instanceType string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceTypeAcceleratorCount", ctx, instanceType)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceTypeAcceleratorCount indicates an expected call of GetInstanceTypeAcceleratorCount.
+func (mr *MockCloudMockRecorder) GetInstanceTypeAcceleratorCount(ctx, instanceType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceTypeAcceleratorCount", reflect.TypeOf((*MockCloud)(nil).GetInstanceTypeAcceleratorCount), ctx, instanceType)
+}
+
+// GetInstanceTypeMaxIOPS mocks base method.
+func (m *MockCloud) GetInstanceTypeMaxIOPS(ctx context.Context, instanceType string) (int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstanceTypeMaxIOPS", ctx, instanceType)
+	ret0, _ := ret[0].(int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstanceTypeMaxIOPS indicates an expected call of GetInstanceTypeMaxIOPS.
+func (mr *MockCloudMockRecorder) GetInstanceTypeMaxIOPS(ctx, instanceType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstanceTypeMaxIOPS", reflect.TypeOf((*MockCloud)(nil).GetInstanceTypeMaxIOPS), ctx, instanceType)
+}
+
 // GetInstancesPatching mocks base method.
 func (m *MockCloud) GetInstancesPatching(ctx context.Context, nodeIDs []string) ([]*types.Instance, error) {
 	m.ctrl.T.Helper()
@@ -259,6 +289,36 @@ func (mr *MockCloudMockRecorder) GetVolumeIDByNodeAndDevice(ctx, nodeID, deviceN
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeIDByNodeAndDevice", reflect.TypeOf((*MockCloud)(nil).GetVolumeIDByNodeAndDevice), ctx, nodeID, deviceName)
 }
 
+// GetVolumeModificationProgress mocks base method.
+func (m *MockCloud) GetVolumeModificationProgress(ctx context.Context, volumeID string) (string, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeModificationProgress", ctx, volumeID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetVolumeModificationProgress indicates an expected call of GetVolumeModificationProgress.
+func (mr *MockCloudMockRecorder) GetVolumeModificationProgress(ctx, volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeModificationProgress", reflect.TypeOf((*MockCloud)(nil).GetVolumeModificationProgress), ctx, volumeID)
+}
+
+// IsNitroInstanceType mocks base method.
+func (m *MockCloud) IsNitroInstanceType(ctx context.Context, instanceType string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsNitroInstanceType", ctx, instanceType)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsNitroInstanceType indicates an expected call of IsNitroInstanceType.
+func (mr *MockCloudMockRecorder) IsNitroInstanceType(ctx, instanceType interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsNitroInstanceType", reflect.TypeOf((*MockCloud)(nil).IsNitroInstanceType), ctx, instanceType)
+}
+
 // IsVolumeInitialized mocks base method.
 func (m *MockCloud) IsVolumeInitialized(ctx context.Context, volumeID string) (bool, error) {
 	m.ctrl.T.Helper()
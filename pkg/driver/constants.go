@@ -35,6 +35,9 @@ const (
 	// VolumeAttributePartition represents key for partition config in VolumeContext
 	// this represents the partition number on a device used to mount.
 	VolumeAttributePartition = "partition"
+	// VolumeAttributeSizeGiB represents key for the size of a volume restored from a snapshot in VolumeContext
+	// it is used by the node to scale how long it waits for the device to appear.
+	VolumeAttributeSizeGiB = "sizegib"
 )
 
 // constants of keys in volume parameters.
@@ -184,6 +187,14 @@ const (
 
 	// ClusterNameTagKey is the resource tag key for cluster-scoped IAM policies.
 	ClusterNameTagKey = "ebs.csi.aws.com/cluster-name"
+
+	// SnapshotSourceAZTagKey is the snapshot tag key that records the availability zone of the source volume.
+	// It is applied only when --tag-snapshots-with-source-volume is set.
+	SnapshotSourceAZTagKey = "source-az"
+
+	// SnapshotSourceVolumeTypeTagKey is the snapshot tag key that records the type of the source volume.
+	// It is applied only when --tag-snapshots-with-source-volume is set.
+	SnapshotSourceVolumeTypeTagKey = "source-volume-type"
 )
 
 // constants for default command line flag values.
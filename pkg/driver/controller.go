@@ -21,6 +21,8 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"math/rand/v2"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -136,6 +138,10 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		blockAttachUntilInitialized bool
 	)
 
+	if err = ValidateParameters(req.GetParameters()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid parameters for CreateVolume: %v", err)
+	}
+
 	tProps := new(template.PVProps)
 
 	for key, value := range req.GetParameters() {
@@ -190,41 +196,25 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		case DeprecatedBlockExpressKey:
 			klog.V(2).InfoS("blockExpress key is deprecated and has no effect, all io2 volumes are now Block Express and share the same IOPS cap")
 		case BlockSizeKey:
-			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse blockSize (%s): %v", value, err)
-			}
 			blockSize = value
 		case InodeSizeKey:
-			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse inodeSize (%s): %v", value, err)
-			}
 			inodeSize = value
 		case BytesPerInodeKey:
-			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse bytesPerInode (%s): %v", value, err)
-			}
 			bytesPerInode = value
 		case NumberOfInodesKey:
-			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse numberOfInodes (%s): %v", value, err)
-			}
 			numberOfInodes = value
 		case Ext4BigAllocKey:
 			ext4BigAlloc = isTrue(value)
 		case Ext4ClusterSizeKey:
-			if isAlphanumeric := util.StringIsAlphanumeric(value); !isAlphanumeric {
-				return nil, status.Errorf(codes.InvalidArgument, "Could not parse ext4ClusterSize (%s): %v", value, err)
-			}
 			ext4ClusterSize = value
 		case Ext4EncryptionSupportKey:
 			ext4EncryptionSupport = isTrue(value)
 		case BlockAttachUntilInitializedKey:
 			blockAttachUntilInitialized = isTrue(value)
 		default:
+			// ValidateParameters already rejected unknown keys
 			if strings.HasPrefix(key, TagKeyPrefix) {
 				tagsToEvaluate = append(tagsToEvaluate, value)
-			} else {
-				return nil, status.Errorf(codes.InvalidArgument, "Invalid parameter key %s for CreateVolume", key)
 			}
 		}
 	}
@@ -387,6 +377,9 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		if err != nil {
 			return nil, err
 		}
+		if len(d.options.AZFilter) > 0 && !slices.Contains(d.options.AZFilter, sourceVolume.AvailabilityZone) {
+			return nil, status.Errorf(codes.InvalidArgument, "Availability zone %s of source volume %s is not handled by this controller", sourceVolume.AvailabilityZone, volumeID)
+		}
 		zone = sourceVolume.AvailabilityZone
 		zoneID = sourceVolume.AvailabilityZoneID
 		outpostArn = sourceVolume.OutpostArn
@@ -394,6 +387,18 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		zone = pickAvailabilityZone(req.GetAccessibilityRequirements())
 		zoneID = pickAvailabilityZoneID(req.GetAccessibilityRequirements())
 		outpostArn = getOutpostArn(req.GetAccessibilityRequirements())
+		if len(d.options.AZFilter) > 0 {
+			// The filter lists zone names, a requirement that only carries zone IDs cannot be matched against it
+			if zone == "" && zoneID != "" {
+				return nil, status.Errorf(codes.InvalidArgument, "Availability zone IDs are not supported with --az-filter, the topology requirement must include the zone name (requested zone ID: %s)", zoneID)
+			}
+			zone = pickFilteredAvailabilityZone(req.GetAccessibilityRequirements(), d.options.AZFilter)
+			if zone == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "None of the requested availability zones are handled by this controller (handled zones: %v)", d.options.AZFilter)
+			}
+			// The zone ID may belong to a requested zone that was filtered out
+			zoneID = ""
+		}
 	}
 
 	opts := &cloud.DiskOptions{
@@ -415,6 +420,8 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		VolumeInitializationRate: volumeInitializationRate,
 	}
 
+	d.warnIfIOPSExceedsInstanceTypes(ctx, volName, req.GetAccessibilityRequirements(), opts)
+
 	disk, err := d.cloud.CreateDisk(ctx, volName, opts)
 	if err != nil {
 		var errCode codes.Code
@@ -430,7 +437,17 @@ func (d *ControllerService) CreateVolume(ctx context.Context, req *csi.CreateVol
 		}
 		return nil, status.Errorf(errCode, "Could not create volume %q: %v", volName, err)
 	}
-	return newCreateVolumeResponse(disk, responseCtx), nil
+	if snapshotID != "" {
+		responseCtx[VolumeAttributeSizeGiB] = strconv.FormatInt(int64(disk.CapacityGiB), 10)
+	}
+	resp := newCreateVolumeResponse(disk, responseCtx)
+	// Keep the region in the volume's topology when nodes report it, so the volume stays accessible from them
+	if region := pickRegion(req.GetAccessibilityRequirements()); region != "" {
+		for _, topology := range resp.GetVolume().GetAccessibleTopology() {
+			topology.Segments[WellKnownRegionTopologyKey] = region
+		}
+	}
+	return resp, nil
 }
 
 func validateCreateVolumeRequest(req *csi.CreateVolumeRequest) error {
@@ -508,11 +525,17 @@ func (d *ControllerService) ControllerPublishVolume(ctx context.Context, req *cs
 	devicePath, err := d.cloud.AttachDisk(ctx, volumeID, nodeID)
 	if err != nil {
 		if errors.Is(err, cloud.ErrNotFound) {
-			return nil, status.Errorf(codes.NotFound, "Volume %q not found", volumeID)
+			return nil, status.Errorf(codes.NotFound, "Volume %q not found: %v", volumeID, err)
 		}
 		if errors.Is(err, cloud.ErrLimitExceeded) {
 			return nil, status.Errorf(codes.ResourceExhausted, "Attachment limit exceeded for volume %q on node %q: %v", volumeID, nodeID, err)
 		}
+		if errors.Is(err, cloud.ErrDeviceNamesExhausted) {
+			return nil, status.Errorf(codes.ResourceExhausted, "No device name available to attach volume %q on node %q: %v", volumeID, nodeID, err)
+		}
+		if errors.Is(err, cloud.ErrVolumeInUse) {
+			return nil, status.Errorf(codes.FailedPrecondition, "Volume %q is attached to another node: %v", volumeID, err)
+		}
 		return nil, status.Errorf(codes.Internal, "Could not attach volume %q to node %q: %v", volumeID, nodeID, err)
 	}
 	klog.InfoS("ControllerPublishVolume: attached", "volumeID", volumeID, "nodeID", nodeID, "devicePath", devicePath)
@@ -722,6 +745,10 @@ func (d *ControllerService) ControllerExpandVolume(ctx context.Context, req *csi
 		newSize: newSize,
 	})
 	if err != nil {
+		// Keep the code of errors that were already mapped, such as InvalidArgument for a shrink
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
 		return nil, status.Errorf(codes.Internal, "Could not resize volume %q: %v", volumeID, err)
 	}
 
@@ -830,6 +857,17 @@ func isValidVolumeContext(volContext map[string]string) bool {
 			return false
 		}
 	}
+	if sizeGiB, ok := volContext[VolumeAttributeSizeGiB]; ok {
+		sizeGiBInt, err := strconv.ParseInt(sizeGiB, 10, 64)
+		if err != nil {
+			klog.ErrorS(err, "failed to parse size as int", "sizeGiB", sizeGiB)
+			return false
+		}
+		if sizeGiBInt < 0 {
+			klog.ErrorS(err, "invalid size", "sizeGiB", sizeGiB)
+			return false
+		}
+	}
 	return true
 }
 
@@ -933,13 +971,25 @@ func (d *ControllerService) CreateSnapshot(ctx context.Context, req *csi.CreateS
 		snapshotTags[NameTag] = d.options.KubernetesClusterID + "-dynamic-" + snapshotName
 		snapshotTags[ClusterNameTagKey] = d.options.KubernetesClusterID
 	}
+	if d.options.TagSnapshotsWithSourceVolume {
+		sourceVolume, err := d.cloud.GetDiskByID(ctx, volumeID)
+		if err != nil {
+			if errors.Is(err, cloud.ErrNotFound) {
+				return nil, status.Errorf(codes.NotFound, "Source volume %s not found", volumeID)
+			}
+			return nil, status.Errorf(codes.Internal, "Could not get source volume %s: %v", volumeID, err)
+		}
+		snapshotTags[SnapshotSourceAZTagKey] = sourceVolume.AvailabilityZone
+		snapshotTags[SnapshotSourceVolumeTypeTagKey] = sourceVolume.VolumeType
+	}
 	maps.Copy(snapshotTags, d.options.ExtraTags)
 
 	maps.Copy(snapshotTags, addTags)
 
 	opts := &cloud.SnapshotOptions{
-		Tags:       snapshotTags,
-		OutpostArn: outpostArn,
+		Tags:                      snapshotTags,
+		OutpostArn:                outpostArn,
+		WaitForVolumeModification: d.options.WaitForVolumeModificationBeforeSnapshot,
 	}
 
 	// Check if the availability zone is supported for fast snapshot restore
@@ -1105,6 +1155,26 @@ func pickAvailabilityZone(requirement *csi.TopologyRequirement) string {
 	return ""
 }
 
+// pickFilteredAvailabilityZone selects the first zone of the topology requirement that is in azFilter.
+// If there is no topology requirement, a random zone of azFilter is returned, so that volumes are spread
+// over the handled zones.
+// If none of the zones of the requirement is in azFilter, empty string is returned.
+func pickFilteredAvailabilityZone(requirement *csi.TopologyRequirement, azFilter []string) string {
+	topologies := slices.Concat(requirement.GetPreferred(), requirement.GetRequisite())
+	if len(topologies) == 0 {
+		return azFilter[rand.IntN(len(azFilter))]
+	}
+	for _, topology := range topologies {
+		for _, key := range []string{WellKnownZoneTopologyKey, ZoneTopologyKey} {
+			zone, exists := topology.GetSegments()[key]
+			if exists && slices.Contains(azFilter, zone) {
+				return zone
+			}
+		}
+	}
+	return ""
+}
+
 func pickAvailabilityZoneID(requirement *csi.TopologyRequirement) string {
 	if requirement == nil {
 		return ""
@@ -1124,6 +1194,66 @@ func pickAvailabilityZoneID(requirement *csi.TopologyRequirement) string {
 	return ""
 }
 
+// pickInstanceTypes returns the distinct instance types of the topology requirement.
+func pickInstanceTypes(requirement *csi.TopologyRequirement) []string {
+	var instanceTypes []string
+	for _, topology := range slices.Concat(requirement.GetPreferred(), requirement.GetRequisite()) {
+		instanceType, exists := topology.GetSegments()[WellKnownInstanceTypeTopologyKey]
+		if exists && !slices.Contains(instanceTypes, instanceType) {
+			instanceTypes = append(instanceTypes, instanceType)
+		}
+	}
+	return instanceTypes
+}
+
+// warnIfIOPSExceedsInstanceTypes logs a warning for each instance type of the topology requirement
+// that cannot deliver the IOPS requested for the volume. EBS provisions the IOPS regardless, so
+// the volume is still created. Returns the instance types that were warned about.
+func (d *ControllerService) warnIfIOPSExceedsInstanceTypes(ctx context.Context, volName string, requirement *csi.TopologyRequirement, opts *cloud.DiskOptions) []string {
+	iops := opts.IOPS
+	if iops == 0 && opts.IOPSPerGB > 0 {
+		iops = opts.IOPSPerGB * util.BytesToGiB(opts.CapacityBytes)
+	}
+	if iops == 0 {
+		return nil
+	}
+
+	var exceeded []string
+	for _, instanceType := range pickInstanceTypes(requirement) {
+		maxIOPS, err := d.cloud.GetInstanceTypeMaxIOPS(ctx, instanceType)
+		if err != nil {
+			klog.V(4).InfoS("Could not check IOPS capability of instance type", "instanceType", instanceType, "err", err)
+			continue
+		}
+		if maxIOPS > 0 && iops > maxIOPS {
+			klog.Warningf("warnIfIOPSExceedsInstanceTypes: requested IOPS %d of volume %s exceed the maximum IOPS %d that instance type %s can deliver", iops, volName, maxIOPS, instanceType)
+			exceeded = append(exceeded, instanceType)
+		}
+	}
+	return exceeded
+}
+
+// pickRegion returns the region of the topology requirement.
+// if not found, empty string is returned.
+func pickRegion(requirement *csi.TopologyRequirement) string {
+	if requirement == nil {
+		return ""
+	}
+	for _, topology := range requirement.GetPreferred() {
+		region, exists := topology.GetSegments()[WellKnownRegionTopologyKey]
+		if exists {
+			return region
+		}
+	}
+	for _, topology := range requirement.GetRequisite() {
+		region, exists := topology.GetSegments()[WellKnownRegionTopologyKey]
+		if exists {
+			return region
+		}
+	}
+	return ""
+}
+
 func getOutpostArn(requirement *csi.TopologyRequirement) string {
 	if requirement == nil {
 		return ""
@@ -26,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/coalescer"
@@ -164,7 +165,7 @@ func executeModifyVolumeRequest(c cloud.Cloud) func(string, modifyVolumeRequest)
 				case errors.Is(err, cloud.ErrLimitExceeded):
 					return 0, status.Errorf(codes.ResourceExhausted, "Could not modify volume (resource exhausted) %q: %v", volumeID, err)
 				default:
-					return 0, status.Errorf(codes.Internal, "Could not modify volume %q: %v", volumeID, err)
+					return 0, status.Errorf(codes.Internal, "Could not modify volume %q: %v%s", volumeID, err, modificationProgress(ctx, c, volumeID))
 				}
 			} else {
 				return actualSizeGiB, nil
@@ -175,6 +176,22 @@ func executeModifyVolumeRequest(c cloud.Cloud) func(string, modifyVolumeRequest)
 	}
 }
 
+// modificationProgress describes the progress of an in-flight modification of the volume, to be appended
+// to errors of failed modifications. Returns an empty string if the volume is not being modified.
+func modificationProgress(ctx context.Context, c cloud.Cloud, volumeID string) string {
+	state, percent, err := c.GetVolumeModificationProgress(ctx, volumeID)
+	if err != nil {
+		if !errors.Is(err, cloud.ErrVolumeNotBeingModified) {
+			klog.V(4).InfoS("Could not get volume modification progress", "volumeID", volumeID, "err", err)
+		}
+		return ""
+	}
+	if state != string(types.VolumeModificationStateModifying) && state != string(types.VolumeModificationStateOptimizing) {
+		return ""
+	}
+	return fmt.Sprintf(" (in-flight modification is %s, %d%% done)", state, percent)
+}
+
 func parseModifyVolumeParameters(params map[string]string) (*modifyVolumeRequest, error) {
 	options := modifyVolumeRequest{
 		modifyTagsOptions: cloud.ModifyTagsOptions{
@@ -17,12 +17,17 @@ limitations under the License.
 package driver
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/testutil"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -299,3 +304,55 @@ func TestParseModifyVolumeParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteModifyVolumeRequestProgress(t *testing.T) {
+	const volumeID = "vol-test"
+	testCases := []struct {
+		name          string
+		state         string
+		percent       int
+		progressErr   error
+		expectedError string
+	}{
+		{
+			name:          "modifying",
+			state:         "modifying",
+			percent:       30,
+			expectedError: `Could not modify volume "vol-test": volume "vol-test" is still being modified to type "gp3" (in-flight modification is modifying, 30% done)`,
+		},
+		{
+			name:          "optimizing",
+			state:         "optimizing",
+			percent:       80,
+			expectedError: `Could not modify volume "vol-test": volume "vol-test" is still being modified to type "gp3" (in-flight modification is optimizing, 80% done)`,
+		},
+		{
+			name:          "completed",
+			state:         "completed",
+			percent:       100,
+			expectedError: `Could not modify volume "vol-test": volume "vol-test" is still being modified to type "gp3"`,
+		},
+		{
+			name:          "not being modified",
+			progressErr:   cloud.ErrVolumeNotBeingModified,
+			expectedError: `Could not modify volume "vol-test": volume "vol-test" is still being modified to type "gp3"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().ResizeOrModifyDisk(testutil.AnyContext(), gomock.Eq(volumeID), gomock.Eq(int64(0)), gomock.Eq(&cloud.ModifyDiskOptions{VolumeType: "gp3"})).
+				Return(int32(0), errors.New(`volume "vol-test" is still being modified to type "gp3"`))
+			mockCloud.EXPECT().GetVolumeModificationProgress(testutil.AnyContext(), gomock.Eq(volumeID)).Return(tc.state, tc.percent, tc.progressErr)
+
+			_, err := executeModifyVolumeRequest(mockCloud)(volumeID, modifyVolumeRequest{
+				modifyDiskOptions: cloud.ModifyDiskOptions{VolumeType: "gp3"},
+			})
+			require.Error(t, err)
+			assert.Equal(t, codes.Internal, status.Code(err))
+			assert.Equal(t, tc.expectedError, status.Convert(err).Message())
+		})
+	}
+}
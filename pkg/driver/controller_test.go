@@ -22,15 +22,21 @@ import (
 	"fmt"
 	"maps"
 	"math/rand"
+	"net/http"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
@@ -252,6 +258,198 @@ func TestCreateVolume(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success with region topology",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Requisite: []*csi.Topology{
+							{
+								Segments: map[string]string{
+									WellKnownZoneTopologyKey:   expZone,
+									WellKnownRegionTopologyKey: "us-west-2",
+								},
+							},
+						},
+					},
+				}
+				expectedSegments := map[string]string{
+					WellKnownZoneTopologyKey:   expZone,
+					WellKnownRegionTopologyKey: "us-west-2",
+				}
+				if p := plugin.GetPlugin(); p != nil {
+					maps.Copy(expectedSegments, p.GetDiskTopologySegments())
+				}
+
+				ctx := t.Context()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.GetName(),
+					AvailabilityZone: expZone,
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				expectedOpts := &cloud.DiskOptions{
+					CapacityBytes:    stdVolSize,
+					AvailabilityZone: expZone,
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey:   req.GetName(),
+						cloud.AwsEbsDriverTagKey: "true",
+					},
+				}
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.GetName()), gomock.Eq(expectedOpts)).Return(mockDisk, nil)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options:  &Options{},
+				}
+
+				resp, err := awsDriver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				expTopology := []*csi.Topology{{Segments: expectedSegments}}
+				if !reflect.DeepEqual(expTopology, resp.GetVolume().GetAccessibleTopology()) {
+					t.Fatalf("Expected AccessibleTopology to be %+v, got: %+v", expTopology, resp.GetVolume().GetAccessibleTopology())
+				}
+			},
+		},
+		{
+			name: "success with az filter picks handled zone",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Preferred: []*csi.Topology{
+							{
+								Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+							},
+							{
+								Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1b"},
+							},
+						},
+					},
+				}
+
+				ctx := t.Context()
+
+				mockDisk := &cloud.Disk{
+					VolumeID:         req.GetName(),
+					AvailabilityZone: "us-east-1b",
+					CapacityGiB:      util.BytesToGiB(stdVolSize),
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				expectedOpts := &cloud.DiskOptions{
+					CapacityBytes:    stdVolSize,
+					AvailabilityZone: "us-east-1b",
+					Tags: map[string]string{
+						cloud.VolumeNameTagKey:   req.GetName(),
+						cloud.AwsEbsDriverTagKey: "true",
+					},
+				}
+				mockCloud.EXPECT().CreateDisk(gomock.Eq(ctx), gomock.Eq(req.GetName()), gomock.Eq(expectedOpts)).Return(mockDisk, nil)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options:  &Options{AZFilter: []string{"us-east-1b"}},
+				}
+
+				if _, err := awsDriver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail with az filter not handling requested zone",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Requisite: []*csi.Topology{
+							{
+								Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+							},
+						},
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				// CreateDisk must not be called
+				mockCloud := cloud.NewMockCloud(mockCtl)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options:  &Options{AZFilter: []string{"us-east-1b"}},
+				}
+
+				_, err := awsDriver.CreateVolume(t.Context(), req)
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+			},
+		},
+		{
+			name: "fail with az filter and zone ID only topology",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateVolumeRequest{
+					Name:               "test-vol",
+					CapacityRange:      stdCapRange,
+					VolumeCapabilities: stdVolCap,
+					Parameters:         map[string]string{},
+					AccessibilityRequirements: &csi.TopologyRequirement{
+						Requisite: []*csi.Topology{
+							{
+								Segments: map[string]string{ZoneIDTopologyKey: "use1-az2"},
+							},
+						},
+					},
+				}
+
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				// CreateDisk must not be called
+				mockCloud := cloud.NewMockCloud(mockCtl)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options:  &Options{AZFilter: []string{"us-east-1b"}},
+				}
+
+				_, err := awsDriver.CreateVolume(t.Context(), req)
+				checkExpectedErrorCode(t, err, codes.InvalidArgument)
+				if !strings.Contains(err.Error(), "zone IDs are not supported with --az-filter") {
+					t.Errorf("expected the error to explain that zone IDs are not supported, got: %v", err)
+				}
+			},
+		},
 		{
 			name: "clone success KMS key id",
 			testFunc: func(t *testing.T) {
@@ -852,6 +1050,9 @@ func TestCreateVolume(t *testing.T) {
 				if rsp.GetVolume().GetContentSource().GetSnapshot().GetSnapshotId() != "snapshot-id" {
 					t.Errorf("Unexpected snapshot ID: %q", snapshotID)
 				}
+				if sizeGiB := rsp.GetVolume().GetVolumeContext()[VolumeAttributeSizeGiB]; sizeGiB != strconv.FormatInt(int64(mockDisk.CapacityGiB), 10) {
+					t.Errorf("Unexpected %s in volume context: %q", VolumeAttributeSizeGiB, sizeGiB)
+				}
 			},
 		},
 		{
@@ -3468,6 +3669,230 @@ func TestPickAvailabilityZone(t *testing.T) {
 	}
 }
 
+func TestPickRegion(t *testing.T) {
+	testCases := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		expRegion   string
+	}{
+		{
+			name: "Pick from preferred",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownRegionTopologyKey: "us-east-1"},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: expZone, WellKnownRegionTopologyKey: "us-west-2"},
+					},
+				},
+			},
+			expRegion: "us-west-2",
+		},
+		{
+			name: "Pick from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: expZone, WellKnownRegionTopologyKey: "us-west-2"},
+					},
+				},
+			},
+			expRegion: "us-west-2",
+		},
+		{
+			name: "No region segment",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: expZone},
+					},
+				},
+			},
+			expRegion: "",
+		},
+		{
+			name:        "Topology Requirement is nil",
+			requirement: nil,
+			expRegion:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := pickRegion(tc.requirement)
+			if actual != tc.expRegion {
+				t.Fatalf("Expected region %v, got region: %v", tc.expRegion, actual)
+			}
+		})
+	}
+}
+
+func TestPickFilteredAvailabilityZone(t *testing.T) {
+	azFilter := []string{"us-east-1b", "us-east-1c"}
+	testCases := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		expZone     string
+	}{
+		{
+			name: "Pick first handled zone from preferred",
+			requirement: &csi.TopologyRequirement{
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+					},
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1c"},
+					},
+				},
+			},
+			expZone: "us-east-1c",
+		},
+		{
+			name: "Pick handled zone from requisite",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{ZoneTopologyKey: "us-east-1b"},
+					},
+				},
+				Preferred: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+					},
+				},
+			},
+			expZone: "us-east-1b",
+		},
+		{
+			name: "No handled zone",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+					},
+				},
+			},
+			expZone: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := pickFilteredAvailabilityZone(tc.requirement, azFilter)
+			if actual != tc.expZone {
+				t.Fatalf("Expected zone %v, got zone: %v", tc.expZone, actual)
+			}
+		})
+	}
+
+	t.Run("Topology Requirement is nil", func(t *testing.T) {
+		picked := map[string]bool{}
+		for range 100 {
+			zone := pickFilteredAvailabilityZone(nil, azFilter)
+			if !slices.Contains(azFilter, zone) {
+				t.Fatalf("Expected a zone of %v, got zone: %v", azFilter, zone)
+			}
+			picked[zone] = true
+		}
+		if len(picked) != len(azFilter) {
+			t.Fatalf("Expected all zones of %v to be picked, got zones: %v", azFilter, picked)
+		}
+	})
+}
+
+func TestWarnIfIOPSExceedsInstanceTypes(t *testing.T) {
+	requirement := &csi.TopologyRequirement{
+		Requisite: []*csi.Topology{
+			{
+				Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a", WellKnownInstanceTypeTopologyKey: "t3.micro"},
+			},
+			{
+				Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1b", WellKnownInstanceTypeTopologyKey: "m7i.48xlarge"},
+			},
+		},
+		Preferred: []*csi.Topology{
+			{
+				Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a", WellKnownInstanceTypeTopologyKey: "t3.micro"},
+			},
+		},
+	}
+	testCases := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		opts        *cloud.DiskOptions
+		mockCloud   func(mockCloud *cloud.MockCloud)
+		expWarned   []string
+	}{
+		{
+			name:        "high IOPS volume targeting a low bandwidth instance type",
+			requirement: requirement,
+			opts:        &cloud.DiskOptions{IOPS: 64000},
+			mockCloud: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetInstanceTypeMaxIOPS(testutil.AnyContext(), gomock.Eq("t3.micro")).Return(int32(11800), nil).Times(1)
+				mockCloud.EXPECT().GetInstanceTypeMaxIOPS(testutil.AnyContext(), gomock.Eq("m7i.48xlarge")).Return(int32(240000), nil).Times(1)
+			},
+			expWarned: []string{"t3.micro"},
+		},
+		{
+			name:        "IOPS derived from iopsPerGB",
+			requirement: requirement,
+			opts:        &cloud.DiskOptions{IOPSPerGB: 500, CapacityBytes: util.GiBToBytes(100)},
+			mockCloud: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetInstanceTypeMaxIOPS(testutil.AnyContext(), gomock.Eq("t3.micro")).Return(int32(11800), nil).Times(1)
+				mockCloud.EXPECT().GetInstanceTypeMaxIOPS(testutil.AnyContext(), gomock.Eq("m7i.48xlarge")).Return(int32(240000), nil).Times(1)
+			},
+			expWarned: []string{"t3.micro"},
+		},
+		{
+			name:        "API error is not a warning",
+			requirement: requirement,
+			opts:        &cloud.DiskOptions{IOPS: 64000},
+			mockCloud: func(mockCloud *cloud.MockCloud) {
+				mockCloud.EXPECT().GetInstanceTypeMaxIOPS(testutil.AnyContext(), testutil.OfType("")).Return(int32(0), errors.New("DescribeInstanceTypes failed")).Times(2)
+			},
+		},
+		{
+			name:        "no IOPS requested",
+			requirement: requirement,
+			opts:        &cloud.DiskOptions{},
+			mockCloud:   func(_ *cloud.MockCloud) {},
+		},
+		{
+			name: "no instance type in topology",
+			requirement: &csi.TopologyRequirement{
+				Requisite: []*csi.Topology{
+					{
+						Segments: map[string]string{WellKnownZoneTopologyKey: "us-east-1a"},
+					},
+				},
+			},
+			opts:      &cloud.DiskOptions{IOPS: 64000},
+			mockCloud: func(_ *cloud.MockCloud) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			tc.mockCloud(mockCloud)
+
+			awsDriver := ControllerService{
+				cloud:    mockCloud,
+				inFlight: internal.NewInFlight(),
+				options:  &Options{},
+			}
+
+			warned := awsDriver.warnIfIOPSExceedsInstanceTypes(t.Context(), "vol-test", tc.requirement, tc.opts)
+			assert.Equal(t, tc.expWarned, warned)
+		})
+	}
+}
+
 func TestGetOutpostArn(t *testing.T) {
 	expRawOutpostArn := testOutpostARN
 	outpostArn, _ := arn.Parse(strings.ReplaceAll(expRawOutpostArn, "outpost/", ""))
@@ -3662,6 +4087,127 @@ func TestCreateSnapshot(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success wait for volume modification",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := t.Context()
+				mockSnapshot := &cloud.Snapshot{
+					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
+					SourceVolumeID: req.GetSourceVolumeId(),
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				expectedSnapshotOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						cloud.SnapshotNameTagKey: req.GetName(),
+						cloud.AwsEbsDriverTagKey: "true",
+					},
+					WaitForVolumeModification: true,
+				}
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.GetSourceVolumeId()), gomock.Eq(expectedSnapshotOpts)).Return(mockSnapshot, nil)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(nil, cloud.ErrNotFound)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options: &Options{
+						WaitForVolumeModificationBeforeSnapshot: true,
+					},
+				}
+				if _, err := awsDriver.CreateSnapshot(t.Context(), req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "success tag with source volume",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := t.Context()
+				mockSnapshot := &cloud.Snapshot{
+					SnapshotID:     fmt.Sprintf("snapshot-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Uint64()),
+					SourceVolumeID: req.GetSourceVolumeId(),
+					Size:           1,
+					CreationTime:   time.Now(),
+				}
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				expectedSnapshotOpts := &cloud.SnapshotOptions{
+					Tags: map[string]string{
+						cloud.SnapshotNameTagKey:       req.GetName(),
+						cloud.AwsEbsDriverTagKey:       "true",
+						SnapshotSourceAZTagKey:         "us-east-1a",
+						SnapshotSourceVolumeTypeTagKey: "io2",
+					},
+				}
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.GetSourceVolumeId())).Return(&cloud.Disk{
+					VolumeID:         req.GetSourceVolumeId(),
+					AvailabilityZone: "us-east-1a",
+					VolumeType:       "io2",
+				}, nil)
+				mockCloud.EXPECT().CreateSnapshot(gomock.Eq(ctx), gomock.Eq(req.GetSourceVolumeId()), gomock.Eq(expectedSnapshotOpts)).Return(mockSnapshot, nil)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options: &Options{
+						TagSnapshotsWithSourceVolume: true,
+					},
+				}
+				if _, err := awsDriver.CreateSnapshot(t.Context(), req); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			name: "fail tag with source volume not found",
+			testFunc: func(t *testing.T) {
+				t.Helper()
+				req := &csi.CreateSnapshotRequest{
+					Name:           "test-snapshot",
+					Parameters:     nil,
+					SourceVolumeId: "vol-test",
+				}
+
+				ctx := t.Context()
+				mockCtl := gomock.NewController(t)
+				defer mockCtl.Finish()
+
+				mockCloud := cloud.NewMockCloud(mockCtl)
+				mockCloud.EXPECT().GetSnapshotByName(gomock.Eq(ctx), gomock.Eq(req.GetName())).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().GetDiskByID(gomock.Eq(ctx), gomock.Eq(req.GetSourceVolumeId())).Return(nil, cloud.ErrNotFound)
+
+				awsDriver := ControllerService{
+					cloud:    mockCloud,
+					inFlight: internal.NewInFlight(),
+					options: &Options{
+						TagSnapshotsWithSourceVolume: true,
+					},
+				}
+				_, err := awsDriver.CreateSnapshot(t.Context(), req)
+				checkExpectedErrorCode(t, err, codes.NotFound)
+			},
+		},
 		{
 			name: "success outpost",
 			testFunc: func(t *testing.T) {
@@ -5164,6 +5710,7 @@ func TestControllerPublishVolume(t *testing.T) {
 		mockAttach       func(mockCloud *cloud.MockCloud, ctx context.Context, volumeID string, nodeID string)
 		expResp          *csi.ControllerPublishVolumeResponse
 		errorCode        codes.Code
+		errorContains    string
 		setupFunc        func(ControllerService *ControllerService)
 	}{
 		{
@@ -5189,6 +5736,36 @@ func TestControllerPublishVolume(t *testing.T) {
 			},
 			errorCode: codes.ResourceExhausted,
 		},
+		{
+			name:             "ResourceExhausted error when device names are exhausted",
+			volumeID:         "vol-test",
+			nodeID:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeID string, nodeID string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(expInstanceID)).Return("", cloud.ErrDeviceNamesExhausted)
+			},
+			errorCode: codes.ResourceExhausted,
+		},
+		{
+			name:             "FailedPrecondition error when volume is attached to another node",
+			volumeID:         "vol-test",
+			nodeID:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeID string, nodeID string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(expInstanceID)).Return("", cloud.ErrVolumeInUse)
+			},
+			errorCode: codes.FailedPrecondition,
+		},
+		{
+			name:             "NotFound error when volume is being deleted",
+			volumeID:         "vol-test",
+			nodeID:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeID string, nodeID string) {
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(expInstanceID)).Return("", fmt.Errorf("%w: volume %q is being deleted", cloud.ErrNotFound, volumeID))
+			},
+			errorCode: codes.NotFound,
+		},
 		{
 			name:             "AttachDisk when volume is already attached to the node",
 			volumeID:         "vol-test",
@@ -5309,6 +5886,24 @@ func TestControllerPublishVolume(t *testing.T) {
 			},
 			errorCode: codes.Internal,
 		},
+		{
+			name:             "Fail with request ID when volume does not exist",
+			volumeID:         "vol-test",
+			nodeID:           expInstanceID,
+			volumeCapability: stdVolCap,
+			mockAttach: func(mockCloud *cloud.MockCloud, ctx context.Context, volumeID string, nodeID string) {
+				awsErr := &awshttp.ResponseError{
+					ResponseError: &smithyhttp.ResponseError{
+						Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusBadRequest}},
+						Err:      &smithy.GenericAPIError{Code: "InvalidVolume.NotFound"},
+					},
+					RequestID: "a1b2c3d4-request-id",
+				}
+				mockCloud.EXPECT().AttachDisk(gomock.Eq(ctx), gomock.Eq(volumeID), gomock.Eq(expInstanceID)).Return("", fmt.Errorf("%w: %w", cloud.ErrNotFound, awsErr))
+			},
+			errorCode:     codes.NotFound,
+			errorContains: "a1b2c3d4-request-id",
+		},
 		{
 			name:             "Aborted error when AttachDisk operation already in-flight",
 			volumeID:         "vol-test",
@@ -5394,6 +5989,9 @@ func TestControllerPublishVolume(t *testing.T) {
 			if tc.errorCode != codes.OK {
 				assert.Equal(t, tc.errorCode, status.Code(err))
 				assert.Nil(t, resp)
+				if tc.errorContains != "" {
+					assert.Contains(t, status.Convert(err).Message(), tc.errorContains)
+				}
 			} else {
 				require.NoError(t, err)
 				assert.NotNil(t, resp)
@@ -5600,6 +6198,62 @@ func TestControllerExpandVolume(t *testing.T) {
 	}
 }
 
+func TestControllerExpandVolumeSize(t *testing.T) {
+	testCases := []struct {
+		name         string
+		reqSizeGiB   int32
+		resizeSize   int32
+		resizeErr    error
+		expSizeGiB   int32
+		expErrorCode codes.Code
+	}{
+		{
+			name:       "grow",
+			reqSizeGiB: 20,
+			resizeSize: 20,
+			expSizeGiB: 20,
+		},
+		{
+			name:       "equal size is a no-op",
+			reqSizeGiB: 10,
+			resizeSize: 10,
+			expSizeGiB: 10,
+		},
+		{
+			name:         "shrink",
+			reqSizeGiB:   5,
+			resizeErr:    fmt.Errorf("%w: volume %q cannot be shrunk from 10 GiB to 5 GiB", cloud.ErrInvalidArgument, "vol-test"),
+			expErrorCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			mockCloud := cloud.NewMockCloud(mockCtl)
+			mockCloud.EXPECT().ResizeOrModifyDisk(testutil.AnyContext(), gomock.Eq("vol-test"), gomock.Eq(util.GiBToBytes(tc.reqSizeGiB)), testutil.OfType(&cloud.ModifyDiskOptions{})).Return(tc.resizeSize, tc.resizeErr).Times(1)
+
+			awsDriver := ControllerService{
+				cloud:                 mockCloud,
+				inFlight:              internal.NewInFlight(),
+				options:               &Options{},
+				modifyVolumeCoalescer: newModifyVolumeCoalescer(mockCloud, &Options{}),
+			}
+
+			resp, err := awsDriver.ControllerExpandVolume(t.Context(), &csi.ControllerExpandVolumeRequest{
+				VolumeId:      "vol-test",
+				CapacityRange: &csi.CapacityRange{RequiredBytes: util.GiBToBytes(tc.reqSizeGiB)},
+			})
+			if tc.expErrorCode != codes.OK {
+				checkExpectedErrorCode(t, err, tc.expErrorCode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, util.GiBToBytes(tc.expSizeGiB), resp.GetCapacityBytes())
+		})
+	}
+}
+
 func TestControllerModifyVolume(t *testing.T) {
 	testCases := []struct {
 		name     string
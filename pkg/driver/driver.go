@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/awslabs/volume-modifier-for-k8s/pkg/rpc"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/limits"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/mounter"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
@@ -50,11 +52,16 @@ const (
 
 const (
 	WellKnownZoneTopologyKey = "topology.kubernetes.io/zone"
+	// WellKnownRegionTopologyKey is only reported by nodes when --enable-region-topology is set.
+	WellKnownRegionTopologyKey = "topology.kubernetes.io/region"
 	// ZoneIDTopologyKey name is purposefully consistent with the CCM's ZoneID topology key.
 	// This key is only used for provisioning by az-id and will not be used for node topology
 	// to prevent any backwards compatibility issues.
 	ZoneIDTopologyKey = "topology.k8s.aws/zone-id"
 	OSTopologyKey     = "kubernetes.io/os"
+	// WellKnownInstanceTypeTopologyKey is not reported by nodes, but may be passed through from the
+	// allowed topologies of a StorageClass. It is only used to warn about unreachable IOPS.
+	WellKnownInstanceTypeTopologyKey = "node.kubernetes.io/instance-type"
 )
 
 // Initialized in NewDriver (depend on driver name).
@@ -114,6 +121,26 @@ func NewDriver(c cloud.Cloud, o *Options, m mounter.Mounter, md metadata.Metadat
 		return nil, fmt.Errorf("unknown mode: %s", o.Mode)
 	}
 
+	if driver.node != nil && c != nil && o.DescribeInstanceTypeHypervisor {
+		// The hypervisor reported by DescribeInstanceTypes is cached by the cloud, which falls back to the
+		// static table when the call fails.
+		driver.node.WithVolumeLimitProvider(limits.WithNitroDetector(driver.node.volumeLimitProvider, func(instanceType string) bool {
+			ctx, cancel := context.WithTimeout(context.Background(), describeHypervisorTimeout)
+			defer cancel()
+			return c.IsNitroInstanceType(ctx, instanceType)
+		}))
+	}
+
+	if driver.node != nil && c != nil && o.DescribeInstanceTypeAccelerators {
+		driver.node.acceleratorCounter = func(instanceType string) (int, error) {
+			ctx, cancel := context.WithTimeout(context.Background(), describeAcceleratorsTimeout)
+			defer cancel()
+			return c.GetInstanceTypeAcceleratorCount(ctx, instanceType)
+		}
+		// Look up the accelerators on startup rather than in the first NodeGetInfo
+		driver.node.getAcceleratorCount(md.GetInstanceType())
+	}
+
 	return driver, nil
 }
 
@@ -164,6 +191,10 @@ func (d *Driver) Run() error {
 		return fmt.Errorf("unknown mode: %s", d.options.Mode)
 	}
 
+	if d.node != nil {
+		d.node.start()
+	}
+
 	klog.V(4).InfoS("Listening for connections", "address", listener.Addr())
 	return d.srv.Serve(listener)
 }
@@ -171,3 +202,22 @@ func (d *Driver) Run() error {
 func (d *Driver) Stop() {
 	d.srv.Stop()
 }
+
+// GracefulStop stops accepting new RPCs and waits up to timeout for in-flight RPCs to finish.
+// RPCs still running after timeout are cancelled.
+func (d *Driver) GracefulStop(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		d.srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		klog.InfoS("All in-flight requests finished, driver stopped")
+	case <-time.After(timeout):
+		klog.InfoS("Timed out waiting for in-flight requests to finish, stopping driver", "timeout", timeout)
+		d.srv.Stop()
+		<-done
+	}
+}
@@ -15,13 +15,28 @@
 package driver
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/limits"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/mounter"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/testutil"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -96,3 +111,143 @@ func TestNewDriver(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDriverConfiguresNodeBeforeStartingIt(t *testing.T) {
+	t.Setenv("CSI_NODE_NAME", "test-node")
+	t.Setenv("DISABLE_TAINT_WATCHER", "true")
+	ctrl := gomock.NewController(t)
+
+	mockMetadataService := metadata.NewMockMetadataService(ctrl)
+	mockMetadataService.EXPECT().GetInstanceType().Return("g5.xlarge").AnyTimes()
+	mockMetadataService.EXPECT().GetNumBlockDeviceMappings().Return(0).AnyTimes()
+	mockMetadataService.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+	mockCloud := cloud.NewMockCloud(ctrl)
+	mockCloud.EXPECT().GetInstanceTypeAcceleratorCount(testutil.AnyContext(), gomock.Eq("g5.xlarge")).Return(4, nil)
+
+	fakeClient := fake.NewClientset(&storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{Name: util.GetDriverName(), NodeID: "i-1234567890abcdef0"}},
+		},
+	})
+
+	driver, err := NewDriver(mockCloud, &Options{
+		Mode:                             NodeMode,
+		VolumeAttachLimit:                -1,
+		ReservedVolumeAttachments:        -1,
+		ReconcileCSINodeAllocatable:      true,
+		DescribeInstanceTypeAccelerators: true,
+	}, mounter.NewMockMounter(ctrl), mockMetadataService, fakeClient)
+	require.NoError(t, err)
+	assert.Empty(t, fakeClient.Actions(), "the node must not start its goroutines before it is configured")
+
+	driver.node.start()
+	// The CSINode is patched with the limit that takes the 3 accelerators beyond the GPU of the limits tables into account
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		csiNode, err := fakeClient.StorageV1().CSINodes().Get(t.Context(), "test-node", metav1.GetOptions{})
+		require.NoError(c, err)
+		allocatable := csiNode.Spec.Drivers[0].Allocatable
+		require.NotNil(c, allocatable)
+		assert.Equal(c, int32(21), aws.ToInt32(allocatable.Count))
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewDriverAsksCloudWhetherInstanceTypeIsNitro(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockCloud := cloud.NewMockCloud(ctrl)
+	mockCloud.EXPECT().IsNitroInstanceType(testutil.AnyContext(), gomock.Eq("m5.large")).Return(false)
+
+	driver, err := NewDriver(mockCloud, &Options{Mode: NodeMode, DescribeInstanceTypeHypervisor: true}, mounter.NewMockMounter(ctrl), metadata.NewMockMetadataService(ctrl), nil)
+	require.NoError(t, err)
+
+	limit, attachmentType := driver.node.volumeLimitProvider.GetVolumeLimits("m5.large")
+	assert.Equal(t, limits.NonNitroMaxAttachments, limit)
+	assert.Equal(t, util.AttachmentDedicated, attachmentType)
+}
+
+func TestNewDriverKeepsLimitsTablesWithoutDescribeInstanceTypeHypervisor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	// IsNitroInstanceType is not expected, the mock fails the test if it is called
+	driver, err := NewDriver(cloud.NewMockCloud(ctrl), &Options{Mode: NodeMode}, mounter.NewMockMounter(ctrl), metadata.NewMockMetadataService(ctrl), nil)
+	require.NoError(t, err)
+
+	limit, attachmentType := driver.node.volumeLimitProvider.GetVolumeLimits("m5.large")
+	assert.Equal(t, limits.NitroMaxAttachments, limit)
+	assert.Equal(t, util.AttachmentShared, attachmentType)
+}
+
+func TestGracefulStop(t *testing.T) {
+	testCases := []struct {
+		name       string
+		attachTime time.Duration
+		timeout    time.Duration
+		expCode    codes.Code
+	}{
+		{
+			name:       "in-flight attach finishes within timeout",
+			attachTime: 200 * time.Millisecond,
+			timeout:    5 * time.Second,
+			expCode:    codes.OK,
+		},
+		{
+			name:       "in-flight attach is cancelled after timeout",
+			attachTime: time.Minute,
+			timeout:    100 * time.Millisecond,
+			// The connection is closed before the cancelled attach returns
+			expCode: codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCloud := cloud.NewMockCloud(ctrl)
+
+			attachStarted := make(chan struct{})
+			mockCloud.EXPECT().AttachDisk(testutil.AnyContext(), gomock.Eq("vol-test"), gomock.Eq("i-test")).DoAndReturn(func(ctx context.Context, _, _ string) (string, error) {
+				close(attachStarted)
+				select {
+				case <-time.After(tc.attachTime):
+					return "/dev/xvdba", nil
+				case <-ctx.Done():
+					return "", ctx.Err()
+				}
+			})
+
+			endpoint := "unix://" + filepath.Join(t.TempDir(), "csi.sock")
+			drv, err := NewDriver(mockCloud, &Options{Mode: ControllerMode, Endpoint: endpoint, ModifyVolumeRequestHandlerTimeout: 1}, nil, nil, nil)
+			require.NoError(t, err)
+
+			runErr := make(chan error, 1)
+			go func() {
+				runErr <- drv.Run()
+			}()
+
+			conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+			require.NoError(t, err)
+			defer conn.Close()
+			client := csi.NewControllerClient(conn)
+
+			publishErr := make(chan error, 1)
+			go func() {
+				_, err := client.ControllerPublishVolume(t.Context(), &csi.ControllerPublishVolumeRequest{
+					VolumeId: "vol-test",
+					NodeId:   "i-test",
+					VolumeCapability: &csi.VolumeCapability{
+						AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+						AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER},
+					},
+				}, grpc.WaitForReady(true))
+				publishErr <- err
+			}()
+
+			<-attachStarted
+			drv.GracefulStop(tc.timeout)
+
+			require.NoError(t, <-runErr)
+			assert.Equal(t, tc.expCode, status.Code(<-publishErr))
+		})
+	}
+}
@@ -69,5 +69,12 @@ func (d *Driver) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeRe
 		}
 	}
 
+	// Node Service optionally verifies that attached EBS volumes can be resolved to their devices
+	if d.node != nil && d.node.options.NVMeHealthCheck {
+		if err := d.node.checkNVMeHealth(); err != nil {
+			return &csi.ProbeResponse{}, status.Errorf(codes.FailedPrecondition, "Failed health check (verify the NVMe driver is loaded and udev is running): %v", err)
+		}
+	}
+
 	return &csi.ProbeResponse{}, nil
 }
@@ -19,33 +19,42 @@ package driver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/limits"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/mounter"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/plugin"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -78,31 +87,148 @@ var (
 const (
 	// taintWatcherDuration is the maximum duration for the not-ready taint watcher to run.
 	taintWatcherDuration = 10 * time.Minute
+	// csiNodeReconcileTimeout is how long the driver waits for kubelet to register it in the CSINode of the node
+	// before giving up on correcting its allocatable volume count.
+	csiNodeReconcileTimeout = 5 * time.Minute
+	// describeAcceleratorsTimeout bounds the DescribeInstanceTypes calls made for --describe-instance-type-accelerators.
+	describeAcceleratorsTimeout = 30 * time.Second
+	// describeHypervisorTimeout bounds the DescribeInstanceTypes call made for --describe-instance-type-hypervisor.
+	describeHypervisorTimeout = 30 * time.Second
+	// volumeLimitsDebugPath is where --debug-volume-limits-endpoint serves the resolution of the volume attach limit.
+	volumeLimitsDebugPath = "/debug/volume-limits"
+	// csiNodeReconcileInterval is how often the CSINode is checked while waiting for the driver to be registered.
+	csiNodeReconcileInterval = 5 * time.Second
+
+	// deviceWaitInterval is how often the device of a volume is looked up while waiting for it to appear.
+	deviceWaitInterval = 1 * time.Second
+
+	// nvmeClassPath only exists when the NVMe driver, which exposes EBS volumes on Nitro instances, is loaded.
+	nvmeClassPath = "/sys/class/nvme"
+	// diskByIDPath holds the udev links FindDevicePath resolves NVMe devices through.
+	diskByIDPath = "/dev/disk/by-id"
+	// instanceStoreModel is the model reported by NVMe controllers of instance store volumes.
+	instanceStoreModel = "Amazon EC2 NVMe Instance Storage"
+	// ebsModel is the model reported by NVMe controllers of EBS volumes.
+	ebsModel = "Amazon Elastic Block Store"
+	// volumeLimitUnknownReason is the reason of the event recorded when the volume attach limit cannot be computed.
+	volumeLimitUnknownReason = "VolumeLimitUnknown"
+	// volumeLimitFallbackReason is the reason of the event recorded when the default volume attach limit is reported.
+	volumeLimitFallbackReason = "VolumeLimitFallback"
 )
 
+// instanceTypeWaitBackoff is how long NodeGetInfo waits for the metadata source to know the instance type.
+var instanceTypeWaitBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Steps:    5,
+}
+
 // NodeService represents the node service of CSI driver.
 type NodeService struct {
 	metadata metadata.MetadataService
 	mounter  mounter.Mounter
 	inFlight *internal.InFlight
 	options  *Options
+	// reportedLimit is the volume attach limit last reported to Kubernetes.
+	reportedLimit atomic.Pointer[reportedVolumeLimit]
+	// volumeLimitProvider provides the volume limits of instance types.
+	// When nil, limits.DefaultVolumeLimitProvider is used.
+	volumeLimitProvider limits.VolumeLimitProvider
+	// instanceStoreVolumeCounter counts the instance store volumes attached to the node.
+	// When nil, the NVMe controllers in nvmeClassPath are counted.
+	instanceStoreVolumeCounter func() (int, error)
+	// ebsVolumeCounter counts the EBS volumes attached to the node.
+	// When nil, the NVMe controllers in nvmeClassPath are counted.
+	ebsVolumeCounter func() (int, error)
+	// eventRecorder records events on the Node object of the node. When nil, no events are recorded.
+	eventRecorder record.EventRecorder
+	// kubeClient is used by the goroutines that watch and update the Node and CSINode objects of the node.
+	// When nil, they are not started.
+	kubeClient kubernetes.Interface
+	// acceleratorCounter looks up the number of GPUs and inference accelerators of an instance type with
+	// DescribeInstanceTypes. When nil, the GPU counts of the limits tables are used.
+	acceleratorCounter func(instanceType string) (int, error)
+	// acceleratorCount caches the result of acceleratorCounter for the instance type of the node.
+	acceleratorCount acceleratorCountCache
 	csi.UnimplementedNodeServer
 }
 
 // NewNodeService creates a new node service.
 func NewNodeService(o *Options, md metadata.MetadataService, m mounter.Mounter, k kubernetes.Interface) *NodeService {
+	d := &NodeService{
+		metadata:            md,
+		mounter:             m,
+		inFlight:            internal.NewInFlight(),
+		options:             o,
+		volumeLimitProvider: limits.DefaultVolumeLimitProvider(),
+	}
+
 	if k != nil {
+		broadcaster := record.NewBroadcaster()
+		broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: k.CoreV1().Events("")})
+		d.eventRecorder = broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "ebs-csi-node"})
+		d.kubeClient = k
+	}
+	metrics.Recorder().SetGaugeFunc(metrics.NodeAvailableAttachmentSlots, metrics.NodeAvailableAttachmentSlotsHelpText,
+		[]string{"instance_type", "node"}, d.availableAttachmentSlots)
+
+	return d
+}
+
+// start starts the goroutines of the node service. They compute the volume limit, so they are only started once
+// the node service is fully configured.
+func (d *NodeService) start() {
+	if d.kubeClient != nil {
 		// Watch for the agent‑not‑ready taint for up to one minute and remove it
 		// as soon as allocatable is available.
-		go startNotReadyTaintWatcher(k, taintWatcherDuration)
+		go startNotReadyTaintWatcher(d.kubeClient, taintWatcherDuration)
+		if d.options.ReconcileCSINodeAllocatable {
+			go d.startCSINodeAllocatableReconciler(d.kubeClient)
+		}
+	}
+	if d.options.DebugVolumeLimitsEndpoint != "" {
+		go d.startVolumeLimitsDebugServer(d.options.DebugVolumeLimitsEndpoint)
+	}
+}
+
+// acceleratorCountCache holds the accelerator count of the instance type it was last looked up for.
+type acceleratorCountCache struct {
+	mux          sync.Mutex
+	instanceType string
+	count        int
+	// found is false when the lookup for instanceType failed
+	found bool
+}
+
+// getAcceleratorCount returns the number of GPUs and inference accelerators of instanceType as reported by
+// DescribeInstanceTypes, or false when they are not looked up or the lookup failed. The result is kept until
+// the instance type of the node changes, for example when the instance is resized.
+func (d *NodeService) getAcceleratorCount(instanceType string) (int, bool) {
+	if d.acceleratorCounter == nil {
+		return 0, false
 	}
 
-	return &NodeService{
-		metadata: md,
-		mounter:  m,
-		inFlight: internal.NewInFlight(),
-		options:  o,
+	d.acceleratorCount.mux.Lock()
+	defer d.acceleratorCount.mux.Unlock()
+	if d.acceleratorCount.instanceType != instanceType {
+		count, err := d.acceleratorCounter(instanceType)
+		if err != nil {
+			klog.ErrorS(err, "Failed to describe the accelerators of the instance type, falling back to the GPU counts of the limits tables", "instanceType", instanceType)
+		} else {
+			klog.V(4).InfoS("Described the accelerators of the instance type", "instanceType", instanceType, "accelerators", count)
+		}
+		d.acceleratorCount.instanceType = instanceType
+		d.acceleratorCount.count = count
+		d.acceleratorCount.found = err == nil
 	}
+	return d.acceleratorCount.count, d.acceleratorCount.found
+}
+
+// WithVolumeLimitProvider replaces the provider used to look up the volume limits of instance types.
+// It must be called before the driver runs.
+func (d *NodeService) WithVolumeLimitProvider(p limits.VolumeLimitProvider) *NodeService {
+	d.volumeLimitProvider = p
+	return d
 }
 
 func (d *NodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
@@ -137,8 +263,32 @@ func (d *NodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.InvalidArgument, "Volume Attribute is not valid")
 	}
 
-	// If the access type is block, do nothing for stage
+	partition := ""
+	if part, ok := volumeContext[VolumeAttributePartition]; ok {
+		if part != "0" {
+			partition = part
+		} else {
+			klog.InfoS("NodeStageVolume: invalid partition config, will ignore.", "partition", part)
+		}
+	}
+
+	effectiveVolumeID := volumeID
+	if isNodeLocalVolume(volumeID) {
+		if realVolumeID, ok := req.GetPublishContext()[VolumeIDKey]; ok && realVolumeID != "" {
+			effectiveVolumeID = realVolumeID
+		}
+	}
+
+	// If the access type is block, the device is bind mounted as-is by NodePublishVolume, so
+	// nothing is formatted or mounted at the staging path. Only check that the device is present.
 	if _, isAccessTypeBlock := volCap.GetAccessType().(*csi.VolumeCapability_Block); isAccessTypeBlock {
+		if devicePath, ok := req.GetPublishContext()[DevicePathKey]; ok {
+			source, err := d.findDevicePath(ctx, devicePath, effectiveVolumeID, partition, d.deviceWaitTimeout(volumeContext))
+			if err != nil {
+				return nil, status.Errorf(codes.NotFound, "Failed to find device path %s. %v", devicePath, err)
+			}
+			klog.V(4).InfoS("NodeStageVolume [block]: find device path", "devicePath", devicePath, "source", source)
+		}
 		return &csi.NodeStageVolumeResponse{}, nil
 	}
 
@@ -203,23 +353,7 @@ func (d *NodeService) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		return nil, status.Error(codes.InvalidArgument, "Device path not provided")
 	}
 
-	partition := ""
-	if part, ok := volumeContext[VolumeAttributePartition]; ok {
-		if part != "0" {
-			partition = part
-		} else {
-			klog.InfoS("NodeStageVolume: invalid partition config, will ignore.", "partition", part)
-		}
-	}
-
-	effectiveVolumeID := volumeID
-	if isNodeLocalVolume(volumeID) {
-		if realVolumeID, ok := req.GetPublishContext()[VolumeIDKey]; ok && realVolumeID != "" {
-			effectiveVolumeID = realVolumeID
-		}
-	}
-
-	source, err := d.mounter.FindDevicePath(devicePath, effectiveVolumeID, partition, d.metadata.GetRegion())
+	source, err := d.findDevicePath(ctx, devicePath, effectiveVolumeID, partition, d.deviceWaitTimeout(volumeContext))
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "Failed to find device path %s. %v", devicePath, err)
 	}
@@ -617,6 +751,9 @@ func (d *NodeService) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 		WellKnownZoneTopologyKey: zone,
 		OSTopologyKey:            osType,
 	}
+	if d.options.EnableRegionTopology {
+		segments[WellKnownRegionTopologyKey] = d.metadata.GetRegion()
+	}
 
 	outpostArn := d.metadata.GetOutpostArn()
 
@@ -632,6 +769,16 @@ func (d *NodeService) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 		maps.Copy(segments, p.GetNodeTopologySegments())
 	}
 
+	// The limit is derived from the instance type, which the metadata source may not know yet shortly after the node
+	// started, for example before the instance type label is added to the Node. Report no limit rather than the
+	// default limit of an unknown instance type, which is too high for many instance types
+	if d.options.VolumeAttachLimit < 0 && d.metadata.GetInstanceType() == "" {
+		if err := d.waitForInstanceType(ctx); err != nil {
+			d.recordNodeWarning(volumeLimitUnknownReason, fmt.Sprintf("Instance type of the node could not be determined from the metadata source, the volume attach limit is not reported: %v", err))
+			return nil, status.Errorf(codes.Unavailable, "Instance type of the node is not known yet, cannot compute the volume attach limit: %v", err)
+		}
+	}
+
 	topology := &csi.Topology{Segments: segments}
 	maxVolumesPerNode := d.getVolumesLimit()
 	klog.V(4).InfoS("NodeGetInfo:", "maxVolumesPerNode", maxVolumesPerNode)
@@ -810,40 +957,392 @@ func (d *NodeService) nodePublishVolumeForFileSystem(req *csi.NodePublishVolumeR
 	return nil
 }
 
+// deviceWaitTimeout returns how long to wait for the device of a volume to appear.
+// The timeout grows with the size of volumes restored from snapshots, whose devices may take longer to show up.
+func (d *NodeService) deviceWaitTimeout(volumeContext map[string]string) time.Duration {
+	timeout := d.options.DeviceWaitBaseTimeout
+	if sizeGiB, err := strconv.ParseInt(volumeContext[VolumeAttributeSizeGiB], 10, 64); err == nil && sizeGiB > 0 {
+		timeout += time.Duration(sizeGiB) * d.options.DeviceWaitTimeoutPerGiB
+	}
+	return timeout
+}
+
+// waitForInstanceType refreshes the metadata until the instance type is known or instanceTypeWaitBackoff is exhausted.
+func (d *NodeService) waitForInstanceType(ctx context.Context) error {
+	klog.InfoS("NodeGetInfo: waiting for the instance type of the node to be known")
+	return wait.ExponentialBackoffWithContext(ctx, instanceTypeWaitBackoff, func(context.Context) (bool, error) {
+		if err := d.metadata.UpdateMetadata(); err != nil {
+			klog.V(4).InfoS("NodeGetInfo: failed to update metadata", "err", err)
+		}
+		return d.metadata.GetInstanceType() != "", nil
+	})
+}
+
+// findDevicePath looks up the device of a volume, retrying until it appears or the timeout expires.
+func (d *NodeService) findDevicePath(ctx context.Context, devicePath, volumeID, partition string, timeout time.Duration) (string, error) {
+	region := d.metadata.GetRegion()
+	source, err := d.mounter.FindDevicePath(devicePath, volumeID, partition, region)
+	if err == nil || timeout <= 0 {
+		return source, err
+	}
+
+	klog.V(4).InfoS("Waiting for device to appear", "devicePath", devicePath, "volumeID", volumeID, "timeout", timeout)
+	pollErr := wait.PollUntilContextTimeout(ctx, deviceWaitInterval, timeout, false, func(context.Context) (bool, error) {
+		source, err = d.mounter.FindDevicePath(devicePath, volumeID, partition, region)
+		return err == nil, nil
+	})
+	if pollErr != nil {
+		return "", err
+	}
+	return source, nil
+}
+
+// checkNVMeHealth returns an error if attached EBS volumes could not be resolved to their NVMe devices,
+// because the NVMe driver is not loaded or udev did not create the links to the devices.
+// Instances that are not built on Nitro attach EBS volumes as Xen devices and are always healthy.
+func (d *NodeService) checkNVMeHealth() error {
+	// Instance types that cannot be told apart are checked, like Nitro instance types
+	if nitro, err := limits.NitroInstanceType(d.metadata.GetInstanceType()); err == nil && !nitro {
+		return nil
+	}
+	for _, path := range []string{nvmeClassPath, diskByIDPath} {
+		exists, err := d.mounter.PathExists(path)
+		if err != nil {
+			return fmt.Errorf("failed to check if %q exists: %w", path, err)
+		}
+		if !exists {
+			return fmt.Errorf("%q does not exist", path)
+		}
+	}
+	return nil
+}
+
+// countInstanceStoreVolumes returns the number of instance store volumes attached to the node.
+func (d *NodeService) countInstanceStoreVolumes() (int, error) {
+	if d.instanceStoreVolumeCounter != nil {
+		return d.instanceStoreVolumeCounter()
+	}
+	return countNVMeControllers(nvmeClassPath, instanceStoreModel)
+}
+
+// countNVMeControllers counts the NVMe controllers in nvmeClassDir whose model is model.
+func countNVMeControllers(nvmeClassDir, model string) (int, error) {
+	controllers, err := os.ReadDir(nvmeClassDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list NVMe controllers: %w", err)
+	}
+	count := 0
+	for _, controller := range controllers {
+		controllerModel, err := os.ReadFile(filepath.Join(nvmeClassDir, controller.Name(), "model"))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read model of NVMe controller %q: %w", controller.Name(), err)
+		}
+		if strings.TrimSpace(string(controllerModel)) == model {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// reportedVolumeLimit is a volume attach limit reported to Kubernetes.
+type reportedVolumeLimit struct {
+	// instanceType is the instance type the limit was computed for.
+	instanceType string
+	limit        int64
+	// reservedVolumes are the EBS volumes attached outside the driver, which the limit does not count.
+	reservedVolumes int
+}
+
+// availableAttachmentSlots returns the number of volumes the driver can still attach to the node, computed from
+// the limit reported to Kubernetes minus the EBS volumes attached to the node that are not reserved. It returns
+// false before a limit was reported, or when the attached volumes cannot be counted.
+func (d *NodeService) availableAttachmentSlots() (float64, []string, bool) {
+	reported := d.reportedLimit.Load()
+	if reported == nil {
+		return 0, nil, false
+	}
+	attached, err := d.countEBSVolumes()
+	if err != nil {
+		klog.V(4).InfoS("Could not count the EBS volumes attached to the node, not reporting available attachment slots", "err", err)
+		return 0, nil, false
+	}
+	driverVolumes := max(attached-reported.reservedVolumes, 0)
+	return float64(reported.limit - int64(driverVolumes)), []string{reported.instanceType, os.Getenv("CSI_NODE_NAME")}, true
+}
+
+// countEBSVolumes returns the number of EBS volumes attached to the node, including the root volume.
+func (d *NodeService) countEBSVolumes() (int, error) {
+	if d.ebsVolumeCounter != nil {
+		return d.ebsVolumeCounter()
+	}
+	return countNVMeControllers(nvmeClassPath, ebsModel)
+}
+
+// reservedVolumeAttachments returns the number of EBS volumes attached to the node outside the driver, from
+// --reserved-volume-attachments or the block device mappings of the instance.
+func (d *NodeService) reservedVolumeAttachments() int {
+	if d.options.ReservedVolumeAttachments != -1 {
+		return d.options.ReservedVolumeAttachments
+	}
+	// Auto-detect number of reserved volume attachments - plus 1 to account for the root volume
+	return d.metadata.GetNumBlockDeviceMappings() + 1
+}
+
+// volumeLimitResolution describes how the volume attach limit of the node was resolved.
+type volumeLimitResolution struct {
+	// InstanceType is the instance type of the node, empty if the limit was set by an option.
+	InstanceType string `json:"instanceType"`
+	// Source is the option or limits table the limit was taken from.
+	Source string `json:"source"`
+	// Overrides are the options that changed the limit of the instance type in the limits tables.
+	Overrides []string `json:"overrides,omitempty"`
+	// MaxAttachments is the attachment limit of the instance type.
+	MaxAttachments int `json:"maxAttachments"`
+	// AttachmentType is either "shared" or "dedicated".
+	AttachmentType string `json:"attachmentType,omitempty"`
+	// ReservedSlots are the attachment slots the driver cannot attach volumes to.
+	ReservedSlots reservedSlots `json:"reservedSlots"`
+	// Limit is the volume attach limit reported by the node.
+	Limit int64 `json:"limit"`
+}
+
+// reservedSlots breaks down the attachment slots of the instance type that are not available to the driver.
+type reservedSlots struct {
+	// Volumes are the root volume and the other volumes attached at boot, or --reserved-volume-attachments.
+	Volumes int `json:"volumes"`
+	// DeviceNames are the --reserved-device-names.
+	DeviceNames int `json:"deviceNames"`
+	// InstanceStore is the difference between the instance store volumes that take up attachments on the node
+	// and the ones the limits tables already exclude.
+	InstanceStore int `json:"instanceStore"`
+	// Accelerators is the difference between the GPUs and inference accelerators reported by DescribeInstanceTypes
+	// and the GPUs the limits tables already exclude.
+	Accelerators int `json:"accelerators"`
+	// ENIs are the ENIs beyond the primary ENI, only on instance types with a shared attachment limit.
+	ENIs int `json:"enis"`
+	// FutureENIs are the --reserved-eni-slots, only on instance types with a shared attachment limit.
+	FutureENIs int `json:"futureENIs"`
+	// Total is the sum of all reserved slots.
+	Total int `json:"total"`
+}
+
 // getVolumesLimit returns the limit of volumes that the node supports.
 func (d *NodeService) getVolumesLimit() int64 {
+	resolution := d.resolveVolumesLimit()
+	if resolution.Source == "unrecognized" {
+		d.recordNodeWarning(volumeLimitFallbackReason, fmt.Sprintf("Instance type %q is not recognized, reporting the default volume attach limit of %d", resolution.InstanceType, resolution.Limit))
+	}
+	recordReservedSlots(resolution)
+	d.recordReportedLimit(resolution)
+	return resolution.Limit
+}
+
+// recordReportedLimit keeps the limit the available attachment slots metric is computed from.
+func (d *NodeService) recordReportedLimit(resolution volumeLimitResolution) {
+	// A node that reports no attachments has no slots to report
+	if metrics.Recorder() == nil || resolution.Limit == 0 {
+		return
+	}
+	reported := &reportedVolumeLimit{
+		instanceType:    resolution.InstanceType,
+		limit:           resolution.Limit,
+		reservedVolumes: resolution.ReservedSlots.Volumes + resolution.ReservedSlots.DeviceNames,
+	}
+	// Limits set by an option are not computed from the slots of the instance type
+	if resolution.MaxAttachments == 0 {
+		reported.instanceType = d.metadata.GetInstanceType()
+		reported.reservedVolumes = d.reservedVolumeAttachments() + len(d.options.ReservedDeviceNames)
+	}
+	d.reportedLimit.Store(reported)
+}
+
+// recordReservedSlots emits the attachment slots of the instance type that are not available to the driver,
+// by category. Unlike resolution.ReservedSlots, the instance store volumes and GPUs include the ones that
+// the limits tables already exclude from the limit of shared instance types.
+func recordReservedSlots(resolution volumeLimitResolution) {
+	r := metrics.Recorder()
+	// Limits set by an option are not computed from the slots of the instance type
+	if r == nil || resolution.MaxAttachments == 0 {
+		return
+	}
+
+	instanceStore, gpus := 0, 0
+	if resolution.AttachmentType == util.AttachmentShared {
+		instanceStore = limits.GetInstanceStoreVolumeCount(resolution.InstanceType) + resolution.ReservedSlots.InstanceStore
+		gpus = limits.GetGPUCount(resolution.InstanceType) + resolution.ReservedSlots.Accelerators
+	}
+	nodeName := os.Getenv("CSI_NODE_NAME")
+	for category, slots := range map[string]int{
+		"boot_volume":    resolution.ReservedSlots.Volumes,
+		"device_names":   resolution.ReservedSlots.DeviceNames,
+		"instance_store": instanceStore,
+		"gpu":            gpus,
+		"eni":            resolution.ReservedSlots.ENIs + resolution.ReservedSlots.FutureENIs,
+	} {
+		r.SetGauge(metrics.NodeReservedSlots, metrics.NodeReservedSlotsHelpText, float64(slots), map[string]string{
+			"category":      category,
+			"instance_type": resolution.InstanceType,
+			"node":          nodeName,
+		})
+	}
+}
+
+// recordNodeWarning records a Warning event on the Node object of the node.
+func (d *NodeService) recordNodeWarning(reason, message string) {
+	if d.eventRecorder == nil {
+		return
+	}
+	nodeName := os.Getenv("CSI_NODE_NAME")
+	if nodeName == "" {
+		klog.V(4).InfoS("CSI_NODE_NAME missing, skipping event", "reason", reason)
+		return
+	}
+	// The kubelet records Node events with the node name as UID, so they are listed by kubectl describe node
+	node := &corev1.ObjectReference{Kind: "Node", Name: nodeName, UID: k8stypes.UID(nodeName)}
+	d.eventRecorder.Event(node, corev1.EventTypeWarning, reason, message)
+}
+
+// resolveVolumesLimit computes the limit of volumes that the node supports and how it was derived.
+func (d *NodeService) resolveVolumesLimit() volumeLimitResolution {
+	// Kubernetes treats a limit of 0 as unlimited, so 1 is the lowest limit that keeps volumes off the node
+	if len(d.options.UnsupportedInstanceTypes) > 0 {
+		if instanceType := d.metadata.GetInstanceType(); slices.Contains(d.options.UnsupportedInstanceTypes, limits.NormalizeInstanceType(instanceType)) {
+			klog.InfoS("getVolumesLimit: instance type is in --unsupported-instance-types, reporting a limit of 1", "instanceType", instanceType)
+			return volumeLimitResolution{InstanceType: instanceType, Source: "unsupported-instance-types", Limit: 1}
+		}
+	}
+
 	if d.options.VolumeAttachLimit >= 0 {
 		klog.V(4).InfoS("getVolumesLimit: VolumeAttachLimit manually set to", d.options.VolumeAttachLimit, "overriding the default value")
-		return d.options.VolumeAttachLimit
+		return volumeLimitResolution{Source: "volume-attach-limit", Limit: d.options.VolumeAttachLimit}
+	}
+
+	if d.options.OutpostVolumeAttachLimit > 0 && len(d.metadata.GetOutpostArn().Resource) > 0 {
+		klog.V(4).InfoS("getVolumesLimit: running on an Outpost, using OutpostVolumeAttachLimit", "limit", d.options.OutpostVolumeAttachLimit)
+		return volumeLimitResolution{Source: "outpost-volume-attach-limit", Limit: d.options.OutpostVolumeAttachLimit}
 	}
 
 	instanceType := d.metadata.GetInstanceType()
-	availableAttachments, limitType := limits.GetVolumeLimits(instanceType)
-	klog.V(4).InfoS("getVolumesLimit: Retrieved inputs", "instanceType", instanceType, "attachmentLimit", availableAttachments, "limitType", limitType)
+	limitProvider := d.volumeLimitProvider
+	if limitProvider == nil {
+		limitProvider = limits.DefaultVolumeLimitProvider()
+	}
+	resolution := volumeLimitResolution{InstanceType: instanceType, Source: limits.ProviderLimitSource(limitProvider, instanceType)}
+	if (d.options.NonNitroMaxAttachments > 0 && d.options.NonNitroMaxAttachments != limits.NonNitroMaxAttachments) ||
+		(d.options.NitroMaxAttachments > 0 && d.options.NitroMaxAttachments != limits.NitroMaxAttachments) {
+		limitProvider = limits.WithMaxAttachments(limitProvider, d.options.NonNitroMaxAttachments, d.options.NitroMaxAttachments)
+		resolution.Overrides = append(resolution.Overrides, "non-nitro-max-attachments", "nitro-max-attachments")
+	}
+	if d.options.UnknownInstanceFamilyMaxAttachments > 0 && resolution.Source == "default" && !limits.IsKnownInstanceFamily(instanceType) {
+		klog.InfoS("getVolumesLimit: instance family has no limits in the driver, using --unknown-instance-family-max-attachments",
+			"instanceType", instanceType, "maxAttachments", d.options.UnknownInstanceFamilyMaxAttachments)
+		limitProvider = limits.WithUnknownFamilyLimit(limitProvider, d.options.UnknownInstanceFamilyMaxAttachments)
+		resolution.Overrides = append(resolution.Overrides, "unknown-instance-family-max-attachments")
+	}
+	if len(d.options.SharedLimitInstanceFamilies) > 0 {
+		limitProvider = limits.WithSharedFamilies(limitProvider, d.options.SharedLimitInstanceFamilies)
+		resolution.Overrides = append(resolution.Overrides, "shared-limit-instance-families")
+	}
 
 	// Calculate reserved volume attachments (additional EBS volumes)
-	reservedVolumeAttachments := d.options.ReservedVolumeAttachments
-	if reservedVolumeAttachments == -1 {
-		// Auto-detect number of reserved volume attachments - plus 1 to account for the root volume
-		reservedVolumeAttachments = d.metadata.GetNumBlockDeviceMappings() + 1
+	reservedVolumeAttachments := d.reservedVolumeAttachments()
+	resolution.ReservedSlots.Volumes = reservedVolumeAttachments
+	// Device names reserved for volumes attached outside the driver take up a slot each
+	resolution.ReservedSlots.DeviceNames = len(d.options.ReservedDeviceNames)
+	reservedVolumeAttachments += resolution.ReservedSlots.DeviceNames
+
+	// ENIs only consume attachment slots on shared attachment types
+	enis := 0
+	if !limitProvider.HasDedicatedEBSLimit(instanceType) {
+		enis = d.metadata.GetNumAttachedENIs()
+		// Keep slots free for the ENIs that are not attached yet
+		resolution.ReservedSlots.FutureENIs = d.options.ReservedENISlots
+		reservedVolumeAttachments += resolution.ReservedSlots.FutureENIs
+	}
+
+	// The limits of shared instance types already exclude the instance store volumes of the instance type,
+	// adjust the reserved attachments when a different number of them takes up attachments on this node
+	if !limitProvider.HasDedicatedEBSLimit(instanceType) {
+		instanceStoreVolumes := limits.GetInstanceStoreVolumeCount(instanceType)
+		countedVolumes := instanceStoreVolumes
+		switch {
+		case d.options.ReleaseInstanceStoreSlots:
+			countedVolumes = 0
+		case d.options.DetectInstanceStoreVolumes && instanceStoreVolumes > 0:
+			detectedVolumes, err := d.countInstanceStoreVolumes()
+			if err != nil {
+				klog.InfoS("getVolumesLimit: could not detect instance store volumes, assuming all instance store volumes of the instance type are attached",
+					"instanceType", instanceType, "instanceStoreVolumes", instanceStoreVolumes, "err", err)
+			} else {
+				countedVolumes = detectedVolumes
+			}
+		}
+		if countedVolumes != instanceStoreVolumes {
+			resolution.ReservedSlots.InstanceStore = countedVolumes - instanceStoreVolumes
+			reservedVolumeAttachments += resolution.ReservedSlots.InstanceStore
+			klog.V(4).InfoS("getVolumesLimit: adjusting for instance store volumes", "instanceType", instanceType, "instanceStoreVolumes", instanceStoreVolumes, "countedVolumes", countedVolumes)
+		}
+
+		// The limits of shared instance types with a GPU count already exclude the GPUs, adjust the reserved
+		// attachments when DescribeInstanceTypes reports a different number of accelerators. Other instance types
+		// in the tables exclude their accelerators from their limit too, but the driver does not know how many.
+		// Fewer accelerators than the GPU count never raise the limit above the one of the tables.
+		if accelerators, found := d.getAcceleratorCount(instanceType); found {
+			gpus, known := limits.InstanceTypeHasGPUs(instanceType)
+			if known || resolution.Source == "default" {
+				resolution.ReservedSlots.Accelerators = max(accelerators-gpus, 0)
+				reservedVolumeAttachments += resolution.ReservedSlots.Accelerators
+			}
+		}
 	}
-	klog.V(4).InfoS("getVolumesLimit: Removing reserved attachments", "reservedVolumeAttachments", reservedVolumeAttachments)
-	availableAttachments -= reservedVolumeAttachments
 
-	// For shared attachment types, subtract ENIs
-	if limitType == util.AttachmentShared {
-		enis := d.metadata.GetNumAttachedENIs()
-		klog.V(4).InfoS("getVolumesLimit: Removing ENIs on shared limit", "enis", enis)
-		availableAttachments -= (enis - 1)
+	volumeLimit := limits.GetVolumeLimitFromProvider(limitProvider, instanceType, reservedVolumeAttachments, enis)
+	klog.V(4).InfoS("getVolumesLimit: Retrieved inputs", "instanceType", instanceType, "attachmentLimit", volumeLimit.MaxAttachments, "limitType", volumeLimit.AttachmentType,
+		"reservedVolumeAttachments", volumeLimit.ReservedAttachments, "enis", enis)
+
+	klog.V(4).InfoS("getVolumesLimit: Returning calculated limit", "availableAttachments", volumeLimit.Limit)
+	resolution.MaxAttachments = volumeLimit.MaxAttachments
+	resolution.AttachmentType = volumeLimit.AttachmentType
+	resolution.ReservedSlots.ENIs = volumeLimit.ENIAttachments
+	resolution.ReservedSlots.Total = volumeLimit.ReservedSlots()
+	resolution.Limit = int64(volumeLimit.Limit)
+	if d.options.MaxAdvertisedAttachments > 0 && resolution.Limit > d.options.MaxAdvertisedAttachments {
+		klog.V(4).InfoS("getVolumesLimit: capping limit at --max-advertised-attachments", "limit", resolution.Limit, "maxAdvertisedAttachments", d.options.MaxAdvertisedAttachments)
+		resolution.Limit = d.options.MaxAdvertisedAttachments
+		resolution.Overrides = append(resolution.Overrides, "max-advertised-attachments")
 	}
+	return resolution
+}
+
+// volumeLimitsDebugHandler serves the resolution of the volume attach limit of the node as JSON.
+func (d *NodeService) volumeLimitsDebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.resolveVolumesLimit()); err != nil {
+			klog.ErrorS(err, "Failed to write volume limits debug response")
+		}
+	})
+}
 
-	// Safety measure: Never return a limit of below 1, as Kubernetes will treat it as infinite
-	if availableAttachments <= 0 {
-		availableAttachments = 1
+// startVolumeLimitsDebugServer serves volumeLimitsDebugHandler at /debug/volume-limits on address.
+func (d *NodeService) startVolumeLimitsDebugServer(address string) {
+	mux := http.NewServeMux()
+	mux.Handle(volumeLimitsDebugPath, d.volumeLimitsDebugHandler())
+	server := &http.Server{
+		Addr:        address,
+		Handler:     mux,
+		ReadTimeout: 3 * time.Second,
 	}
 
-	klog.V(4).InfoS("getVolumesLimit: Returning calculated limit", "availableAttachments", availableAttachments)
-	return int64(availableAttachments)
+	klog.InfoS("Volume limits debug server listening", "address", address, "path", volumeLimitsDebugPath)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		klog.ErrorS(err, "Failed to start volume limits debug server", "address", address)
+	}
 }
 
 // hasMountOption returns a boolean indicating whether the given
@@ -1090,6 +1589,79 @@ func checkAllocatable(ctx context.Context, clientset kubernetes.Interface, nodeN
 	return fmt.Errorf("isAllocatableSet: driver not found on node %s", nodeName)
 }
 
+// startCSINodeAllocatableReconciler waits for kubelet to register the driver in the CSINode of the local node
+// and then overwrites its allocatable volume count with the limit computed by this version of the driver.
+// kubelet keeps the count it got when the driver was registered first, so a limit that changed with an
+// upgrade of the driver would otherwise only take effect once the node is recreated.
+func (d *NodeService) startCSINodeAllocatableReconciler(clientset kubernetes.Interface) {
+	nodeName := os.Getenv("CSI_NODE_NAME")
+	if nodeName == "" {
+		klog.V(4).InfoS("CSI_NODE_NAME missing, skipping CSINode allocatable reconciliation")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), csiNodeReconcileTimeout)
+	defer cancel()
+	err := wait.PollUntilContextCancel(ctx, csiNodeReconcileInterval, true, func(ctx context.Context) (bool, error) {
+		//nolint:gosec // Volume limits are far below MaxInt32
+		reconciled, err := reconcileCSINodeAllocatable(ctx, clientset, nodeName, int32(d.getVolumesLimit()))
+		if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+			return false, err
+		}
+		if err != nil {
+			klog.V(4).InfoS("Failed to reconcile CSINode allocatable count, retrying", "node", nodeName, "err", err)
+		}
+		return reconciled, nil
+	})
+	if err != nil {
+		klog.ErrorS(err, "Failed to reconcile CSINode allocatable count", "node", nodeName)
+	}
+}
+
+// reconcileCSINodeAllocatable sets the allocatable volume count of the driver in the CSINode of nodeName to count,
+// even if it already is count. It returns false if the driver is not registered in the CSINode yet.
+func reconcileCSINodeAllocatable(ctx context.Context, clientset kubernetes.Interface, nodeName string, count int32) (bool, error) {
+	csiNode, err := clientset.StorageV1().CSINodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get CSINode for %s: %w", nodeName, err)
+	}
+
+	for i, driver := range csiNode.Spec.Drivers {
+		if driver.Name != util.GetDriverName() {
+			continue
+		}
+
+		var previousCount any
+		if driver.Allocatable != nil && driver.Allocatable.Count != nil {
+			previousCount = *driver.Allocatable.Count
+		}
+		driverPath := fmt.Sprintf("/spec/drivers/%d", i)
+		patch, err := json.Marshal([]JSONPatch{
+			{
+				OP:    "test",
+				Path:  driverPath + "/name",
+				Value: driver.Name,
+			},
+			{
+				OP:    "add",
+				Path:  driverPath + "/allocatable",
+				Value: storagev1.VolumeNodeResources{Count: &count},
+			},
+		})
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := clientset.StorageV1().CSINodes().Patch(ctx, nodeName, k8stypes.JSONPatchType, patch, metav1.PatchOptions{}); err != nil {
+			return false, fmt.Errorf("failed to patch CSINode for %s: %w", nodeName, err)
+		}
+		klog.InfoS("Reconciled allocatable volume count of CSINode", "node", nodeName, "count", count, "previousCount", previousCount)
+		return true, nil
+	}
+
+	return false, nil
+}
+
 func recheckFormattingOptionParameter(context map[string]string, key string, fsConfigs map[string]fileSystemConfig, fsType string) (value string, err error) {
 	v, ok := context[key]
 	if ok {
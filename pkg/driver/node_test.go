@@ -20,19 +20,26 @@ package driver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/driver/internal"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/metrics"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/mounter"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/plugin"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/util"
@@ -44,6 +51,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/testutil"
 )
 
 func TestNewNodeService(t *testing.T) {
@@ -120,6 +129,46 @@ func TestNodeStageVolume(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "success_device_appears_after_wait",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          "vol-test",
+				StagingTargetPath: "/staging/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							FsType: "ext4",
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				PublishContext: map[string]string{DevicePathKey: "/dev/xvdba"},
+				VolumeContext:  map[string]string{VolumeAttributeSizeGiB: "100"},
+			},
+			options: &Options{
+				DeviceWaitTimeoutPerGiB: 100 * time.Millisecond,
+			},
+			mounterMock: func(ctrl *gomock.Controller) *mounter.MockMounter {
+				m := mounter.NewMockMounter(ctrl)
+				gomock.InOrder(
+					m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("", errors.New("device not found")),
+					m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("/dev/xvdba", nil),
+				)
+				m.EXPECT().PathExists(gomock.Eq("/staging/path")).Return(true, nil)
+				m.EXPECT().GetDeviceNameFromMount(gomock.Eq("/staging/path")).Return("", 1, nil)
+				m.EXPECT().FormatAndMountSensitiveWithFormatOptions(gomock.Eq("/dev/xvdba"), gomock.Eq("/staging/path"), gomock.Eq("ext4"), gomock.Nil(), gomock.Nil(), gomock.Eq([]string{})).Return(nil)
+				m.EXPECT().NeedResize(gomock.Eq("/dev/xvdba"), gomock.Eq("/staging/path")).Return(false, nil)
+				return m
+			},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetRegion().Return("us-west-2")
+				return m
+			},
+			expectedErr: nil,
+		},
 		{
 			name: "missing_volume_id",
 			req: &csi.NodeStageVolumeRequest{
@@ -229,6 +278,61 @@ func TestNodeStageVolume(t *testing.T) {
 			metadataMock: nil,
 			expectedErr:  nil,
 		},
+		{
+			name: "block_volume_device_present",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          "vol-test",
+				StagingTargetPath: "/staging/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Block{
+						Block: &csi.VolumeCapability_BlockVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				PublishContext: map[string]string{DevicePathKey: "/dev/xvdba"},
+			},
+			mounterMock: func(ctrl *gomock.Controller) *mounter.MockMounter {
+				m := mounter.NewMockMounter(ctrl)
+				m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("/dev/nvme1n1", nil)
+				// No other calls are expected, so the staging path is never created, formatted or mounted
+				return m
+			},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetRegion().Return("us-west-2")
+				return m
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "block_volume_device_missing",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          "vol-test",
+				StagingTargetPath: "/staging/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Block{
+						Block: &csi.VolumeCapability_BlockVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				PublishContext: map[string]string{DevicePathKey: "/dev/xvdba"},
+			},
+			mounterMock: func(ctrl *gomock.Controller) *mounter.MockMounter {
+				m := mounter.NewMockMounter(ctrl)
+				m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("", errors.New("device not found"))
+				return m
+			},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetRegion().Return("us-west-2")
+				return m
+			},
+			expectedErr: status.Errorf(codes.NotFound, "Failed to find device path %s. %v", "/dev/xvdba", errors.New("device not found")),
+		},
 		{
 			name: "missing_mount_volume",
 			req: &csi.NodeStageVolumeRequest{
@@ -1140,6 +1244,111 @@ func TestNodeStageVolume(t *testing.T) {
 	}
 }
 
+func TestCheckNVMeHealth(t *testing.T) {
+	testCases := []struct {
+		name          string
+		instanceType  string
+		existingPaths map[string]bool
+		expectedErr   bool
+	}{
+		{
+			name:          "healthy",
+			instanceType:  "m5.large",
+			existingPaths: map[string]bool{nvmeClassPath: true, diskByIDPath: true},
+		},
+		{
+			name:          "NVMe driver not loaded",
+			instanceType:  "m5.large",
+			existingPaths: map[string]bool{nvmeClassPath: false},
+			expectedErr:   true,
+		},
+		{
+			name:          "by-id links missing",
+			instanceType:  "m5.large",
+			existingPaths: map[string]bool{nvmeClassPath: true, diskByIDPath: false},
+			expectedErr:   true,
+		},
+		{
+			name:         "non-Nitro instance type is not checked",
+			instanceType: "m4.large",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+
+			mockMetadata := metadata.NewMockMetadataService(ctrl)
+			mockMetadata.EXPECT().GetInstanceType().Return(tc.instanceType)
+			mockMounter := mounter.NewMockMounter(ctrl)
+			for path, exists := range tc.existingPaths {
+				mockMounter.EXPECT().PathExists(path).Return(exists, nil)
+			}
+
+			d := &Driver{
+				node: &NodeService{
+					metadata: mockMetadata,
+					mounter:  mockMounter,
+					options:  &Options{NVMeHealthCheck: true},
+				},
+			}
+			_, err := d.Probe(t.Context(), &csi.ProbeRequest{})
+			if tc.expectedErr {
+				checkExpectedErrorCode(t, err, codes.FailedPrecondition)
+			} else if err != nil {
+				t.Fatalf("Probe() failed: expected no error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestDeviceWaitTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       *Options
+		volumeContext map[string]string
+		expected      time.Duration
+	}{
+		{
+			name:     "disabled by default",
+			options:  &Options{},
+			expected: 0,
+		},
+		{
+			name:     "base timeout without size",
+			options:  &Options{DeviceWaitBaseTimeout: 10 * time.Second, DeviceWaitTimeoutPerGiB: time.Second},
+			expected: 10 * time.Second,
+		},
+		{
+			name:          "small volume",
+			options:       &Options{DeviceWaitBaseTimeout: 10 * time.Second, DeviceWaitTimeoutPerGiB: 100 * time.Millisecond},
+			volumeContext: map[string]string{VolumeAttributeSizeGiB: "10"},
+			expected:      11 * time.Second,
+		},
+		{
+			name:          "large volume",
+			options:       &Options{DeviceWaitBaseTimeout: 10 * time.Second, DeviceWaitTimeoutPerGiB: 100 * time.Millisecond},
+			volumeContext: map[string]string{VolumeAttributeSizeGiB: "1000"},
+			expected:      110 * time.Second,
+		},
+		{
+			name:          "invalid size",
+			options:       &Options{DeviceWaitBaseTimeout: 10 * time.Second, DeviceWaitTimeoutPerGiB: 100 * time.Millisecond},
+			volumeContext: map[string]string{VolumeAttributeSizeGiB: "large"},
+			expected:      10 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driver := &NodeService{options: tc.options}
+			if got := driver.deviceWaitTimeout(tc.volumeContext); got != tc.expected {
+				t.Fatalf("Expected timeout %v but got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestGetVolumesLimit(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -1157,288 +1366,949 @@ func TestGetVolumesLimit(t *testing.T) {
 			expectedVal: 10,
 		},
 		{
-			name: "t2.medium_volume_attach_limit",
+			name: "OutpostVolumeAttachLimit_on_outpost",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				OutpostVolumeAttachLimit:  12,
 			},
-			expectedVal: 38,
+			expectedVal: 12,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetInstanceType().Return("t2.medium")
+				m.EXPECT().GetOutpostArn().Return(arn.ARN{
+					Partition: "aws",
+					Service:   "outposts",
+					Region:    "us-west-2",
+					AccountID: "111111111111",
+					Resource:  "op-0aaa000a0aaaa00a0",
+				})
 				return m
 			},
 		},
 		{
-			name: "m5.large_volume_attach_limit",
+			name: "OutpostVolumeAttachLimit_not_on_outpost",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				OutpostVolumeAttachLimit:  12,
 			},
-			expectedVal: 27,
+			expectedVal: 38,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetOutpostArn().Return(arn.ARN{})
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetInstanceType().Return("m5.large")
-				m.EXPECT().GetNumAttachedENIs().Return(0)
+				m.EXPECT().GetInstanceType().Return("t2.medium")
 				return m
 			},
 		},
 		{
-			name: "ReservedVolumeAttachments_specified",
+			name: "unsupported_instance_type",
 			options: &Options{
-				VolumeAttachLimit:         -1,
-				ReservedVolumeAttachments: 3,
+				VolumeAttachLimit:         10,
+				ReservedVolumeAttachments: -1,
+				UnsupportedInstanceTypes:  []string{"i3.metal", "m5.metal"},
 			},
-			expectedVal: 36,
+			// A limit of 0 would be treated as unlimited
+			expectedVal: 1,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("t2.medium")
+				m.EXPECT().GetInstanceType().Return("m5.metal")
 				return m
 			},
 		},
 		{
-			name: "m5d.large_volume_attach_limit",
+			name: "unsupported_instance_type_not_normalized",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				UnsupportedInstanceTypes:  []string{"i3.metal", "m5.metal"},
 			},
-			expectedVal: 23,
+			expectedVal: 1,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("m5d.large")
-				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(3)
+				m.EXPECT().GetInstanceType().Return("M5.Metal\n")
 				return m
 			},
 		},
 		{
-			name: "d3en.12xlarge_volume_attach_limit",
+			name: "unsupported_instance_types_other_instance_type",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				UnsupportedInstanceTypes:  []string{"i3.metal", "m5.metal"},
 			},
-			expectedVal: 2,
+			expectedVal: 38,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("d3en.12xlarge")
+				m.EXPECT().GetInstanceType().Return("t2.medium").Times(2)
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
 				return m
 			},
 		},
 		{
-			name: "d3.8xlarge_volume_attach_limit",
+			name: "t2.medium_volume_attach_limit",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
 			},
-			expectedVal: 2,
+			expectedVal: 38,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("d3.8xlarge")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetInstanceType().Return("t2.medium")
 				return m
 			},
 		},
 		{
-			name: "nitro_volume_attach_limit",
+			name: "m5.large_volume_attach_limit",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
 			},
-			expectedVal: 127,
+			expectedVal: 27,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("m7i.48xlarge")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetInstanceType().Return("m5.large")
+				m.EXPECT().GetNumAttachedENIs().Return(0)
 				return m
 			},
 		},
 		{
-			name: "attached_max_enis",
+			name: "m5.large_reserves_root_and_block_device_mappings",
 			options: &Options{
-				VolumeAttachLimit: -1,
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
 			},
-			expectedVal: 1,
+			// 27 (shared limit) - 1 (root volume) - 2 (pre-attached volumes from the block device mapping)
+			expectedVal: 24,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("t3.xlarge")
-				m.EXPECT().GetNumAttachedENIs().Return(40)
+				m.EXPECT().GetNumBlockDeviceMappings().Return(2)
+				m.EXPECT().GetInstanceType().Return("m5.large")
+				m.EXPECT().GetNumAttachedENIs().Return(1)
 				return m
 			},
 		},
 		{
-			name: "mac1.metal_volume_attach_limit",
+			name: "ReservedVolumeAttachments_specified",
 			options: &Options{
 				VolumeAttachLimit:         -1,
-				ReservedVolumeAttachments: -1,
+				ReservedVolumeAttachments: 3,
 			},
-			expectedVal: 15,
+			expectedVal: 36,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("mac1.metal")
-				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetInstanceType().Return("t2.medium")
 				return m
 			},
 		},
 		{
-			name: "g4dn.xlarge_volume_attach_limit (1 GPU 1 InstanceStoreVolume)",
+			name: "ReservedVolumeAttachments_exceeds_limit",
 			options: &Options{
 				VolumeAttachLimit:         -1,
-				ReservedVolumeAttachments: -1,
+				ReservedVolumeAttachments: 50,
 			},
-			expectedVal: 24,
+			expectedVal: 1,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("g4dn.xlarge")
-				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetInstanceType().Return("t2.medium")
 				return m
 			},
 		},
 		{
-			name: "g4ad.xlarge_volume_attach_limit (1 GPU 1 InstanceStoreVolume)",
+			name: "m5d.large_reserved_device_names",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				ReservedDeviceNames:       []string{"/dev/xvdba", "/dev/xvdbb"},
 			},
-			expectedVal: 24,
+			expectedVal: 21,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("g4ad.xlarge")
+				m.EXPECT().GetInstanceType().Return("m5d.large")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetNumAttachedENIs().Return(3)
 				return m
 			},
 		},
 		{
-			name: "g4dn.12xlarge_volume_attach_limit (4 GPUS, 1 InstanceStoreVolume)",
+			name: "m5d.large_volume_attach_limit",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
 			},
-			expectedVal: 21,
+			expectedVal: 23,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("g4dn.12xlarge")
+				m.EXPECT().GetInstanceType().Return("m5d.large")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetNumAttachedENIs().Return(3)
 				return m
 			},
 		},
 		{
-			// will and should fail if g5.48xlarge instance type is in any table other than maxVolumeLimits table
-			name: "g5.48xlarge_volume_attach_limit (Instance has attached GPUs and NVMe Instance Store volumes but should be ignored for EBS volume limits calculation)",
+			name: "m7i.48xlarge_dedicated_limit_skips_eni_deduction",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
 			},
-			expectedVal: 8,
+			expectedVal: 127,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("g5.48xlarge")
+				m.EXPECT().GetInstanceType().Return("m7i.48xlarge")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
 				return m
 			},
 		},
 		{
-			// Should fail if inf1.xlarge instance type is in any table other than maxVolumeLimits table
-			name: "inf1.xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			name: "t2.medium_non_nitro_max_attachments",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				NonNitroMaxAttachments:    30,
+				NitroMaxAttachments:       27,
 			},
-			expectedVal: 25,
+			expectedVal: 29,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("inf1.xlarge")
+				m.EXPECT().GetInstanceType().Return("t2.medium")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
 				return m
 			},
 		},
 		{
-			// Should fail if inf1.xlarge instance type is in any table other than maxVolumeLimits table
-			name: "inf1.2xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			name: "m5.large_nitro_max_attachments",
 			options: &Options{
 				VolumeAttachLimit:         -1,
 				ReservedVolumeAttachments: -1,
+				NonNitroMaxAttachments:    39,
+				NitroMaxAttachments:       31,
 			},
-			expectedVal: 25,
+			// 31 - 1 root volume - 2 ENIs
+			expectedVal: 28,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("inf1.2xlarge")
+				m.EXPECT().GetInstanceType().Return("m5.large")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetNumAttachedENIs().Return(3)
 				return m
 			},
 		},
 		{
-			// Should fail if inf1.6xlarge instance type is in any table other than maxVolumeLimits table
-			name: "inf1.6xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			name: "m7i.large_shared_limit_instance_family_deducts_enis",
 			options: &Options{
-				VolumeAttachLimit:         -1,
-				ReservedVolumeAttachments: -1,
+				VolumeAttachLimit:           -1,
+				ReservedVolumeAttachments:   -1,
+				SharedLimitInstanceFamilies: []string{"c7i", "M7I"},
 			},
-			expectedVal: 22,
+			expectedVal: 29,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("inf1.6xlarge")
+				m.EXPECT().GetInstanceType().Return("m7i.large")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
-				m.EXPECT().GetNumAttachedENIs().Return(1)
+				m.EXPECT().GetNumAttachedENIs().Return(3)
 				return m
 			},
 		},
 		{
-			// Should fail if inf1.24xlarge instance type is in any table other than maxVolumeLimits table
-			name: "inf1.24xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			name: "m7i.large_shared_limit_instance_family_other_family",
 			options: &Options{
-				VolumeAttachLimit:         -1,
-				ReservedVolumeAttachments: -1,
+				VolumeAttachLimit:           -1,
+				ReservedVolumeAttachments:   -1,
+				SharedLimitInstanceFamilies: []string{"c7i"},
 			},
-			expectedVal: 10,
+			expectedVal: 31,
 			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
 				m := metadata.NewMockMetadataService(ctrl)
-				m.EXPECT().GetInstanceType().Return("inf1.24xlarge")
+				m.EXPECT().GetInstanceType().Return("m7i.large")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			name: "g4dn.metal_shared_limit_deducts_enis",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 28,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g4dn.metal")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(3)
+				return m
+			},
+		},
+		{
+			name: "d3en.12xlarge_volume_attach_limit",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 2,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("d3en.12xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "d3.8xlarge_volume_attach_limit",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 2,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("d3.8xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "nitro_volume_attach_limit",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 127,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("m7i.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			name: "attached_max_enis",
+			options: &Options{
+				VolumeAttachLimit: -1,
+			},
+			expectedVal: 1,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("t3.xlarge")
+				m.EXPECT().GetNumAttachedENIs().Return(40)
+				return m
+			},
+		},
+		{
+			name: "mac1.metal_volume_attach_limit",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 15,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("mac1.metal")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "i3.metal_volume_attach_limit (8 InstanceStoreVolumes)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			// 31 (bare metal) - 8 (instance store) - 1 (root volume)
+			expectedVal: 22,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("i3.metal")
 				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
 				m.EXPECT().GetNumAttachedENIs().Return(1)
 				return m
 			},
 		},
+		{
+			name: "g6e.48xlarge_volume_attach_limit (8 GPUs 4 InstanceStoreVolumes, dedicated)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 127,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g6e.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			name: "p6-b200.48xlarge_volume_attach_limit (8 GPUs 8 InstanceStoreVolumes, dedicated)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 63,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("p6-b200.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			name: "p6-b300.48xlarge_volume_attach_limit (8 GPUs, dedicated)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 63,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("p6-b300.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			name: "g4dn.xlarge_volume_attach_limit (1 GPU 1 InstanceStoreVolume)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 24,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g4dn.xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "g4ad.xlarge_volume_attach_limit (1 GPU 1 InstanceStoreVolume)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 24,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g4ad.xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "g4dn.12xlarge_volume_attach_limit (4 GPUS, 1 InstanceStoreVolume)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 21,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g4dn.12xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "g4dn.12xlarge_volume_attach_limit_instance_store_not_counted (4 GPUS, 1 InstanceStoreVolume)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				ReleaseInstanceStoreSlots: true,
+			},
+			// 22 (table limit) + 1 (instance store) - 1 (root volume)
+			expectedVal: 22,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g4dn.12xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			name: "g6e.48xlarge_volume_attach_limit_instance_store_not_counted (8 GPUs 4 InstanceStoreVolumes, dedicated)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+				ReleaseInstanceStoreSlots: true,
+			},
+			expectedVal: 127,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g6e.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				return m
+			},
+		},
+		{
+			// will and should fail if g5.48xlarge instance type is in any table other than maxVolumeLimits table
+			name: "g5.48xlarge_volume_attach_limit (Instance has attached GPUs and NVMe Instance Store volumes but should be ignored for EBS volume limits calculation)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 8,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("g5.48xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			// Should fail if inf1.xlarge instance type is in any table other than maxVolumeLimits table
+			name: "inf1.xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 25,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("inf1.xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			// Should fail if inf1.xlarge instance type is in any table other than maxVolumeLimits table
+			name: "inf1.2xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 25,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("inf1.2xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			// Should fail if inf1.6xlarge instance type is in any table other than maxVolumeLimits table
+			name: "inf1.6xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 22,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("inf1.6xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+		{
+			// Should fail if inf1.24xlarge instance type is in any table other than maxVolumeLimits table
+			name: "inf1.24xlarge_volume_attach_limit (Instance has attached Accelerators but should be ignored for EBS volume limits calculation)",
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+			expectedVal: 10,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("inf1.24xlarge")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+				return m
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var mounter *mounter.MockMounter
+
+			var metadata *metadata.MockMetadataService
+			if tc.metadataMock != nil {
+				metadata = tc.metadataMock(ctrl)
+			}
+
+			driver := &NodeService{
+				mounter:  mounter,
+				inFlight: internal.NewInFlight(),
+				options:  tc.options,
+				metadata: metadata,
+			}
+
+			value := driver.getVolumesLimit()
+			if value != tc.expectedVal {
+				t.Fatalf("Expected value %v but got %v", tc.expectedVal, value)
+			}
+		})
+	}
+}
+
+func TestGetVolumesLimitDetectedInstanceStoreVolumes(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		instanceType         string
+		releaseInstanceStore bool
+		detect               bool
+		detectedVolumes      int
+		detectErr            error
+		expectDetectionCall  bool
+		expectedVal          int64
+	}{
+		{
+			name:         "static count without detection",
+			instanceType: "g4dn.12xlarge",
+			// 22 (table limit without the 1 instance store volume) - 1 (root volume)
+			expectedVal: 21,
+		},
+		{
+			name:                "detected count matches static count",
+			instanceType:        "g4dn.12xlarge",
+			detect:              true,
+			detectedVolumes:     1,
+			expectDetectionCall: true,
+			expectedVal:         21,
+		},
+		{
+			name:                "launched without instance store",
+			instanceType:        "g4dn.12xlarge",
+			detect:              true,
+			detectedVolumes:     0,
+			expectDetectionCall: true,
+			expectedVal:         22,
+		},
+		{
+			name:                "launched with fewer instance store volumes",
+			instanceType:        "p3dn.24xlarge",
+			detect:              true,
+			detectedVolumes:     1,
+			expectDetectionCall: true,
+			// 17 (table limit without the 2 instance store volumes) + 1 (missing instance store volume) - 1 (root volume)
+			expectedVal: 17,
+		},
+		{
+			name:                "detection fails, falls back to static count",
+			instanceType:        "g4dn.12xlarge",
+			detect:              true,
+			detectErr:           errors.New("no NVMe driver"),
+			expectDetectionCall: true,
+			expectedVal:         21,
+		},
+		{
+			name:                "instance store device not presented on a small instance",
+			instanceType:        "c6gd.medium",
+			detect:              true,
+			detectedVolumes:     0,
+			expectDetectionCall: true,
+			// 26 (table limit without the 1 instance store volume) + 1 (missing instance store volume) - 1 (root volume)
+			expectedVal: 26,
+		},
+		{
+			name:                "NVMe enumeration unavailable on a small instance",
+			instanceType:        "c6gd.medium",
+			detect:              true,
+			detectErr:           errors.New("no NVMe driver"),
+			expectDetectionCall: true,
+			expectedVal:         25,
+		},
+		{
+			name:                 "instance store not counted",
+			instanceType:         "g4dn.12xlarge",
+			releaseInstanceStore: true,
+			detect:               true,
+			expectedVal:          22,
+		},
+		{
+			name:            "instance type without known instance store volumes",
+			instanceType:    "m5.large",
+			detect:          true,
+			detectedVolumes: 2,
+			expectedVal:     26,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
-
-			var mounter *mounter.MockMounter
 
-			var metadata *metadata.MockMetadataService
-			if tc.metadataMock != nil {
-				metadata = tc.metadataMock(ctrl)
-			}
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType)
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1)
 
+			detectionCalled := false
 			driver := &NodeService{
-				mounter:  mounter,
 				inFlight: internal.NewInFlight(),
-				options:  tc.options,
-				metadata: metadata,
+				options: &Options{
+					VolumeAttachLimit:          -1,
+					ReservedVolumeAttachments:  -1,
+					ReleaseInstanceStoreSlots:  tc.releaseInstanceStore,
+					DetectInstanceStoreVolumes: tc.detect,
+				},
+				metadata: m,
+				instanceStoreVolumeCounter: func() (int, error) {
+					detectionCalled = true
+					return tc.detectedVolumes, tc.detectErr
+				},
 			}
 
-			value := driver.getVolumesLimit()
-			if value != tc.expectedVal {
-				t.Fatalf("Expected value %v but got %v", tc.expectedVal, value)
+			if value := driver.getVolumesLimit(); value != tc.expectedVal {
+				t.Errorf("Expected value %v but got %v", tc.expectedVal, value)
+			}
+			if detectionCalled != tc.expectDetectionCall {
+				t.Errorf("Expected instance store volumes to be detected: %v, detected: %v", tc.expectDetectionCall, detectionCalled)
+			}
+		})
+	}
+}
+
+func TestCountNVMeInstanceStoreControllers(t *testing.T) {
+	nvmeClassDir := t.TempDir()
+	models := map[string]string{
+		"nvme0": "Amazon Elastic Block Store              \n",
+		"nvme1": "Amazon EC2 NVMe Instance Storage        \n",
+		"nvme2": "Amazon EC2 NVMe Instance Storage        \n",
+		"nvme3": "Amazon Elastic Block Store              \n",
+	}
+	for controller, model := range models {
+		if err := os.Mkdir(filepath.Join(nvmeClassDir, controller), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(nvmeClassDir, controller, "model"), []byte(model), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := countNVMeControllers(nvmeClassDir, instanceStoreModel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 instance store volumes, got %d", count)
+	}
+
+	count, err = countNVMeControllers(nvmeClassDir, ebsModel)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 EBS volumes, got %d", count)
+	}
+
+	if _, err := countNVMeControllers(filepath.Join(nvmeClassDir, "missing"), instanceStoreModel); err == nil {
+		t.Error("expected an error for a missing NVMe class directory")
+	}
+}
+
+// fakeVolumeLimitProvider returns the same limit for every instance type.
+type fakeVolumeLimitProvider struct {
+	limit     int
+	dedicated bool
+}
+
+func (p fakeVolumeLimitProvider) GetVolumeLimits(_ string) (int, string) {
+	if p.dedicated {
+		return p.limit, util.AttachmentDedicated
+	}
+	return p.limit, util.AttachmentShared
+}
+
+func (p fakeVolumeLimitProvider) HasDedicatedEBSLimit(_ string) bool {
+	return p.dedicated
+}
+
+func (p fakeVolumeLimitProvider) IsNitroInstanceType(_ string) bool {
+	return true
+}
+
+func (p fakeVolumeLimitProvider) GetCardCount(_ string) int {
+	return 1
+}
+
+func (p fakeVolumeLimitProvider) KnownInstanceTypes() []string {
+	return nil
+}
+
+func TestGetVolumesLimitWithVolumeLimitProvider(t *testing.T) {
+	testCases := []struct {
+		name         string
+		provider     fakeVolumeLimitProvider
+		expectedVal  int64
+		metadataMock func(ctrl *gomock.Controller) *metadata.MockMetadataService
+	}{
+		{
+			name:        "dedicated_custom_limit",
+			provider:    fakeVolumeLimitProvider{limit: 100, dedicated: true},
+			expectedVal: 99,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("m7i.large")
+				return m
+			},
+		},
+		{
+			name:        "shared_custom_limit",
+			provider:    fakeVolumeLimitProvider{limit: 20},
+			expectedVal: 17,
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceType().Return("m7i.large")
+				m.EXPECT().GetNumAttachedENIs().Return(3)
+				return m
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			options := &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: 1,
+			}
+
+			driver := NewNodeService(options, tc.metadataMock(ctrl), nil, nil).WithVolumeLimitProvider(tc.provider)
+
+			resolution := driver.resolveVolumesLimit()
+			if resolution.Limit != tc.expectedVal {
+				t.Fatalf("Expected value %v but got %v", tc.expectedVal, resolution.Limit)
+			}
+			// The limits tables have a limit for m7i.large, but the provider does not know it
+			if resolution.Source != "default" {
+				t.Errorf("Expected source default but got %s", resolution.Source)
 			}
 		})
 	}
 }
 
+func TestAvailableAttachmentSlotsMetric(t *testing.T) {
+	t.Setenv("CSI_NODE_NAME", "test-node")
+	recorder, registry := metrics.InitializeRecorder(false)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	md := metadata.NewMockMetadataService(ctrl)
+	md.EXPECT().UpdateMetadata().Return(nil).AnyTimes()
+	md.EXPECT().GetAvailabilityZone().Return("us-west-2a").AnyTimes()
+	md.EXPECT().GetOutpostArn().Return(arn.ARN{}).AnyTimes()
+	md.EXPECT().GetInstanceID().Return("i-1234567890abcdef0").AnyTimes()
+	instanceType := "m5.large"
+	md.EXPECT().GetInstanceType().DoAndReturn(func() string { return instanceType }).AnyTimes()
+	md.EXPECT().GetNumBlockDeviceMappings().Return(1).AnyTimes()
+
+	// The root volume and the block device mapping are attached when the node plugin starts, as well as volumes
+	// the driver attached before it restarted
+	attached := 5
+	driver := &NodeService{
+		metadata: md,
+		mounter:  mounter.NewMockMounter(ctrl),
+		inFlight: internal.NewInFlight(),
+		options:  &Options{VolumeAttachLimit: 25, ReservedVolumeAttachments: -1},
+		ebsVolumeCounter: func() (int, error) {
+			return attached, nil
+		},
+	}
+	recorder.SetGaugeFunc(metrics.NodeAvailableAttachmentSlots, metrics.NodeAvailableAttachmentSlotsHelpText,
+		[]string{"instance_type", "node"}, driver.availableAttachmentSlots)
+
+	// Nothing is reported before the limit is
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(""), metrics.NodeAvailableAttachmentSlots); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 3 of the 25 attachments are used by the volumes attached by the driver
+	expected := `
+# HELP ebs_csi_node_available_attachment_slots Number of EBS volumes that can still be attached to the node, by instance type and node name
+# TYPE ebs_csi_node_available_attachment_slots gauge
+ebs_csi_node_available_attachment_slots{instance_type="m5.large",node="test-node"} 22
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), metrics.NodeAvailableAttachmentSlots); err != nil {
+		t.Fatal(err)
+	}
+
+	attached = 4
+	expected = strings.Replace(expected, "} 22", "} 23", 1)
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), metrics.NodeAvailableAttachmentSlots); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the series of the current instance type is reported
+	instanceType = "m7i.large"
+	if _, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = `
+# HELP ebs_csi_node_available_attachment_slots Number of EBS volumes that can still be attached to the node, by instance type and node name
+# TYPE ebs_csi_node_available_attachment_slots gauge
+ebs_csi_node_available_attachment_slots{instance_type="m7i.large",node="test-node"} 23
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), metrics.NodeAvailableAttachmentSlots); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a count of the attached volumes, nothing is reported
+	driver.ebsVolumeCounter = func() (int, error) {
+		return 0, errors.New("no NVMe controllers")
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(""), metrics.NodeAvailableAttachmentSlots); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReservedSlotsMetric(t *testing.T) {
+	t.Setenv("CSI_NODE_NAME", "test-node")
+	_, registry := metrics.InitializeRecorder(false)
+
+	ctrl := gomock.NewController(t)
+	m := metadata.NewMockMetadataService(ctrl)
+	// g5.xlarge has a GPU and an NVMe instance store volume, which its shared limit of 25 already excludes
+	m.EXPECT().GetInstanceType().Return("g5.xlarge")
+	m.EXPECT().GetNumBlockDeviceMappings().Return(1)
+	m.EXPECT().GetNumAttachedENIs().Return(2)
+
+	driver := &NodeService{
+		inFlight: internal.NewInFlight(),
+		options: &Options{
+			VolumeAttachLimit:         -1,
+			ReservedVolumeAttachments: -1,
+		},
+		metadata: m,
+	}
+	if limit := driver.getVolumesLimit(); limit != 22 {
+		t.Fatalf("expected limit 22, got %d", limit)
+	}
+
+	expected := `
+# HELP ebs_csi_node_reserved_slots Number of attachment slots of the node taken by devices other than the volumes attached by the driver, by category, instance type and node name
+# TYPE ebs_csi_node_reserved_slots gauge
+ebs_csi_node_reserved_slots{category="boot_volume",instance_type="g5.xlarge",node="test-node"} 2
+ebs_csi_node_reserved_slots{category="device_names",instance_type="g5.xlarge",node="test-node"} 0
+ebs_csi_node_reserved_slots{category="eni",instance_type="g5.xlarge",node="test-node"} 1
+ebs_csi_node_reserved_slots{category="gpu",instance_type="g5.xlarge",node="test-node"} 1
+ebs_csi_node_reserved_slots{category="instance_store",instance_type="g5.xlarge",node="test-node"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), metrics.NodeReservedSlots); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestNodePublishVolume(t *testing.T) {
 	testCases := []struct {
 		name         string
@@ -1482,6 +2352,75 @@ func TestNodePublishVolume(t *testing.T) {
 				return m
 			},
 		},
+		{
+			name: "success_block_device_already_published",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          "vol-test",
+				StagingTargetPath: "/staging/path",
+				TargetPath:        "/target/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Block{
+						Block: &csi.VolumeCapability_BlockVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				PublishContext: map[string]string{
+					DevicePathKey: "/dev/xvdba",
+				},
+			},
+			mounterMock: func(ctrl *gomock.Controller) *mounter.MockMounter {
+				m := mounter.NewMockMounter(ctrl)
+
+				m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("/dev/xvdba", nil)
+				m.EXPECT().PathExists(gomock.Eq("/target")).Return(true, nil)
+				m.EXPECT().MakeFile(gomock.Eq("/target/path")).Return(nil)
+				// The target file is already bind mounted, so publishing again must not mount it twice
+				m.EXPECT().IsLikelyNotMountPoint(gomock.Eq("/target/path")).Return(false, nil)
+				return m
+			},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetRegion().Return("us-west-2")
+				return m
+			},
+		},
+		{
+			name: "success_block_device_missing_target_dir",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          "vol-test",
+				StagingTargetPath: "/staging/path",
+				TargetPath:        "/target/path",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Block{
+						Block: &csi.VolumeCapability_BlockVolume{},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+					},
+				},
+				PublishContext: map[string]string{
+					DevicePathKey: "/dev/xvdba",
+				},
+			},
+			mounterMock: func(ctrl *gomock.Controller) *mounter.MockMounter {
+				m := mounter.NewMockMounter(ctrl)
+
+				m.EXPECT().FindDevicePath(gomock.Eq("/dev/xvdba"), gomock.Eq("vol-test"), gomock.Eq(""), gomock.Eq("us-west-2")).Return("/dev/xvdba", nil)
+				m.EXPECT().PathExists(gomock.Eq("/target")).Return(false, nil)
+				m.EXPECT().MakeDir(gomock.Eq("/target")).Return(nil)
+				m.EXPECT().MakeFile(gomock.Eq("/target/path")).Return(nil)
+				m.EXPECT().IsLikelyNotMountPoint(gomock.Eq("/target/path")).Return(true, nil)
+				m.EXPECT().Mount(gomock.Eq("/dev/xvdba"), gomock.Eq("/target/path"), gomock.Eq(""), gomock.Eq([]string{"bind"})).Return(nil)
+				return m
+			},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetRegion().Return("us-west-2")
+				return m
+			},
+		},
 		{
 			name: "success_fs",
 			req: &csi.NodePublishVolumeRequest{
@@ -2070,8 +3009,15 @@ func TestNodeGetInfo(t *testing.T) {
 		AwsAccountIDKey:          "123456789012",
 		AwsOutpostIDKey:          "op-1234567890abcdef0",
 	}
+	expectedSegmentsWithRegion := map[string]string{
+		ZoneTopologyKey:            "us-west-2a",
+		WellKnownZoneTopologyKey:   "us-west-2a",
+		WellKnownRegionTopologyKey: "us-west-2",
+		OSTopologyKey:              runtime.GOOS,
+	}
 	testCases := []struct {
 		name         string
+		options      *Options
 		metadataMock func(ctrl *gomock.Controller) *metadata.MockMetadataService
 		expectedResp *csi.NodeGetInfoResponse
 	}{
@@ -2133,35 +3079,180 @@ func TestNodeGetInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "with_region_topology",
+			options: &Options{EnableRegionTopology: true},
+			metadataMock: func(ctrl *gomock.Controller) *metadata.MockMetadataService {
+				m := metadata.NewMockMetadataService(ctrl)
+				m.EXPECT().GetInstanceID().Return("i-1234567890abcdef0")
+				m.EXPECT().GetAvailabilityZone().Return("us-west-2a")
+				m.EXPECT().GetRegion().Return("us-west-2")
+				m.EXPECT().UpdateMetadata().Return(nil)
+				m.EXPECT().GetOutpostArn().Return(arn.ARN{})
+				return m
+			},
+			expectedResp: &csi.NodeGetInfoResponse{
+				NodeId: "i-1234567890abcdef0",
+				AccessibleTopology: &csi.Topology{
+					Segments: expectedSegmentsWithRegion,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			metadataService := tc.metadataMock(ctrl)
+			mounter := mounter.NewMockMounter(ctrl)
+
+			options := tc.options
+			if options == nil {
+				options = &Options{}
+			}
+			driver := &NodeService{
+				metadata: metadataService,
+				mounter:  mounter,
+				inFlight: internal.NewInFlight(),
+				options:  options,
+			}
+
+			resp, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if p := plugin.GetPlugin(); p != nil {
+				if segments := tc.expectedResp.GetAccessibleTopology().GetSegments(); segments != nil {
+					maps.Copy(segments, p.GetNodeTopologySegments())
+				}
+			}
+			if !reflect.DeepEqual(resp, tc.expectedResp) {
+				t.Fatalf("Expected response %+v, but got %+v", tc.expectedResp, resp)
+			}
+		})
+	}
+}
+
+func TestNodeGetInfoAfterInstanceResize(t *testing.T) {
+	// Each boot is a restart of the node plugin on the same instance, after it was stopped and its
+	// instance type changed. The limit must follow the instance type, not what was reported before.
+	boots := []struct {
+		instanceType  string
+		expectedLimit int64
+	}{
+		{instanceType: "m5.large", expectedLimit: 26},
+		{instanceType: "m7i.large", expectedLimit: 31},
+		{instanceType: "m5.large", expectedLimit: 26},
+	}
+
+	for _, boot := range boots {
+		ctrl := gomock.NewController(t)
+		m := metadata.NewMockMetadataService(ctrl)
+		m.EXPECT().UpdateMetadata().Return(nil)
+		m.EXPECT().GetInstanceID().Return("i-1234567890abcdef0")
+		m.EXPECT().GetAvailabilityZone().Return("us-west-2a")
+		m.EXPECT().GetOutpostArn().Return(arn.ARN{})
+		m.EXPECT().GetInstanceType().Return(boot.instanceType).AnyTimes()
+		m.EXPECT().GetNumBlockDeviceMappings().Return(0).AnyTimes()
+		m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+		driver := &NodeService{
+			metadata: m,
+			mounter:  mounter.NewMockMounter(ctrl),
+			inFlight: internal.NewInFlight(),
+			options: &Options{
+				VolumeAttachLimit:         -1,
+				ReservedVolumeAttachments: -1,
+			},
+		}
+
+		resp, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if resp.GetMaxVolumesPerNode() != boot.expectedLimit {
+			t.Errorf("expected limit %d for %s, got %d", boot.expectedLimit, boot.instanceType, resp.GetMaxVolumesPerNode())
+		}
+		ctrl.Finish()
+	}
+}
+
+func TestNodeGetInfoWaitsForInstanceType(t *testing.T) {
+	defaultBackoff := instanceTypeWaitBackoff
+	instanceTypeWaitBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	t.Cleanup(func() {
+		instanceTypeWaitBackoff = defaultBackoff
+	})
+
+	testCases := []struct {
+		name string
+		// resolveAfter is the number of metadata updates after which the instance type is known, 0 for never
+		resolveAfter int
+		expectErr    bool
+		expectedVal  int64
+	}{
+		{
+			name:         "instance type known after metadata update",
+			resolveAfter: 2,
+			expectedVal:  26,
+		},
+		{
+			name:      "instance type never known",
+			expectErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			ctrl := gomock.NewController(t)
-			defer ctrl.Finish()
 
-			metadataService := tc.metadataMock(ctrl)
-			mounter := mounter.NewMockMounter(ctrl)
+			updates := 0
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().UpdateMetadata().DoAndReturn(func() error {
+				updates++
+				return nil
+			}).MinTimes(1)
+			m.EXPECT().GetInstanceType().DoAndReturn(func() string {
+				if tc.resolveAfter > 0 && updates >= tc.resolveAfter {
+					return "m5.large"
+				}
+				return ""
+			}).MinTimes(1)
+			m.EXPECT().GetAvailabilityZone().Return("us-west-2a")
+			m.EXPECT().GetOutpostArn().Return(arn.ARN{})
+			if !tc.expectErr {
+				m.EXPECT().GetInstanceID().Return("i-1234567890abcdef0")
+				m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+				m.EXPECT().GetNumAttachedENIs().Return(1)
+			}
 
 			driver := &NodeService{
-				metadata: metadataService,
-				mounter:  mounter,
+				metadata: m,
 				inFlight: internal.NewInFlight(),
-				options:  &Options{},
+				options: &Options{
+					VolumeAttachLimit:         -1,
+					ReservedVolumeAttachments: -1,
+				},
 			}
 
 			resp, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{})
+			if tc.expectErr {
+				if status.Code(err) != codes.Unavailable {
+					t.Fatalf("Expected Unavailable error, got response %+v and error %v", resp, err)
+				}
+				if reported := driver.reportedLimit.Load(); reported != nil {
+					t.Errorf("Expected no volume limit to be recorded, got %d", reported.limit)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatalf("Unexpected error: %v", err)
 			}
-
-			if p := plugin.GetPlugin(); p != nil {
-				if segments := tc.expectedResp.GetAccessibleTopology().GetSegments(); segments != nil {
-					maps.Copy(segments, p.GetNodeTopologySegments())
-				}
-			}
-			if !reflect.DeepEqual(resp, tc.expectedResp) {
-				t.Fatalf("Expected response %+v, but got %+v", tc.expectedResp, resp)
+			if resp.GetMaxVolumesPerNode() != tc.expectedVal {
+				t.Errorf("Expected MaxVolumesPerNode %d, got %d", tc.expectedVal, resp.GetMaxVolumesPerNode())
 			}
 		})
 	}
@@ -2839,6 +3930,173 @@ func TestRemoveNotReadyTaint(t *testing.T) {
 	}
 }
 
+func TestReconcileCSINodeAllocatable(t *testing.T) {
+	nodeName := "test-node-123"
+	newCSINode := func(driverName string, count *int32) *v1.CSINode {
+		driver := v1.CSINodeDriver{Name: driverName, NodeID: nodeName}
+		if count != nil {
+			driver.Allocatable = &v1.VolumeNodeResources{Count: count}
+		}
+		return &v1.CSINode{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Spec: v1.CSINodeSpec{
+				Drivers: []v1.CSINodeDriver{{Name: "other.csi.example.com", NodeID: nodeName}, driver},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		csiNode        *v1.CSINode
+		count          int32
+		expReconciled  bool
+		expPatch       bool
+		expErrContains string
+	}{
+		{
+			name:          "count changed",
+			csiNode:       newCSINode(util.GetDriverName(), aws.Int32(25)),
+			count:         26,
+			expReconciled: true,
+			expPatch:      true,
+		},
+		{
+			name:          "count unchanged is patched again",
+			csiNode:       newCSINode(util.GetDriverName(), aws.Int32(26)),
+			count:         26,
+			expReconciled: true,
+			expPatch:      true,
+		},
+		{
+			name:          "count not set",
+			csiNode:       newCSINode(util.GetDriverName(), nil),
+			count:         26,
+			expReconciled: true,
+			expPatch:      true,
+		},
+		{
+			name:    "driver not registered yet",
+			csiNode: newCSINode("another.csi.example.com", aws.Int32(25)),
+			count:   26,
+		},
+		{
+			name:           "CSINode missing",
+			count:          26,
+			expErrContains: "failed to get CSINode for " + nodeName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := fake.NewClientset()
+			if tc.csiNode != nil {
+				client = fake.NewClientset(tc.csiNode)
+			}
+
+			reconciled, err := reconcileCSINodeAllocatable(t.Context(), client, nodeName, tc.count)
+			if tc.expErrContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expErrContains) {
+					t.Fatalf("expected error containing %q, got %v", tc.expErrContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if reconciled != tc.expReconciled {
+				t.Fatalf("expected reconciled %t, got %t", tc.expReconciled, reconciled)
+			}
+
+			patched := false
+			for _, action := range client.Actions() {
+				if action.GetVerb() == "patch" && action.GetResource().Resource == "csinodes" {
+					patched = true
+				}
+			}
+			if patched != tc.expPatch {
+				t.Fatalf("expected CSINode to be patched: %t, patched: %t", tc.expPatch, patched)
+			}
+			if !tc.expPatch {
+				return
+			}
+
+			csiNode, err := client.StorageV1().CSINodes().Get(t.Context(), nodeName, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("failed to get CSINode: %v", err)
+			}
+			if other := csiNode.Spec.Drivers[0]; other.Allocatable != nil {
+				t.Fatalf("expected allocatable of driver %s to be untouched, got %v", other.Name, other.Allocatable)
+			}
+			driver := csiNode.Spec.Drivers[1]
+			if driver.Allocatable == nil || driver.Allocatable.Count == nil || *driver.Allocatable.Count != tc.count {
+				t.Fatalf("expected allocatable count %d, got %v", tc.count, driver.Allocatable)
+			}
+		})
+	}
+}
+
+func TestVolumeLimitsDebugHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := metadata.NewMockMetadataService(ctrl)
+	m.EXPECT().GetInstanceType().Return("m5d.large")
+	m.EXPECT().GetNumBlockDeviceMappings().Return(1)
+	m.EXPECT().GetNumAttachedENIs().Return(3)
+
+	driver := &NodeService{
+		inFlight: internal.NewInFlight(),
+		options: &Options{
+			VolumeAttachLimit:         -1,
+			ReservedVolumeAttachments: -1,
+			ReservedDeviceNames:       []string{"/dev/xvdba"},
+		},
+		metadata: m,
+	}
+	handler := driver.volumeLimitsDebugHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodGet, volumeLimitsDebugPath, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("expected Content-Type application/json, got %q", contentType)
+	}
+
+	var got volumeLimitResolution
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	// 26 - 2 volumes attached at boot - 1 reserved device name - 2 ENIs
+	expected := volumeLimitResolution{
+		InstanceType:   "m5d.large",
+		Source:         "volume-limits",
+		MaxAttachments: 26,
+		AttachmentType: util.AttachmentShared,
+		ReservedSlots: reservedSlots{
+			Volumes:     2,
+			DeviceNames: 1,
+			ENIs:        2,
+			Total:       5,
+		},
+		Limit: 21,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+	for _, field := range []string{`"instanceType"`, `"source"`, `"reservedSlots"`, `"limit"`} {
+		if !strings.Contains(rec.Body.String(), field) {
+			t.Errorf("expected response to contain %s, got %s", field, rec.Body.String())
+		}
+	}
+
+	// The endpoint is read-only
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodPost, volumeLimitsDebugPath, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d for POST, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
 func TestStartNotReadyTaintWatcher(t *testing.T) {
 	const nodeName = "ip-10-0-0-1"
 	t.Setenv("CSI_NODE_NAME", nodeName)
@@ -2934,3 +4192,378 @@ func TestStartNotReadyTaintWatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestGetVolumesLimitRecordsFallbackEvent(t *testing.T) {
+	t.Setenv("CSI_NODE_NAME", "test-node")
+
+	testCases := []struct {
+		name          string
+		instanceType  string
+		expectedEvent string
+	}{
+		{
+			name:          "malformed instance type",
+			instanceType:  "m5",
+			expectedEvent: `Warning VolumeLimitFallback Instance type "m5" is not recognized, reporting the default volume attach limit of 26`,
+		},
+		{
+			name:         "instance type in the generated table",
+			instanceType: "m7i.large",
+		},
+		{
+			name:         "instance type with the default limit",
+			instanceType: "m5.large",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType)
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+			recorder := record.NewFakeRecorder(10)
+			driver := &NodeService{
+				inFlight: internal.NewInFlight(),
+				options: &Options{
+					VolumeAttachLimit:         -1,
+					ReservedVolumeAttachments: -1,
+				},
+				metadata:      m,
+				eventRecorder: recorder,
+			}
+			driver.getVolumesLimit()
+
+			select {
+			case event := <-recorder.Events:
+				if event != tc.expectedEvent {
+					t.Fatalf("expected event %q, got %q", tc.expectedEvent, event)
+				}
+			default:
+				if tc.expectedEvent != "" {
+					t.Fatalf("expected event %q, got none", tc.expectedEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestGetVolumesLimitUnknownInstanceFamily(t *testing.T) {
+	testCases := []struct {
+		name                                string
+		instanceType                        string
+		unknownInstanceFamilyMaxAttachments int
+		expectedLimit                       int64
+	}{
+		{
+			name:          "unknown family assumed to be Nitro by default",
+			instanceType:  "zz9.large",
+			expectedLimit: 26,
+		},
+		{
+			name:                                "unknown family with --unknown-instance-family-max-attachments",
+			instanceType:                        "zz9.large",
+			unknownInstanceFamilyMaxAttachments: 16,
+			expectedLimit:                       15,
+		},
+		{
+			name:                                "known family with --unknown-instance-family-max-attachments",
+			instanceType:                        "t3.large",
+			unknownInstanceFamilyMaxAttachments: 16,
+			expectedLimit:                       26,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType)
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+			driver := &NodeService{
+				inFlight: internal.NewInFlight(),
+				options: &Options{
+					VolumeAttachLimit:                   -1,
+					ReservedVolumeAttachments:           -1,
+					UnknownInstanceFamilyMaxAttachments: tc.unknownInstanceFamilyMaxAttachments,
+				},
+				metadata: m,
+			}
+			if limit := driver.getVolumesLimit(); limit != tc.expectedLimit {
+				t.Fatalf("expected limit %d, got %d", tc.expectedLimit, limit)
+			}
+		})
+	}
+}
+
+func TestGetVolumesLimitReservedENISlots(t *testing.T) {
+	testCases := []struct {
+		name               string
+		instanceType       string
+		reservedENISlots   int
+		expectedLimit      int64
+		expectedFutureENIs int
+	}{
+		{
+			name:          "shared limit without reserved ENI slots",
+			instanceType:  "m5.large",
+			expectedLimit: 26,
+		},
+		{
+			name:               "shared limit with reserved ENI slots",
+			instanceType:       "m5.large",
+			reservedENISlots:   3,
+			expectedLimit:      23,
+			expectedFutureENIs: 3,
+		},
+		{
+			name:             "dedicated limit with reserved ENI slots",
+			instanceType:     "m7i.large",
+			reservedENISlots: 3,
+			expectedLimit:    31,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType)
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+			driver := &NodeService{
+				inFlight: internal.NewInFlight(),
+				options: &Options{
+					VolumeAttachLimit:         -1,
+					ReservedVolumeAttachments: -1,
+					ReservedENISlots:          tc.reservedENISlots,
+				},
+				metadata: m,
+			}
+			resolution := driver.resolveVolumesLimit()
+			if resolution.Limit != tc.expectedLimit {
+				t.Fatalf("expected limit %d, got %d", tc.expectedLimit, resolution.Limit)
+			}
+			if resolution.ReservedSlots.FutureENIs != tc.expectedFutureENIs {
+				t.Errorf("expected %d future ENI slots, got %d", tc.expectedFutureENIs, resolution.ReservedSlots.FutureENIs)
+			}
+		})
+	}
+}
+
+func TestGetVolumesLimitDescribedAccelerators(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		instanceType         string
+		acceleratorCount     int
+		describeErr          error
+		expectedLimit        int64
+		expectedAccelerators int
+	}{
+		{
+			name:             "known GPU count matches described accelerators",
+			instanceType:     "g5.xlarge",
+			acceleratorCount: 1,
+			expectedLimit:    24,
+		},
+		{
+			name:                 "described accelerators exceed known GPU count",
+			instanceType:         "g5.xlarge",
+			acceleratorCount:     4,
+			expectedLimit:        21,
+			expectedAccelerators: 3,
+		},
+		{
+			name:             "described accelerators below known GPU count keep the table limit",
+			instanceType:     "g5.12xlarge",
+			acceleratorCount: 1,
+			expectedLimit:    21,
+		},
+		{
+			name:          "describe failure falls back to known GPU count",
+			instanceType:  "g5.xlarge",
+			describeErr:   errors.New("UnauthorizedOperation"),
+			expectedLimit: 24,
+		},
+		{
+			name:          "shared limit without accelerators",
+			instanceType:  "m5.large",
+			expectedLimit: 26,
+		},
+		{
+			name:                 "shared limit without known GPU count",
+			instanceType:         "zz9.xlarge",
+			acceleratorCount:     2,
+			expectedLimit:        24,
+			expectedAccelerators: 2,
+		},
+		{
+			name:             "dedicated limit ignores accelerators",
+			instanceType:     "m7i.large",
+			acceleratorCount: 2,
+			expectedLimit:    31,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType).AnyTimes()
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+			driver := &NodeService{
+				inFlight: internal.NewInFlight(),
+				options: &Options{
+					VolumeAttachLimit:         -1,
+					ReservedVolumeAttachments: -1,
+				},
+				metadata: m,
+				acceleratorCounter: func(instanceType string) (int, error) {
+					if instanceType != tc.instanceType {
+						t.Errorf("expected accelerators of %s to be looked up, got %s", tc.instanceType, instanceType)
+					}
+					return tc.acceleratorCount, tc.describeErr
+				},
+			}
+			resolution := driver.resolveVolumesLimit()
+			if resolution.Limit != tc.expectedLimit {
+				t.Fatalf("expected limit %d, got %d", tc.expectedLimit, resolution.Limit)
+			}
+			if resolution.ReservedSlots.Accelerators != tc.expectedAccelerators {
+				t.Errorf("expected %d accelerator slots, got %d", tc.expectedAccelerators, resolution.ReservedSlots.Accelerators)
+			}
+		})
+	}
+}
+
+func TestGetVolumesLimitDescribedAcceleratorsAfterResize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := metadata.NewMockMetadataService(ctrl)
+	m.EXPECT().GetNumBlockDeviceMappings().Return(0).AnyTimes()
+	m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+	gomock.InOrder(
+		m.EXPECT().GetInstanceType().Return("g5.xlarge").Times(2),
+		m.EXPECT().GetInstanceType().Return("g5.12xlarge").Times(2),
+	)
+
+	accelerators := map[string]int{"g5.xlarge": 1, "g5.12xlarge": 8}
+	lookups := map[string]int{}
+	driver := &NodeService{
+		inFlight: internal.NewInFlight(),
+		options: &Options{
+			VolumeAttachLimit:         -1,
+			ReservedVolumeAttachments: -1,
+		},
+		metadata: m,
+		acceleratorCounter: func(instanceType string) (int, error) {
+			lookups[instanceType]++
+			return accelerators[instanceType], nil
+		},
+	}
+
+	// The accelerators are looked up once per instance type, and again after the instance was resized
+	for _, expectedLimit := range []int64{24, 24, 17, 17} {
+		resolution := driver.resolveVolumesLimit()
+		if resolution.Limit != expectedLimit {
+			t.Fatalf("expected limit %d for %s, got %d", expectedLimit, resolution.InstanceType, resolution.Limit)
+		}
+	}
+	if lookups["g5.xlarge"] != 1 || lookups["g5.12xlarge"] != 1 {
+		t.Errorf("expected one lookup per instance type, got %v", lookups)
+	}
+}
+
+func TestGetVolumesLimitMaxAdvertisedAttachments(t *testing.T) {
+	testCases := []struct {
+		name                     string
+		instanceType             string
+		maxAdvertisedAttachments int64
+		expectedLimit            int64
+	}{
+		{
+			name:          "high dedicated limit without ceiling",
+			instanceType:  "m7i.48xlarge",
+			expectedLimit: 127,
+		},
+		{
+			name:                     "high dedicated limit capped by ceiling",
+			instanceType:             "m7i.48xlarge",
+			maxAdvertisedAttachments: 64,
+			expectedLimit:            64,
+		},
+		{
+			name:                     "limit below ceiling",
+			instanceType:             "m5.large",
+			maxAdvertisedAttachments: 64,
+			expectedLimit:            26,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := metadata.NewMockMetadataService(ctrl)
+			m.EXPECT().GetInstanceType().Return(tc.instanceType)
+			m.EXPECT().GetNumBlockDeviceMappings().Return(0)
+			m.EXPECT().GetNumAttachedENIs().Return(1).AnyTimes()
+
+			driver := &NodeService{
+				inFlight: internal.NewInFlight(),
+				options: &Options{
+					VolumeAttachLimit:         -1,
+					ReservedVolumeAttachments: -1,
+					MaxAdvertisedAttachments:  tc.maxAdvertisedAttachments,
+				},
+				metadata: m,
+			}
+			if limit := driver.getVolumesLimit(); limit != tc.expectedLimit {
+				t.Fatalf("expected limit %d, got %d", tc.expectedLimit, limit)
+			}
+		})
+	}
+}
+
+func TestNodeGetInfoRecordsUnknownInstanceTypeEvent(t *testing.T) {
+	t.Setenv("CSI_NODE_NAME", "test-node")
+	defaultBackoff := instanceTypeWaitBackoff
+	instanceTypeWaitBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	t.Cleanup(func() {
+		instanceTypeWaitBackoff = defaultBackoff
+	})
+
+	ctrl := gomock.NewController(t)
+	m := metadata.NewMockMetadataService(ctrl)
+	m.EXPECT().UpdateMetadata().Return(nil).AnyTimes()
+	m.EXPECT().GetAvailabilityZone().Return("us-west-2a")
+	m.EXPECT().GetOutpostArn().Return(arn.ARN{})
+	m.EXPECT().GetInstanceType().Return("").AnyTimes()
+
+	recorder := record.NewFakeRecorder(10)
+	driver := &NodeService{
+		inFlight: internal.NewInFlight(),
+		options: &Options{
+			VolumeAttachLimit: -1,
+		},
+		metadata:      m,
+		eventRecorder: recorder,
+	}
+	if _, err := driver.NodeGetInfo(t.Context(), &csi.NodeGetInfoRequest{}); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable error, got %v", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning VolumeLimitUnknown ") {
+			t.Fatalf("expected a VolumeLimitUnknown warning, got %q", event)
+		}
+	default:
+		t.Fatal("expected a VolumeLimitUnknown warning, got none")
+	}
+}
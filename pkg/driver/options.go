@@ -18,9 +18,16 @@ package driver
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/limits"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	flag "github.com/spf13/pflag"
 	cliflag "k8s.io/component-base/cli/flag"
@@ -46,6 +53,12 @@ type Options struct {
 	MetricsKeyFile string
 	// EnableOtelTracing is a flag to enable opentelemetry tracing for the driver
 	EnableOtelTracing bool
+	// GracefulShutdownTimeout is how long the driver waits for in-flight RPCs to finish after
+	// receiving SIGTERM. When 0, the driver exits immediately.
+	GracefulShutdownTimeout time.Duration
+	// ReservedDeviceNames are device names attached by tooling outside the driver. The controller never
+	// assigns them to a volume and the node subtracts them from the attachment limit it reports.
+	ReservedDeviceNames []string
 
 	// #### Controller options ####
 
@@ -65,8 +78,18 @@ type Options struct {
 	WarnOnInvalidTag bool
 	// flag to set user agent
 	UserAgentExtra string
+	// AwsMaxIdleConnsPerHost is the maximum number of idle connections to each AWS API endpoint kept open for reuse.
+	AwsMaxIdleConnsPerHost int
+	// AwsIdleConnTimeout is how long an idle connection to an AWS API endpoint is kept open for reuse.
+	AwsIdleConnTimeout time.Duration
+	// AwsEC2Endpoint is the URL the EC2 API is called at instead of the regional endpoint.
+	AwsEC2Endpoint string
 	// flag to enable batching of API calls
 	Batching bool
+	// BatchingVolumeMaxEntries is the maximum number of volumes looked up by a single batched DescribeVolumes call.
+	BatchingVolumeMaxEntries int
+	// BatchingVolumeMaxDelay is how long DescribeVolumes requests are collected before a batch is sent.
+	BatchingVolumeMaxDelay time.Duration
 	// flag to set the timeout for volume modification requests to be coalesced into a single
 	// volume modification call to AWS.
 	ModifyVolumeRequestHandlerTimeout time.Duration
@@ -74,6 +97,16 @@ type Options struct {
 	DeprecatedMetrics bool
 	// flag to enable node-local volume support
 	EnableNodeLocalVolumes bool
+	// flag to wait for an in-progress modification of the source volume to finish before creating a snapshot,
+	// instead of creating the snapshot right away with a warning
+	WaitForVolumeModificationBeforeSnapshot bool
+	// TagSnapshotsWithSourceVolume tags snapshots with the availability zone and type of their source volume.
+	TagSnapshotsWithSourceVolume bool
+	// AZFilter restricts the availability zones the controller creates volumes in. Empty means all zones.
+	AZFilter []string
+	// VolumeTypeAttachmentLimits caps the number of volumes of a volume type, such as io2, that are attached
+	// to a node, for instances where AWS enforces a cap for the volume type besides the attachment limit.
+	VolumeTypeAttachmentLimits map[string]int
 
 	// #### Node options #####
 
@@ -92,12 +125,62 @@ type Options struct {
 	// When -1, the amount of reserved attachments is loaded from instance metadata that captured state at node boot
 	// and may include not only system disks but also CSI volumes (and therefore it may be wrong).
 	ReservedVolumeAttachments int
+	// ReservedENISlots is the number of ENIs expected to be attached after the node starts, for example by a CNI
+	// that adds ENIs as pods are scheduled. Each takes an attachment slot on instance types with a shared limit.
+	ReservedENISlots int
+	// OutpostVolumeAttachLimit is the volume attach limit reported by nodes running on AWS Outposts, where the
+	// limits tables of commercial regions do not apply. When 0, Outposts nodes compute their limit like other nodes.
+	OutpostVolumeAttachLimit int64
 	// ALPHA: WindowsHostProcess indicates whether the driver is running in a Windows privileged container
 	WindowsHostProcess bool
 	// LegacyXFSProgs formats XFS volumes with `bigtime=0,inobtcount=0,reflink=0,nrext64=0`, so that they can be mounted onto nodes with linux kernel ≤ v5.4. Volumes formatted with this option may experience issues after 2038, and will be unable to use some XFS features (for example, reflinks).
 	LegacyXFSProgs bool
 	// CsiMountPointPath is the path where CSI volumes are expected to be mounted on the node.
 	CsiMountPointPath string
+	// DeviceWaitBaseTimeout is how long NodeStageVolume waits for the device of a volume to appear.
+	// When zero together with DeviceWaitTimeoutPerGiB, the device is looked up only once.
+	DeviceWaitBaseTimeout time.Duration
+	// DeviceWaitTimeoutPerGiB is added to DeviceWaitBaseTimeout for every GiB of a volume restored from a snapshot.
+	DeviceWaitTimeoutPerGiB time.Duration
+	// EnableRegionTopology adds the well-known region topology key to the topology reported by NodeGetInfo.
+	EnableRegionTopology bool
+	// UnsupportedInstanceTypes are instance types the node reports the lowest possible volume attach limit on,
+	// for instance types known to be unable to attach EBS volumes.
+	UnsupportedInstanceTypes []string
+	// NonNitroMaxAttachments is the attachment limit of instance types not built on the Nitro System.
+	NonNitroMaxAttachments int
+	// NitroMaxAttachments is the attachment limit of Nitro instance types that are not in the limits tables.
+	NitroMaxAttachments int
+	// UnknownInstanceFamilyMaxAttachments is the shared attachment limit of instance types whose family is in none
+	// of the limits tables. When 0, they are assumed to be Nitro instance types and get NitroMaxAttachments.
+	UnknownInstanceFamilyMaxAttachments int
+	// MaxAdvertisedAttachments caps the volume attach limit computed from the instance type of the node.
+	// When 0, the computed limit is reported as is.
+	MaxAdvertisedAttachments int64
+	// DebugVolumeLimitsEndpoint is the TCP network address where the node serves how its volume attach limit
+	// was resolved at /debug/volume-limits. Empty disables the endpoint.
+	DebugVolumeLimitsEndpoint string
+	// ReconcileCSINodeAllocatable overwrites the allocatable volume count of the CSINode of the node
+	// on startup with the limit computed by the driver.
+	ReconcileCSINodeAllocatable bool
+	// SharedLimitInstanceFamilies are instance families whose attachment limit is treated as shared with ENIs,
+	// even if the limits tables list it as dedicated.
+	SharedLimitInstanceFamilies []string
+	// ReleaseInstanceStoreSlots gives the attachments of NVMe instance store volumes, which are excluded from the
+	// volume attach limit of shared instance types, back to EBS volumes. Set by --count-instance-store-as-attachments=false.
+	ReleaseInstanceStoreSlots bool
+	// DetectInstanceStoreVolumes makes the node count the NVMe instance store volumes that are actually attached,
+	// instead of assuming all instance store volumes of the instance type are.
+	DetectInstanceStoreVolumes bool
+	// DescribeInstanceTypeAccelerators makes the node look up the GPUs and inference accelerators of its instance
+	// type with DescribeInstanceTypes on startup and when the instance type changes, instead of relying on the
+	// GPU counts of the limits tables.
+	DescribeInstanceTypeAccelerators bool
+	// DescribeInstanceTypeHypervisor makes the node look up the hypervisor of its instance type with
+	// DescribeInstanceTypes, instead of relying on the Nitro instance types of the limits tables.
+	DescribeInstanceTypeHypervisor bool
+	// NVMeHealthCheck makes the node fail Probe on Nitro instances when the NVMe devices of EBS volumes cannot be resolved.
+	NVMeHealthCheck bool
 	// MetadataSources dictates which sources are used to retrieve instance metadata.
 	// The driver will attempt to rely on each source in order until one succeeds.
 	// Valid options include 'imds' and 'kubernetes'.
@@ -113,12 +196,17 @@ func (o *Options) AddFlags(f *flag.FlagSet) {
 	f.StringVar(&o.MetricsCertFile, "metrics-cert-file", "", "The path to a certificate to use for serving the metrics server over HTTPS. If the certificate is signed by a certificate authority, this file should be the concatenation of the server's certificate, any intermediates, and the CA's certificate. If this is non-empty, --http-endpoint and --metrics-key-file MUST also be non-empty.")
 	f.StringVar(&o.MetricsKeyFile, "metrics-key-file", "", "The path to a key to use for serving the metrics server over HTTPS. If this is non-empty, --http-endpoint and --metrics-cert-file MUST also be non-empty.")
 	f.BoolVar(&o.EnableOtelTracing, "enable-otel-tracing", false, "To enable opentelemetry tracing for the driver. The tracing is disabled by default. Configure the exporter endpoint with OTEL_EXPORTER_OTLP_ENDPOINT and other env variables, see https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/#general-sdk-configuration.")
+	f.DurationVar(&o.GracefulShutdownTimeout, "graceful-shutdown-timeout", 0, "How long to wait for in-flight RPCs, such as volume attachments, to finish after receiving SIGTERM while no new RPCs are accepted. The default of 0 exits immediately.")
+	f.StringSliceVar(&o.ReservedDeviceNames, "reserved-device-names", nil, "Comma separated list of device names, such as /dev/xvdba, that are used by volumes attached outside of the driver. The controller never assigns these names and each of them is subtracted from the volume attach limit of the node. Must be set to the same value for the controller and the node.")
 	f.StringSliceVar(&o.MetadataSources, "metadata-sources", metadata.DefaultMetadataSources, "Dictates which sources are used to retrieve instance metadata. The driver will attempt to rely on each source in order until one succeeds. Valid options include 'imds', 'kubernetes', and (ALPHA) 'metadata-labeler'.")
 
 	// AWS SDK options, shared by all modes that create a cloud client
 	if o.Mode == AllMode || o.Mode == ControllerMode || o.Mode == MetadataLabelerMode {
 		f.StringVar(&o.UserAgentExtra, "user-agent-extra", "", "Extra string appended to user agent.")
 		f.BoolVar(&o.AwsSdkDebugLog, "aws-sdk-debug-log", false, "To enable the aws sdk debug log level (default to false).")
+		f.IntVar(&o.AwsMaxIdleConnsPerHost, "aws-max-idle-conns-per-host", awshttp.DefaultHTTPTransportMaxIdleConnsPerHost, "Maximum number of idle connections to each AWS API endpoint kept open for reuse. Raise this for controllers that issue many concurrent EC2 API calls.")
+		f.DurationVar(&o.AwsIdleConnTimeout, "aws-idle-conn-timeout", awshttp.DefaultHTTPTransportIdleConnTimeout, "How long an idle connection to an AWS API endpoint is kept open for reuse.")
+		f.StringVar(&o.AwsEC2Endpoint, "aws-ec2-endpoint", "", "URL of the EC2 API to use instead of the regional endpoint, for example a proxy in environments without direct access to the EC2 API. Takes precedence over the AWS_EC2_ENDPOINT environment variable.")
 	}
 
 	// Controller options
@@ -128,16 +216,40 @@ func (o *Options) AddFlags(f *flag.FlagSet) {
 		f.StringVar(&o.KubernetesClusterID, "k8s-tag-cluster-id", "", "ID of the Kubernetes cluster used for tagging provisioned EBS volumes (optional).")
 		f.BoolVar(&o.WarnOnInvalidTag, "warn-on-invalid-tag", false, "To warn on invalid tags, instead of returning an error")
 		f.BoolVar(&o.Batching, "batching", false, "To enable batching of API calls. This is especially helpful for improving performance in workloads that are sensitive to EC2 rate limits.")
+		f.IntVar(&o.BatchingVolumeMaxEntries, "batching-volume-max-entries", cloud.DefaultVolumeBatchMaxEntries, "Maximum number of volumes looked up by a single DescribeVolumes call when --batching is enabled. Must be between 1 and 500.")
+		f.DurationVar(&o.BatchingVolumeMaxDelay, "batching-volume-max-delay", cloud.DefaultVolumeBatchMaxDelay, "How long DescribeVolumes requests are collected before they are sent as one call when --batching is enabled. Higher values save EC2 API calls at the cost of latency.")
 		f.DurationVar(&o.ModifyVolumeRequestHandlerTimeout, "modify-volume-request-handler-timeout", DefaultModifyVolumeRequestHandlerTimeout, "Timeout for the window in which volume modification calls must be received in order for them to coalesce into a single volume modification call to AWS. This must be lower than the csi-resizer and volumemodifier timeouts")
 		f.BoolVar(&o.DeprecatedMetrics, "deprecated-metrics", false, "DEPRECATED: To enable deprecated metrics. This parameter is only for backward compatibility and may be removed in a future release.")
 		f.BoolVar(&o.EnableNodeLocalVolumes, "enable-node-local-volumes", false, "Enable support for node-local volumes that use pre-attached EBS volumes.")
+		f.BoolVar(&o.WaitForVolumeModificationBeforeSnapshot, "wait-for-volume-modification-before-snapshot", false, "Wait for an in-progress modification of the source volume to finish before creating a snapshot. When false, the snapshot is created right away and a warning is logged.")
+		f.BoolVar(&o.TagSnapshotsWithSourceVolume, "tag-snapshots-with-source-volume", false, "Tag snapshots with the availability zone (source-az) and type (source-volume-type) of their source volume. Requires an additional DescribeVolumes call per snapshot.")
+		f.StringToIntVar(&o.VolumeTypeAttachmentLimits, "volume-type-attachment-limits", nil, "Maximum number of volumes of a volume type attached to a node, checked by the controller in addition to the attachment limit of the node. It is a comma separated list of volume type and limit pairs like 'io2=16'. Attaching an additional volume of a capped type fails once the cap is reached. Requires an additional DescribeVolumes call per attachment when set.")
+		f.StringSliceVar(&o.AZFilter, "az-filter", nil, "Comma separated list of availability zones the controller creates volumes in. Requests for volumes in other zones are rejected. The default is empty, which means all zones are handled.")
 	}
 	// Node options
 	if o.Mode == AllMode || o.Mode == NodeMode {
 		f.Int64Var(&o.VolumeAttachLimit, "volume-attach-limit", -1, "Value for the maximum number of volumes attachable per node. If specified, the limit applies to all nodes and overrides --reserved-volume-attachments. If not specified, the value is approximated from the instance type.")
 		f.IntVar(&o.ReservedVolumeAttachments, "reserved-volume-attachments", -1, "Number of volume attachments reserved for system use. Not used when --volume-attach-limit is specified. The total amount of volume attachments for a node is computed as: <nr. of attachments for corresponding instance type> - <number of NICs, if relevant to the instance type> - <reserved-volume-attachments value>. When -1, the amount of reserved attachments is loaded from instance metadata that captured state at node boot and may include not only system disks but also CSI volumes.")
+		f.IntVar(&o.ReservedENISlots, "reserved-eni-slots", 0, "Number of attachment slots reserved for ENIs attached after the node starts, such as the ENIs a CNI attaches as pods are scheduled. Only subtracted from the volume attach limit of instance types whose attachment limit is shared with ENIs. Not used when --volume-attach-limit is specified.")
+		f.Int64Var(&o.OutpostVolumeAttachLimit, "outpost-volume-attach-limit", 0, "Value for the maximum number of volumes attachable per node on nodes running on AWS Outposts. Overridden by --volume-attach-limit. The default of 0 approximates the value from the instance type like on other nodes.")
 		f.BoolVar(&o.WindowsHostProcess, "windows-host-process", false, "ALPHA: Indicates whether the driver is running in a Windows privileged container")
 		f.BoolVar(&o.LegacyXFSProgs, "legacy-xfs", false, "Warning: This option will be removed in a future version of EBS CSI Driver. Formats XFS volumes with `bigtime=0,inobtcount=0,reflink=0,nrext64=0`, so that they can be mounted onto nodes with linux kernel ≤ v5.4. Volumes formatted with this option may experience issues after 2038, and will be unable to use some XFS features (for example, reflinks).")
+		f.DurationVar(&o.DeviceWaitBaseTimeout, "device-wait-base-timeout", 0, "How long to wait for the device of a volume to appear during NodeStageVolume. The default of 0 looks up the device once and lets the CO retry.")
+		f.DurationVar(&o.DeviceWaitTimeoutPerGiB, "device-wait-timeout-per-gib", 0, "Additional time to wait for the device of a volume restored from a snapshot to appear, per GiB of volume size. Added to --device-wait-base-timeout.")
+		f.BoolVar(&o.EnableRegionTopology, "enable-region-topology", false, "Report the topology.kubernetes.io/region topology segment in addition to the zone.")
+		f.StringSliceVar(&o.UnsupportedInstanceTypes, "unsupported-instance-types", nil, "Comma separated list of instance types, such as i3.metal, that volumes must not be attached to. Nodes of these types report a volume attach limit of 1, the lowest limit Kubernetes enforces, regardless of --volume-attach-limit.")
+		f.IntVar(&o.NonNitroMaxAttachments, "non-nitro-max-attachments", limits.NonNitroMaxAttachments, "Maximum number of volume attachments of instance types not built on the Nitro System, before reserved attachments are subtracted. Only change for accounts where this limit is known to differ.")
+		f.IntVar(&o.NitroMaxAttachments, "nitro-max-attachments", limits.NitroMaxAttachments, "Maximum number of attachments of Nitro instance types the driver has no limit for, shared with ENIs and instance store volumes. Only change for accounts where this limit is known to differ.")
+		f.IntVar(&o.UnknownInstanceFamilyMaxAttachments, "unknown-instance-family-max-attachments", 0, "Maximum number of attachments, shared with ENIs, of instance types whose family the driver has no limits for, such as families released after the driver. The default of 0 assumes that they are built on the Nitro System and uses --nitro-max-attachments. Set a lower value to stay on the safe side until the driver knows the family.")
+		f.Int64Var(&o.MaxAdvertisedAttachments, "max-advertised-attachments", 0, "Maximum volume attach limit the node reports when the limit is computed from the instance type, for instance types whose attachment limit is higher than the number of volumes a node can manage. Not used when --volume-attach-limit is specified. The default of 0 reports the computed limit.")
+		f.StringVar(&o.DebugVolumeLimitsEndpoint, "debug-volume-limits-endpoint", "", "The TCP network address where the node serves how its volume attach limit was resolved as JSON at /debug/volume-limits (example: `:8081`). The default is empty string, which means the endpoint is disabled.")
+		f.BoolVar(&o.ReconcileCSINodeAllocatable, "reconcile-csinode-allocatable", false, "Overwrite the allocatable volume count of the CSINode of the node on startup with the volume attach limit computed by the driver, so that a changed limit takes effect without recreating the node. Requires the patch permission on csinodes and a Kubernetes version where the allocatable count of CSINodes is mutable.")
+		f.StringSliceVar(&o.SharedLimitInstanceFamilies, "shared-limit-instance-families", nil, "Comma separated list of instance families, such as m7i, whose attachment limit is shared with ENIs, for accounts where the attachment limit of these families is shared even though the driver considers it dedicated. Attached ENIs are subtracted from the volume attach limit of nodes of these families.")
+		f.VarPF(&invertedBool{value: &o.ReleaseInstanceStoreSlots}, "count-instance-store-as-attachments", "", "Whether NVMe instance store volumes take up attachment slots on instance types with a shared attachment limit. Only set to false after verifying that the instance store volumes of your instances do not consume EBS attachments, as the node would report a limit it cannot reach otherwise.").NoOptDefVal = "true"
+		f.BoolVar(&o.DetectInstanceStoreVolumes, "detect-instance-store-volumes", false, "Count the NVMe instance store volumes attached to the node instead of assuming that all instance store volumes of the instance type are attached, for instances launched without some of them. Falls back to the instance type when they cannot be counted. Linux only.")
+		f.BoolVar(&o.DescribeInstanceTypeAccelerators, "describe-instance-type-accelerators", false, "Look up the GPUs and inference accelerators of the instance type of the node with DescribeInstanceTypes on startup and when the instance type changes, and subtract the ones that take up attachment slots from the volume attach limit of instance types whose attachment limit is shared. Requires the ec2:DescribeInstanceTypes permission on the node. Falls back to the GPU counts built into the driver when the call fails.")
+		f.BoolVar(&o.DescribeInstanceTypeHypervisor, "describe-instance-type-hypervisor", false, "Look up the hypervisor of the instance type of the node with DescribeInstanceTypes, instead of the Nitro instance types built into the driver, to decide whether the attachment limit of Nitro or of non-Nitro instances applies. Requires the ec2:DescribeInstanceTypes permission on the node. Falls back to the limits built into the driver when the call fails.")
+		f.BoolVar(&o.NVMeHealthCheck, "nvme-health-check", false, "Fail the health check of the node on Nitro instances when the NVMe driver is not loaded or /dev/disk/by-id does not exist, as attached volumes could not be found. Linux only.")
 		f.StringVar(&o.CsiMountPointPath, "csi-mount-point-prefix", "", "A prefix of the mountpoints of all CSI-managed volumes. If this value is non-empty, all volumes mounted to a path beginning with the provided value are assumed to be CSI volumes owned by the EBS CSI Driver and safe to treat as such (for example, by exposing volume metrics).")
 	}
 }
@@ -147,6 +259,75 @@ func (o *Options) Validate() error {
 		if o.VolumeAttachLimit != -1 && o.ReservedVolumeAttachments != -1 {
 			return errors.New("only one of --volume-attach-limit and --reserved-volume-attachments may be specified")
 		}
+		if o.ReservedENISlots < 0 {
+			return errors.New("--reserved-eni-slots must not be negative")
+		}
+		if o.OutpostVolumeAttachLimit < 0 {
+			return errors.New("--outpost-volume-attach-limit must not be negative")
+		}
+		if o.DeviceWaitBaseTimeout < 0 || o.DeviceWaitTimeoutPerGiB < 0 {
+			return errors.New("--device-wait-base-timeout and --device-wait-timeout-per-gib must not be negative")
+		}
+		if o.NonNitroMaxAttachments < 1 || o.NitroMaxAttachments < 1 {
+			return errors.New("--non-nitro-max-attachments and --nitro-max-attachments must be positive")
+		}
+		if o.UnknownInstanceFamilyMaxAttachments < 0 {
+			return errors.New("--unknown-instance-family-max-attachments must not be negative")
+		}
+		if o.MaxAdvertisedAttachments < 0 {
+			return errors.New("--max-advertised-attachments must not be negative")
+		}
+	}
+
+	if o.Mode == AllMode || o.Mode == ControllerMode {
+		if o.BatchingVolumeMaxEntries < 1 || o.BatchingVolumeMaxEntries > cloud.DefaultVolumeBatchMaxEntries {
+			return fmt.Errorf("--batching-volume-max-entries must be between 1 and %d", cloud.DefaultVolumeBatchMaxEntries)
+		}
+		if o.BatchingVolumeMaxDelay <= 0 {
+			return errors.New("--batching-volume-max-delay must be positive")
+		}
+		for volumeType, limit := range o.VolumeTypeAttachmentLimits {
+			if !slices.Contains(cloud.ValidVolumeTypes, volumeType) {
+				return fmt.Errorf("--volume-type-attachment-limits: %q is not a volume type", volumeType)
+			}
+			if limit < 1 {
+				return fmt.Errorf("--volume-type-attachment-limits: the limit of %s must be positive", volumeType)
+			}
+		}
+	}
+
+	if o.GracefulShutdownTimeout < 0 {
+		return errors.New("--graceful-shutdown-timeout must not be negative")
+	}
+
+	for _, family := range o.SharedLimitInstanceFamilies {
+		if family == "" || strings.Contains(family, ".") {
+			return fmt.Errorf("--shared-limit-instance-families: %q is not an instance family, such as m7i", family)
+		}
+	}
+
+	for i, instanceType := range o.UnsupportedInstanceTypes {
+		instanceType = limits.NormalizeInstanceType(instanceType)
+		if instanceType == "" || !strings.Contains(instanceType, ".") {
+			return fmt.Errorf("--unsupported-instance-types: %q is not an instance type, such as i3.metal", o.UnsupportedInstanceTypes[i])
+		}
+		o.UnsupportedInstanceTypes[i] = instanceType
+	}
+
+	for _, name := range o.ReservedDeviceNames {
+		if !strings.HasPrefix(name, "/dev/") {
+			return fmt.Errorf("--reserved-device-names: %q is not a device name, it must start with /dev/", name)
+		}
+	}
+
+	if o.AwsMaxIdleConnsPerHost < 0 || o.AwsIdleConnTimeout < 0 {
+		return errors.New("--aws-max-idle-conns-per-host and --aws-idle-conn-timeout must not be negative")
+	}
+
+	if o.AwsEC2Endpoint != "" {
+		if u, err := url.Parse(o.AwsEC2Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("--aws-ec2-endpoint: %q is not an http or https URL", o.AwsEC2Endpoint)
+		}
 	}
 
 	if o.MetricsCertFile != "" || o.MetricsKeyFile != "" {
@@ -172,3 +353,29 @@ func (o *Options) Validate() error {
 
 	return nil
 }
+
+// invertedBool is a boolean flag that sets the negation of its value, for flags whose default is true
+// but whose option must default to false.
+type invertedBool struct {
+	value *bool
+}
+
+func (b *invertedBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.value = !v
+	return nil
+}
+
+func (b *invertedBool) String() string {
+	if b.value == nil {
+		return "true"
+	}
+	return strconv.FormatBool(!*b.value)
+}
+
+func (b *invertedBool) Type() string {
+	return "bool"
+}
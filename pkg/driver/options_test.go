@@ -15,9 +15,11 @@
 package driver
 
 import (
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud/metadata"
 	flag "github.com/spf13/pflag"
 )
@@ -41,9 +43,21 @@ func TestAddFlags(t *testing.T) {
 	if err := f.Set("metrics-key-file", "/https.key"); err != nil {
 		t.Errorf("error setting metrics-key-file: %v", err)
 	}
+	if err := f.Set("graceful-shutdown-timeout", "20s"); err != nil {
+		t.Errorf("error setting graceful-shutdown-timeout: %v", err)
+	}
+	if err := f.Set("reserved-device-names", "/dev/xvdba,/dev/xvdbb"); err != nil {
+		t.Errorf("error setting reserved-device-names: %v", err)
+	}
 	if err := f.Set("enable-otel-tracing", "true"); err != nil {
 		t.Errorf("error setting enable-otel-tracing: %v", err)
 	}
+	if err := f.Set("batching-volume-max-entries", "100"); err != nil {
+		t.Errorf("error setting batching-volume-max-entries: %v", err)
+	}
+	if err := f.Set("batching-volume-max-delay", "1s"); err != nil {
+		t.Errorf("error setting batching-volume-max-delay: %v", err)
+	}
 	if err := f.Set("extra-tags", "key1=value1,key2=value2"); err != nil {
 		t.Errorf("error setting extra-tags: %v", err)
 	}
@@ -53,6 +67,15 @@ func TestAddFlags(t *testing.T) {
 	if err := f.Set("aws-sdk-debug-log", "true"); err != nil {
 		t.Errorf("error setting aws-sdk-debug-log: %v", err)
 	}
+	if err := f.Set("aws-max-idle-conns-per-host", "64"); err != nil {
+		t.Errorf("error setting aws-max-idle-conns-per-host: %v", err)
+	}
+	if err := f.Set("aws-idle-conn-timeout", "2m"); err != nil {
+		t.Errorf("error setting aws-idle-conn-timeout: %v", err)
+	}
+	if err := f.Set("aws-ec2-endpoint", "https://ec2-proxy.example.com"); err != nil {
+		t.Errorf("error setting aws-ec2-endpoint: %v", err)
+	}
 	if err := f.Set("deprecated-metrics", "true"); err != nil {
 		t.Errorf("error setting deprecated-metrics: %v", err)
 	}
@@ -74,12 +97,78 @@ func TestAddFlags(t *testing.T) {
 	if err := f.Set("reserved-volume-attachments", "5"); err != nil {
 		t.Errorf("error setting reserved-volume-attachments: %v", err)
 	}
+	if err := f.Set("reserved-eni-slots", "3"); err != nil {
+		t.Errorf("error setting reserved-eni-slots: %v", err)
+	}
+	if err := f.Set("outpost-volume-attach-limit", "12"); err != nil {
+		t.Errorf("error setting outpost-volume-attach-limit: %v", err)
+	}
 	if err := f.Set("legacy-xfs", "true"); err != nil {
 		t.Errorf("error setting legacy-xfs: %v", err)
 	}
+	if err := f.Set("device-wait-base-timeout", "30s"); err != nil {
+		t.Errorf("error setting device-wait-base-timeout: %v", err)
+	}
+	if err := f.Set("device-wait-timeout-per-gib", "100ms"); err != nil {
+		t.Errorf("error setting device-wait-timeout-per-gib: %v", err)
+	}
+	if err := f.Set("enable-region-topology", "true"); err != nil {
+		t.Errorf("error setting enable-region-topology: %v", err)
+	}
+	if err := f.Set("unsupported-instance-types", "i3.metal,m5.metal"); err != nil {
+		t.Errorf("error setting unsupported-instance-types: %v", err)
+	}
+	if err := f.Set("non-nitro-max-attachments", "30"); err != nil {
+		t.Errorf("error setting non-nitro-max-attachments: %v", err)
+	}
+	if err := f.Set("nitro-max-attachments", "28"); err != nil {
+		t.Errorf("error setting nitro-max-attachments: %v", err)
+	}
+	if err := f.Set("unknown-instance-family-max-attachments", "16"); err != nil {
+		t.Errorf("error setting unknown-instance-family-max-attachments: %v", err)
+	}
+	if err := f.Set("max-advertised-attachments", "64"); err != nil {
+		t.Errorf("error setting max-advertised-attachments: %v", err)
+	}
+	if err := f.Set("debug-volume-limits-endpoint", ":8081"); err != nil {
+		t.Errorf("error setting debug-volume-limits-endpoint: %v", err)
+	}
+	if err := f.Set("reconcile-csinode-allocatable", "true"); err != nil {
+		t.Errorf("error setting reconcile-csinode-allocatable: %v", err)
+	}
+	if err := f.Set("shared-limit-instance-families", "m7i,c7i"); err != nil {
+		t.Errorf("error setting shared-limit-instance-families: %v", err)
+	}
+	if err := f.Set("count-instance-store-as-attachments", "false"); err != nil {
+		t.Errorf("error setting count-instance-store-as-attachments: %v", err)
+	}
+	if err := f.Set("detect-instance-store-volumes", "true"); err != nil {
+		t.Errorf("error setting detect-instance-store-volumes: %v", err)
+	}
+	if err := f.Set("describe-instance-type-accelerators", "true"); err != nil {
+		t.Errorf("error setting describe-instance-type-accelerators: %v", err)
+	}
+	if err := f.Set("describe-instance-type-hypervisor", "true"); err != nil {
+		t.Errorf("error setting describe-instance-type-hypervisor: %v", err)
+	}
+	if err := f.Set("nvme-health-check", "true"); err != nil {
+		t.Errorf("error setting nvme-health-check: %v", err)
+	}
 	if err := f.Set("enable-node-local-volumes", "true"); err != nil {
 		t.Errorf("error setting enable-node-local-volumes: %v", err)
 	}
+	if err := f.Set("wait-for-volume-modification-before-snapshot", "true"); err != nil {
+		t.Errorf("error setting wait-for-volume-modification-before-snapshot: %v", err)
+	}
+	if err := f.Set("tag-snapshots-with-source-volume", "true"); err != nil {
+		t.Errorf("error setting tag-snapshots-with-source-volume: %v", err)
+	}
+	if err := f.Set("az-filter", "us-east-1a,us-east-1b"); err != nil {
+		t.Errorf("error setting az-filter: %v", err)
+	}
+	if err := f.Set("volume-type-attachment-limits", "io2=16"); err != nil {
+		t.Errorf("error setting volume-type-attachment-limits: %v", err)
+	}
 
 	if err := f.Set("csi-mount-point-prefix", "/var/lib/kubelet"); err != nil {
 		t.Errorf("error setting csi-mount-point-prefix: %v", err)
@@ -94,6 +183,18 @@ func TestAddFlags(t *testing.T) {
 	if !o.EnableOtelTracing {
 		t.Error("unexpected EnableOtelTracing: got false, want true")
 	}
+	if o.GracefulShutdownTimeout != 20*time.Second {
+		t.Errorf("unexpected GracefulShutdownTimeout: got %v, want 20s", o.GracefulShutdownTimeout)
+	}
+	if !slices.Equal(o.ReservedDeviceNames, []string{"/dev/xvdba", "/dev/xvdbb"}) {
+		t.Errorf("unexpected ReservedDeviceNames: got %v, want [/dev/xvdba /dev/xvdbb]", o.ReservedDeviceNames)
+	}
+	if o.BatchingVolumeMaxEntries != 100 {
+		t.Errorf("unexpected BatchingVolumeMaxEntries: got %d, want 100", o.BatchingVolumeMaxEntries)
+	}
+	if o.BatchingVolumeMaxDelay != time.Second {
+		t.Errorf("unexpected BatchingVolumeMaxDelay: got %v, want 1s", o.BatchingVolumeMaxDelay)
+	}
 	if len(o.ExtraTags) != 2 || o.ExtraTags["key1"] != "value1" || o.ExtraTags["key2"] != "value2" {
 		t.Errorf("unexpected ExtraTags: got %v, want map[key1:value1 key2:value2]", o.ExtraTags)
 	}
@@ -103,6 +204,15 @@ func TestAddFlags(t *testing.T) {
 	if !o.AwsSdkDebugLog {
 		t.Error("unexpected AwsSdkDebugLog: got false, want true")
 	}
+	if o.AwsMaxIdleConnsPerHost != 64 {
+		t.Errorf("unexpected AwsMaxIdleConnsPerHost: got %d, want 64", o.AwsMaxIdleConnsPerHost)
+	}
+	if o.AwsIdleConnTimeout != 2*time.Minute {
+		t.Errorf("unexpected AwsIdleConnTimeout: got %v, want 2m", o.AwsIdleConnTimeout)
+	}
+	if o.AwsEC2Endpoint != "https://ec2-proxy.example.com" {
+		t.Errorf("unexpected AwsEC2Endpoint: got %q, want https://ec2-proxy.example.com", o.AwsEC2Endpoint)
+	}
 	if !o.WarnOnInvalidTag {
 		t.Error("unexpected WarnOnInvalidTag: got false, want true")
 	}
@@ -121,12 +231,78 @@ func TestAddFlags(t *testing.T) {
 	if o.ReservedVolumeAttachments != 5 {
 		t.Errorf("unexpected ReservedVolumeAttachments: got %d, want 5", o.ReservedVolumeAttachments)
 	}
+	if o.ReservedENISlots != 3 {
+		t.Errorf("unexpected ReservedENISlots: got %d, want 3", o.ReservedENISlots)
+	}
+	if o.OutpostVolumeAttachLimit != 12 {
+		t.Errorf("unexpected OutpostVolumeAttachLimit: got %d, want 12", o.OutpostVolumeAttachLimit)
+	}
 	if !o.LegacyXFSProgs {
 		t.Errorf("unexpected LegacyXFSProgs: got false, want true")
 	}
+	if o.DeviceWaitBaseTimeout != 30*time.Second {
+		t.Errorf("unexpected DeviceWaitBaseTimeout: got %v, want 30s", o.DeviceWaitBaseTimeout)
+	}
+	if o.DeviceWaitTimeoutPerGiB != 100*time.Millisecond {
+		t.Errorf("unexpected DeviceWaitTimeoutPerGiB: got %v, want 100ms", o.DeviceWaitTimeoutPerGiB)
+	}
+	if !o.EnableRegionTopology {
+		t.Error("unexpected EnableRegionTopology: got false, want true")
+	}
+	if !slices.Equal(o.UnsupportedInstanceTypes, []string{"i3.metal", "m5.metal"}) {
+		t.Errorf("unexpected UnsupportedInstanceTypes: got %v, want [i3.metal m5.metal]", o.UnsupportedInstanceTypes)
+	}
+	if o.NonNitroMaxAttachments != 30 {
+		t.Errorf("unexpected NonNitroMaxAttachments: got %d, want 30", o.NonNitroMaxAttachments)
+	}
+	if o.NitroMaxAttachments != 28 {
+		t.Errorf("unexpected NitroMaxAttachments: got %d, want 28", o.NitroMaxAttachments)
+	}
+	if o.UnknownInstanceFamilyMaxAttachments != 16 {
+		t.Errorf("unexpected UnknownInstanceFamilyMaxAttachments: got %d, want 16", o.UnknownInstanceFamilyMaxAttachments)
+	}
+	if o.MaxAdvertisedAttachments != 64 {
+		t.Errorf("unexpected MaxAdvertisedAttachments: got %d, want 64", o.MaxAdvertisedAttachments)
+	}
+	if o.DebugVolumeLimitsEndpoint != ":8081" {
+		t.Errorf("unexpected DebugVolumeLimitsEndpoint: got %s, want :8081", o.DebugVolumeLimitsEndpoint)
+	}
+	if !o.ReconcileCSINodeAllocatable {
+		t.Errorf("unexpected ReconcileCSINodeAllocatable: got false, want true")
+	}
+	if !slices.Equal(o.SharedLimitInstanceFamilies, []string{"m7i", "c7i"}) {
+		t.Errorf("unexpected SharedLimitInstanceFamilies: got %v, want [m7i c7i]", o.SharedLimitInstanceFamilies)
+	}
+	if !o.ReleaseInstanceStoreSlots {
+		t.Error("unexpected ReleaseInstanceStoreSlots: got false, want true")
+	}
+	if !o.DetectInstanceStoreVolumes {
+		t.Error("unexpected DetectInstanceStoreVolumes: got false, want true")
+	}
+	if !o.DescribeInstanceTypeAccelerators {
+		t.Error("unexpected DescribeInstanceTypeAccelerators: got false, want true")
+	}
+	if !o.DescribeInstanceTypeHypervisor {
+		t.Error("unexpected DescribeInstanceTypeHypervisor: got false, want true")
+	}
+	if !o.NVMeHealthCheck {
+		t.Error("unexpected NVMeHealthCheck: got false, want true")
+	}
 	if !o.EnableNodeLocalVolumes {
 		t.Error("unexpected EnableNodeLocalVolumes: got false, want true")
 	}
+	if !o.WaitForVolumeModificationBeforeSnapshot {
+		t.Error("unexpected WaitForVolumeModificationBeforeSnapshot: got false, want true")
+	}
+	if !o.TagSnapshotsWithSourceVolume {
+		t.Error("unexpected TagSnapshotsWithSourceVolume: got false, want true")
+	}
+	if len(o.AZFilter) != 2 || o.AZFilter[0] != "us-east-1a" || o.AZFilter[1] != "us-east-1b" {
+		t.Errorf("unexpected AZFilter: got %v, want [us-east-1a us-east-1b]", o.AZFilter)
+	}
+	if len(o.VolumeTypeAttachmentLimits) != 1 || o.VolumeTypeAttachmentLimits["io2"] != 16 {
+		t.Errorf("unexpected VolumeTypeAttachmentLimits: got %v, want map[io2:16]", o.VolumeTypeAttachmentLimits)
+	}
 }
 
 func TestAddFlagsMetadataLabelerMode(t *testing.T) {
@@ -173,6 +349,7 @@ func TestValidateAttachmentLimits(t *testing.T) {
 		name                string
 		volumeAttachLimit   int64
 		reservedAttachments int
+		reservedENISlots    int
 		expectedErr         bool
 		errMsg              string
 	}{
@@ -201,6 +378,21 @@ func TestValidateAttachmentLimits(t *testing.T) {
 			expectedErr:         true,
 			errMsg:              "only one of --volume-attach-limit and --reserved-volume-attachments may be specified",
 		},
+		{
+			name:                "reservedENISlots set",
+			volumeAttachLimit:   -1,
+			reservedAttachments: -1,
+			reservedENISlots:    2,
+			expectedErr:         false,
+		},
+		{
+			name:                "negative reservedENISlots",
+			volumeAttachLimit:   -1,
+			reservedAttachments: -1,
+			reservedENISlots:    -1,
+			expectedErr:         true,
+			errMsg:              "--reserved-eni-slots must not be negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -214,6 +406,7 @@ func TestValidateAttachmentLimits(t *testing.T) {
 			// Override with test flags
 			o.VolumeAttachLimit = tt.volumeAttachLimit
 			o.ReservedVolumeAttachments = tt.reservedAttachments
+			o.ReservedENISlots = tt.reservedENISlots
 
 			err := o.Validate()
 			if (err != nil) != tt.expectedErr {
@@ -289,6 +482,338 @@ func TestValidateMetricsHTTPS(t *testing.T) {
 	}
 }
 
+func TestValidateBatchingVolume(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxEntries  int
+		maxDelay    time.Duration
+		expectError bool
+	}{
+		{
+			name:       "defaults",
+			maxEntries: cloud.DefaultVolumeBatchMaxEntries,
+			maxDelay:   cloud.DefaultVolumeBatchMaxDelay,
+		},
+		{
+			name:        "max entries above DescribeVolumes page size",
+			maxEntries:  501,
+			maxDelay:    cloud.DefaultVolumeBatchMaxDelay,
+			expectError: true,
+		},
+		{
+			name:        "zero max entries",
+			maxDelay:    cloud.DefaultVolumeBatchMaxDelay,
+			expectError: true,
+		},
+		{
+			name:        "zero max delay",
+			maxEntries:  cloud.DefaultVolumeBatchMaxEntries,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = ControllerMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.BatchingVolumeMaxEntries = tt.maxEntries
+			o.BatchingVolumeMaxDelay = tt.maxDelay
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateVolumeTypeAttachmentLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		limits      map[string]int
+		expectError bool
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:   "io2 cap",
+			limits: map[string]int{"io2": 16},
+		},
+		{
+			name:        "unknown volume type",
+			limits:      map[string]int{"io3": 16},
+			expectError: true,
+		},
+		{
+			name:        "zero cap",
+			limits:      map[string]int{"io2": 0},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = ControllerMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.VolumeTypeAttachmentLimits = tt.limits
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateAwsEC2Endpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		endpoint    string
+		expectError bool
+	}{
+		{
+			name: "unset",
+		},
+		{
+			name:     "https proxy",
+			endpoint: "https://ec2-proxy.example.com:8443",
+		},
+		{
+			name:        "missing scheme",
+			endpoint:    "ec2-proxy.example.com",
+			expectError: true,
+		},
+		{
+			name:        "unsupported scheme",
+			endpoint:    "ftp://ec2-proxy.example.com",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = ControllerMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.AwsEC2Endpoint = tt.endpoint
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateReservedDeviceNames(t *testing.T) {
+	tests := []struct {
+		name                string
+		reservedDeviceNames []string
+		expectError         bool
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:                "device names",
+			reservedDeviceNames: []string{"/dev/xvdba", "/dev/xvdbb"},
+		},
+		{
+			name:                "name without /dev/ prefix",
+			reservedDeviceNames: []string{"/dev/xvdba", "xvdbb"},
+			expectError:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = AllMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.ReservedDeviceNames = tt.reservedDeviceNames
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateUnsupportedInstanceTypes(t *testing.T) {
+	tests := []struct {
+		name                     string
+		unsupportedInstanceTypes []string
+		expected                 []string
+		expectError              bool
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:                     "instance types are normalized",
+			unsupportedInstanceTypes: []string{" I3.Metal", "m5.metal\n"},
+			expected:                 []string{"i3.metal", "m5.metal"},
+		},
+		{
+			name:                     "instance family",
+			unsupportedInstanceTypes: []string{"i3.metal", "m5"},
+			expectError:              true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = AllMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.UnsupportedInstanceTypes = tt.unsupportedInstanceTypes
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+			if err == nil && !slices.Equal(o.UnsupportedInstanceTypes, tt.expected) {
+				t.Errorf("unexpected UnsupportedInstanceTypes: got %v, want %v", o.UnsupportedInstanceTypes, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateMaxAttachments(t *testing.T) {
+	tests := []struct {
+		name                   string
+		nonNitroMaxAttachments int
+		nitroMaxAttachments    int
+		unknownFamilyLimit     int
+		maxAdvertised          int64
+		expectError            bool
+	}{
+		{
+			name:                   "defaults",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    27,
+		},
+		{
+			name:                   "overridden",
+			nonNitroMaxAttachments: 30,
+			nitroMaxAttachments:    28,
+		},
+		{
+			name:                   "zero non-Nitro limit",
+			nonNitroMaxAttachments: 0,
+			nitroMaxAttachments:    27,
+			expectError:            true,
+		},
+		{
+			name:                   "negative Nitro limit",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    -1,
+			expectError:            true,
+		},
+		{
+			name:                   "unknown family limit",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    27,
+			unknownFamilyLimit:     16,
+		},
+		{
+			name:                   "negative unknown family limit",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    27,
+			unknownFamilyLimit:     -1,
+			expectError:            true,
+		},
+		{
+			name:                   "max advertised attachments",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    27,
+			maxAdvertised:          64,
+		},
+		{
+			name:                   "negative max advertised attachments",
+			nonNitroMaxAttachments: 39,
+			nitroMaxAttachments:    27,
+			maxAdvertised:          -1,
+			expectError:            true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = NodeMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.NonNitroMaxAttachments = tt.nonNitroMaxAttachments
+			o.NitroMaxAttachments = tt.nitroMaxAttachments
+			o.UnknownInstanceFamilyMaxAttachments = tt.unknownFamilyLimit
+			o.MaxAdvertisedAttachments = tt.maxAdvertised
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateSharedLimitInstanceFamilies(t *testing.T) {
+	tests := []struct {
+		name        string
+		families    []string
+		expectError bool
+	}{
+		{
+			name: "not set",
+		},
+		{
+			name:     "families",
+			families: []string{"m7i", "c7i"},
+		},
+		{
+			name:        "instance type",
+			families:    []string{"m7i", "c7i.large"},
+			expectError: true,
+		},
+		{
+			name:        "empty family",
+			families:    []string{""},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Options{}
+			o.Mode = AllMode
+			f := flag.NewFlagSet("test", flag.ExitOnError)
+			o.AddFlags(f)
+
+			o.SharedLimitInstanceFamilies = tt.families
+
+			err := o.Validate()
+			if (err != nil) != tt.expectError {
+				t.Errorf("Options.Validate() error = %v, wantErr %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
 func TestValidateMetadataSources(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -178,6 +178,7 @@ func testRequestFail(t *testing.T, executor modifyVolumeExecutor) {
 		klog.InfoS("ResizeOrModifyDisk called", "volumeID", volumeID, "newSize", newSize, "options", options)
 		return 0, errors.New("ResizeOrModifyDisk failed")
 	})
+	mockCloud.EXPECT().GetVolumeModificationProgress(testutil.AnyContext(), gomock.Eq(volumeID)).Return("", 0, cloud.ErrVolumeNotBeingModified)
 
 	options := &Options{
 		ModifyVolumeRequestHandlerTimeout: 2 * time.Second,
@@ -19,6 +19,7 @@ package driver
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/kubernetes-sigs/aws-ebs-csi-driver/pkg/cloud"
@@ -69,6 +70,68 @@ func validateExtraTags(tags map[string]string, warnOnly bool) error {
 	return nil
 }
 
+// ValidateParameters checks the parameters of a CreateVolume request (i.e. the StorageClass parameters)
+// without calling AWS, so that they can also be checked ahead of time, for example by an admission webhook.
+// Parameters whose validity depends on the volume capabilities or on EC2 itself are not checked here.
+func ValidateParameters(params map[string]string) error {
+	var (
+		volumeType string
+		iops       int32
+		iopsPerGB  int32
+		throughput int32
+	)
+
+	for key, value := range params {
+		var err error
+		switch strings.ToLower(key) {
+		case "fstype", AllowAutoIOPSIncreaseOnModifyKey, AllowAutoIOPSPerGBIncreaseKey, EncryptedKey, KmsKeyIDKey,
+			PVCNameKey, PVCNamespaceKey, PVNameKey, DeprecatedBlockExpressKey, Ext4BigAllocKey, Ext4EncryptionSupportKey,
+			BlockAttachUntilInitializedKey:
+		case VolumeTypeKey:
+			volumeType = value
+		case IopsPerGBKey:
+			iopsPerGB, err = parseInt32Parameter(key, value)
+		case IopsKey:
+			iops, err = parseInt32Parameter(key, value)
+		case ThroughputKey:
+			throughput, err = parseInt32Parameter(key, value)
+		case VolumeInitializationRateKey:
+			_, err = parseInt32Parameter(key, value)
+		case BlockSizeKey, InodeSizeKey, BytesPerInodeKey, NumberOfInodesKey, Ext4ClusterSizeKey:
+			if !util.StringIsAlphanumeric(value) {
+				err = fmt.Errorf("could not parse %s (%s): value must be alphanumeric", key, value)
+			}
+		default:
+			if !strings.HasPrefix(key, TagKeyPrefix) {
+				return fmt.Errorf("invalid parameter key %s for CreateVolume", key)
+			}
+			if !strings.Contains(value, "=") {
+				err = fmt.Errorf("invalid %s %q: the key-value pair doesn't contain a value", key, value)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if iops > 0 && iopsPerGB > 0 {
+		return fmt.Errorf("specify either %s or %s, not both", IopsKey, IopsPerGBKey)
+	}
+	if throughput > 0 && volumeType != "" && !strings.EqualFold(volumeType, cloud.VolumeTypeGP3) {
+		return fmt.Errorf("%s is only supported for %s volumes, not %s", ThroughputKey, cloud.VolumeTypeGP3, volumeType)
+	}
+
+	return nil
+}
+
+func parseInt32Parameter(key, value string) (int32, error) {
+	v, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse invalid %s: %w", key, err)
+	}
+	return int32(v), nil
+}
+
 func validateMode(mode Mode) error {
 	if mode != AllMode && mode != ControllerMode && mode != NodeMode {
 		return fmt.Errorf("mode is not supported (actual: %s, supported: %v)", mode, []Mode{AllMode, ControllerMode, NodeMode})
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -196,3 +197,191 @@ func TestValidateDriverOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateParameters(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params map[string]string
+		expErr string
+	}{
+		{
+			name:   "success: no parameters",
+			params: nil,
+		},
+		{
+			name: "success: all supported parameters",
+			params: map[string]string{
+				VolumeTypeKey:                    cloud.VolumeTypeIO2,
+				IopsKey:                          "5000",
+				VolumeInitializationRateKey:      "200",
+				AllowAutoIOPSIncreaseOnModifyKey: "true",
+				AllowAutoIOPSPerGBIncreaseKey:    "true",
+				EncryptedKey:                     "true",
+				KmsKeyIDKey:                      "arn:aws:kms:us-east-1:012345678901:key/some-key",
+				PVCNameKey:                       "pvc",
+				PVCNamespaceKey:                  "default",
+				PVNameKey:                        "pv",
+				DeprecatedBlockExpressKey:        "true",
+				BlockSizeKey:                     "4096",
+				InodeSizeKey:                     "512",
+				BytesPerInodeKey:                 "8192",
+				NumberOfInodesKey:                "13107200",
+				Ext4BigAllocKey:                  "true",
+				Ext4ClusterSizeKey:               "16384",
+				Ext4EncryptionSupportKey:         "true",
+				BlockAttachUntilInitializedKey:   "false",
+				TagKeyPrefix + "_1":              "owner={{ .PVCNamespace }}",
+			},
+		},
+		{
+			name: "success: keys are case insensitive",
+			params: map[string]string{
+				"IOPSPerGB": "50",
+				"Type":      cloud.VolumeTypeIO1,
+			},
+		},
+		{
+			name: "success: throughput with gp3",
+			params: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeGP3,
+				ThroughputKey: "250",
+			},
+		},
+		{
+			name: "success: throughput without type defaults to gp3",
+			params: map[string]string{
+				ThroughputKey: "250",
+			},
+		},
+		{
+			name: "success: unknown volume type is left to EC2",
+			params: map[string]string{
+				VolumeTypeKey: "new-type",
+			},
+		},
+		{
+			name: "fail: unknown key",
+			params: map[string]string{
+				"unknownKey": "value",
+			},
+			expErr: "invalid parameter key unknownKey",
+		},
+		{
+			name: "fail: iops is not a number",
+			params: map[string]string{
+				IopsKey: "fast",
+			},
+			expErr: "could not parse invalid iops",
+		},
+		{
+			name: "fail: iops overflows int32",
+			params: map[string]string{
+				IopsKey: "9999999999",
+			},
+			expErr: "could not parse invalid iops",
+		},
+		{
+			name: "fail: iopsPerGB is not a number",
+			params: map[string]string{
+				IopsPerGBKey: "1.5",
+			},
+			expErr: "could not parse invalid iopspergb",
+		},
+		{
+			name: "fail: throughput is not a number",
+			params: map[string]string{
+				ThroughputKey: "125MiB",
+			},
+			expErr: "could not parse invalid throughput",
+		},
+		{
+			name: "fail: volumeInitializationRate is not a number",
+			params: map[string]string{
+				VolumeInitializationRateKey: "",
+			},
+			expErr: "could not parse invalid volumeinitializationrate",
+		},
+		{
+			name: "fail: iops and iopsPerGB",
+			params: map[string]string{
+				IopsKey:      "3000",
+				IopsPerGBKey: "10",
+			},
+			expErr: "specify either iops or iopspergb, not both",
+		},
+		{
+			name: "fail: throughput with gp2",
+			params: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeGP2,
+				ThroughputKey: "250",
+			},
+			expErr: "throughput is only supported for gp3 volumes, not gp2",
+		},
+		{
+			name: "fail: throughput with io2",
+			params: map[string]string{
+				VolumeTypeKey: cloud.VolumeTypeIO2,
+				IopsKey:       "3000",
+				ThroughputKey: "250",
+			},
+			expErr: "throughput is only supported for gp3 volumes, not io2",
+		},
+		{
+			name: "fail: blockSize is not alphanumeric",
+			params: map[string]string{
+				BlockSizeKey: "4096; rm -rf /",
+			},
+			expErr: "could not parse blocksize",
+		},
+		{
+			name: "fail: inodeSize is not alphanumeric",
+			params: map[string]string{
+				InodeSizeKey: "-512",
+			},
+			expErr: "could not parse inodesize",
+		},
+		{
+			name: "fail: bytesPerInode is not alphanumeric",
+			params: map[string]string{
+				BytesPerInodeKey: "8 192",
+			},
+			expErr: "could not parse bytesperinode",
+		},
+		{
+			name: "fail: numberOfInodes is not alphanumeric",
+			params: map[string]string{
+				NumberOfInodesKey: "1e6!",
+			},
+			expErr: "could not parse numberofinodes",
+		},
+		{
+			name: "fail: ext4ClusterSize is not alphanumeric",
+			params: map[string]string{
+				Ext4ClusterSizeKey: "16k/",
+			},
+			expErr: "could not parse ext4clustersize",
+		},
+		{
+			name: "fail: tag specification without value",
+			params: map[string]string{
+				TagKeyPrefix + "_1": "owner",
+			},
+			expErr: "the key-value pair doesn't contain a value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateParameters(tc.params)
+			if tc.expErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expErr) {
+				t.Fatalf("error not matching\ngot:\n%v\nexpected to contain:\n%s", err, tc.expErr)
+			}
+		})
+	}
+}
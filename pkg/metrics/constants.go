@@ -28,4 +28,9 @@ const (
 	DeprecatedAPIRequestDuration          = "cloudprovider_aws_api_request_duration_seconds"
 	DeprecatedAPIRequestErrors            = "cloudprovider_aws_api_request_errors"
 	DeprecatedAPIRequestThrottles         = "cloudprovider_aws_api_throttled_requests_total"
+
+	NodeAvailableAttachmentSlots         = "ebs_csi_node_available_attachment_slots"
+	NodeAvailableAttachmentSlotsHelpText = "Number of EBS volumes that can still be attached to the node, by instance type and node name"
+	NodeReservedSlots                    = "ebs_csi_node_reserved_slots"
+	NodeReservedSlotsHelpText            = "Number of attachment slots of the node taken by devices other than the volumes attached by the driver, by category, instance type and node name"
 )
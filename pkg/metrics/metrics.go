@@ -173,6 +173,90 @@ func (m *MetricRecorder) ObserveHistogram(name string, helpText string, value fl
 	}
 }
 
+// SetGauge sets the gauge metric to the given value.
+func (m *MetricRecorder) SetGauge(name string, helpText string, value float64, labels map[string]string) {
+	if m == nil {
+		return // recorder is not initialized
+	}
+
+	m.mu.RLock()
+	metric, ok := m.metrics[name]
+	m.mu.RUnlock()
+
+	if !ok {
+		klog.V(4).InfoS("Metric not found, registering", "name", name, "labels", labels)
+		m.registerGaugeVec(name, helpText, getLabelNames(labels))
+		m.SetGauge(name, helpText, value, labels)
+		return
+	}
+
+	metricAsGaugeVec, ok := metric.(*prometheus.GaugeVec)
+	if ok {
+		metricAsGaugeVec.With(labels).Set(value)
+	} else {
+		klog.V(4).InfoS("Could not assert metric as metrics.GaugeVec. Metric update may have been skipped")
+	}
+}
+
+// SetGaugeFunc registers a gauge whose value and label values are returned by f every time the metrics are
+// gathered, so the gauge never reports a series that no longer applies. f returns false when there is nothing
+// to report. Calling it again with the same name replaces f.
+func (m *MetricRecorder) SetGaugeFunc(name string, helpText string, labelNames []string, f func() (float64, []string, bool)) {
+	if m == nil {
+		return // recorder is not initialized
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if metric, ok := m.metrics[name]; ok {
+		if collector, ok := metric.(*gaugeFuncCollector); ok {
+			collector.set(f)
+		} else {
+			klog.V(4).InfoS("Could not assert metric as gaugeFuncCollector. Metric update may have been skipped")
+		}
+		return
+	}
+	collector := &gaugeFuncCollector{desc: prometheus.NewDesc(name, helpText, labelNames, nil), f: f}
+	m.metrics[name] = collector
+	m.registry.MustRegister(collector)
+}
+
+// gaugeFuncCollector collects a single gauge computed at collection time.
+type gaugeFuncCollector struct {
+	desc *prometheus.Desc
+	mu   sync.RWMutex
+	f    func() (float64, []string, bool)
+}
+
+func (c *gaugeFuncCollector) set(f func() (float64, []string, bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.f = f
+}
+
+// Describe sends the descriptor of the gauge to Prometheus.
+func (c *gaugeFuncCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect is invoked by Prometheus at collection time.
+func (c *gaugeFuncCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	f := c.f
+	c.mu.RUnlock()
+
+	value, labelValues, ok := f()
+	if !ok {
+		return
+	}
+	metric, err := prometheus.NewConstMetric(c.desc, prometheus.GaugeValue, value, labelValues...)
+	if err != nil {
+		klog.V(4).InfoS("Could not create gauge metric", "err", err)
+		return
+	}
+	ch <- metric
+}
+
 // rateLimitMiddleware applies rate limiting to metric HTTP requests.
 func rateLimitMiddleware(limiter *rate.Limiter, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -259,6 +343,23 @@ func (m *MetricRecorder) registerCounterVec(name, help string, labels []string)
 	m.registry.MustRegister(counter)
 }
 
+func (m *MetricRecorder) registerGaugeVec(name, help string, labels []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.metrics[name]; exists {
+		return
+	}
+	gauge := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: name,
+			Help: help,
+		},
+		labels,
+	)
+	m.metrics[name] = gauge
+	m.registry.MustRegister(gauge)
+}
+
 func getLabelNames(labels map[string]string) []string {
 	names := make([]string, 0, len(labels))
 	for n := range labels {
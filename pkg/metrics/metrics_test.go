@@ -60,6 +60,36 @@ test_count{key="value"} 1
 			`,
 			recorder: true,
 		},
+		{
+			name: "TestMetricRecorder: SetGaugeMetric",
+			exec: func(m *MetricRecorder) {
+				m.SetGauge("test_value", "help text", 3, map[string]string{"key": "value"})
+				m.SetGauge("test_value", "help text", 2, map[string]string{"key": "value"})
+			},
+			expected: `
+# HELP test_value help text
+# TYPE test_value gauge
+test_value{key="value"} 2
+			`,
+			recorder: true,
+		},
+		{
+			name: "TestMetricRecorder: SetGaugeFuncMetric",
+			exec: func(m *MetricRecorder) {
+				m.SetGaugeFunc("test_func_value", "help text", []string{"key"}, func() (float64, []string, bool) {
+					return 3, []string{"old"}, true
+				})
+				m.SetGaugeFunc("test_func_value", "help text", []string{"key"}, func() (float64, []string, bool) {
+					return 2, []string{"new"}, true
+				})
+			},
+			expected: `
+# HELP test_func_value help text
+# TYPE test_func_value gauge
+test_func_value{key="new"} 2
+			`,
+			recorder: true,
+		},
 		{
 			name: "TestMetricRecorder: Re-register metric",
 			exec: func(m *MetricRecorder) {
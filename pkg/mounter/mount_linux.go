@@ -252,6 +252,11 @@ func (m *NodeMounter) IsCorruptedMnt(err error) bool {
 // MakeFile function is mirrored in ./sanity_test.go to make sure sanity test covered this block of code
 // Please mirror the change to func MakeFile in ./sanity_test.go.
 func (m *NodeMounter) MakeFile(path string) error {
+	// Create any missing parent directories so the file can be created on setups
+	// where kubelet has not created them yet
+	if err := m.MakeDir(filepath.Dir(path)); err != nil {
+		return err
+	}
 	f, err := os.OpenFile(path, os.O_CREATE, os.FileMode(0644))
 	if err != nil {
 		if !os.IsExist(err) {
@@ -20,6 +20,7 @@ package mounter
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -106,6 +107,32 @@ func TestMakeDir(t *testing.T) {
 	}
 }
 
+func TestMakeDirMissingParent(t *testing.T) {
+	dir := t.TempDir()
+
+	targetPath := filepath.Join(dir, "missing", "parent", "targetdir")
+
+	mountObj, err := NewNodeMounter(false)
+	if err != nil {
+		t.Fatalf("error creating mounter %v", err)
+	}
+
+	if err = mountObj.MakeDir(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if !info.IsDir() {
+		t.Fatalf("Expect %q to be a directory", targetPath)
+	}
+	if perm := info.Mode().Perm(); perm&0700 != 0700 {
+		t.Fatalf("Expect owner to have full permissions on %q but got %v", targetPath, perm)
+	}
+}
+
 func TestMakeFile(t *testing.T) {
 	// Setup the full driver and its environment
 	dir := t.TempDir()
@@ -130,6 +157,33 @@ func TestMakeFile(t *testing.T) {
 	}
 }
 
+func TestMakeFileMissingParent(t *testing.T) {
+	dir := t.TempDir()
+
+	targetPath := filepath.Join(dir, "missing", "parent", "targetfile")
+
+	mountObj, err := NewNodeMounter(false)
+	if err != nil {
+		t.Fatalf("error creating mounter %v", err)
+	}
+
+	if err = mountObj.MakeFile(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+
+	if err = mountObj.MakeFile(targetPath); err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		t.Fatalf("Expect no error but got: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		t.Fatalf("Expect %q to be a regular file", targetPath)
+	}
+}
+
 func TestPathExists(t *testing.T) {
 	// Setup the full driver and its environment
 	dir := t.TempDir()
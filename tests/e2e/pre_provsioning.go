@@ -80,7 +80,7 @@ var _ = Describe("[ebs-csi-e2e] [single-az] Pre-Provisioned", func() {
 		availabilityZone := availabilityZones[rand.Intn(len(availabilityZones))]
 		region := availabilityZone[0 : len(availabilityZone)-1]
 
-		cloud = awscloud.NewCloud(region, false, "", true, false)
+		cloud = awscloud.NewCloud(region, awscloud.CloudOptions{Batching: awscloud.BatchingOptions{Enabled: true}})
 		diskOptions := &awscloud.DiskOptions{
 			CapacityBytes:    defaultDiskSizeBytes,
 			VolumeType:       defaultVolumeType,
@@ -248,7 +248,7 @@ var _ = Describe("[ebs-csi-e2e] [single-az] Pre-Provisioned with Multi-Attach",
 		availabilityZone := availabilityZones[rand.Intn(len(availabilityZones))]
 		region := availabilityZone[0 : len(availabilityZone)-1]
 
-		cloud = awscloud.NewCloud(region, false, "", true, false)
+		cloud = awscloud.NewCloud(region, awscloud.CloudOptions{Batching: awscloud.BatchingOptions{Enabled: true}})
 		diskOptions := &awscloud.DiskOptions{
 			CapacityBytes:      defaultDiskSizeBytes,
 			VolumeType:         awscloud.VolumeTypeIO2,
@@ -226,10 +226,25 @@ func (d *fakeCloud) WaitForAttachmentState(ctx context.Context, expectedState ty
 	return &types.VolumeAttachment{}, nil
 }
 
+func (d *fakeCloud) GetVolumeModificationProgress(ctx context.Context, volumeID string) (string, int, error) {
+	if _, exists := d.disks[volumeID]; !exists {
+		return "", 0, cloud.ErrNotFound
+	}
+	return "", 0, cloud.ErrVolumeNotBeingModified
+}
+
 func (d *fakeCloud) IsVolumeInitialized(ctx context.Context, volumeID string) (bool, error) {
 	return true, nil
 }
 
+func (d *fakeCloud) IsNitroInstanceType(ctx context.Context, instanceType string) bool {
+	return true
+}
+
+func (d *fakeCloud) GetInstanceTypeMaxIOPS(ctx context.Context, instanceType string) (int32, error) {
+	return 0, nil
+}
+
 func (d *fakeCloud) DryRun(ctx context.Context) error {
 	return nil
 }